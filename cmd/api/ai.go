@@ -3,30 +3,51 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
 	// Logic: Add the 'net/url' package to the imports.
 	"net/url"
-	"strings"
+	"slices"
+	"time"
 
-	"github.com/google/generative-ai-go/genai"
+	"github.com/sixync/birdlens-be/internal/ai"
 	"github.com/sixync/birdlens-be/internal/response"
-	"google.golang.org/api/option"
+	"github.com/sixync/birdlens-be/internal/store"
 )
 
 // --- Structs for API communication with Android Client ---
 
 // AIIdentifyResponse mirrors the Kotlin data class expected by the Android client.
+// Possibilities and IdentifiedBird carry the names only; Candidates carries
+// the same species with their codes and confidence.
 type AIIdentifyResponse struct {
-	Possibilities  []string `json:"possibilities,omitempty"`
-	IdentifiedBird string   `json:"identified_bird,omitempty"`
-	ChatResponse   string   `json:"chat_response,omitempty"`
-	ImageURL       string   `json:"image_url,omitempty"`
+	// IdentificationID is where feedback on this identification goes; it
+	// is zero when the identification could not be recorded.
+	IdentificationID int64               `json:"identification_id,omitempty"`
+	Possibilities    []string            `json:"possibilities,omitempty"`
+	IdentifiedBird   string              `json:"identified_bird,omitempty"`
+	SpeciesCode      string              `json:"species_code,omitempty"`
+	Candidates       []IdentifiedSpecies `json:"candidates,omitempty"`
+	Model            string              `json:"model,omitempty"`
+	ChatResponse     string              `json:"chat_response,omitempty"`
+	ImageURL         string              `json:"image_url,omitempty"`
 }
 
+// IdentifiedSpecies is an AI candidate and whether the user has already
+// seen it.
+type IdentifiedSpecies struct {
+	store.IdentificationCandidate
+	OnLifeList bool `json:"on_life_list"`
+}
+
+// aiConfidentIdentification is the confidence from which the top candidate
+// is reported as the identified bird even when the model listed others.
+const aiConfidentIdentification = 0.85
+
 // ChatMessage mirrors the Kotlin data class for conversation history.
 type ChatMessage struct {
 	Role string `json:"role"` // "user" or "model"
@@ -45,161 +66,221 @@ type AIQuestionResponse struct {
 	ChatResponse string `json:"chat_response"`
 }
 
-// --- Prompts for Gemini ---
-
-const geminiPromptIdentifyFromImage = "Identify the bird in this image. If you are very confident about one species, respond with ONLY that bird's most common English name. If it could be several similar-looking birds, respond with a comma-separated list of up to 3 most likely species. For example: 'House Sparrow, Eurasian Tree Sparrow, Chipping Sparrow'. If there is no bird in the image, respond with 'no bird'."
-const geminiPromptExtractNameFromText = "You are an expert ornithologist and polyglot. Your task is to extract the bird name from the user's text.\n- If the name is specific (e.g., 'Blue Jay', 'Họa mi'), respond with ONLY that single common English name (e.g., 'Blue Jay', 'Chinese Hwamei').\n- If the name is ambiguous (e.g., 'sparrow', 'chim sẻ'), respond with a comma-separated list of up to 5 likely species in English (e.g., 'House Sparrow, Eurasian Tree Sparrow, American Tree Sparrow, Song Sparrow, Chipping Sparrow').\n- If you cannot identify a bird (e.g., the text is 'con mèo' or 'what is the weather?'), you MUST respond with the exact text: 'Error: No bird name found'.\n\nUser text: \"%s\""
-
 // --- Handlers ---
 
 func (app *application) identifyBirdHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := r.Context()
 	user := app.getUserFromFirebaseClaimsCtx(r)
 	if user == nil {
 		app.unauthorized(w, r)
 		return
 	}
 
-	client, err := genai.NewClient(ctx, option.WithAPIKey(app.config.gemini.apiKey))
-	if err != nil {
-		app.serverError(w, r, fmt.Errorf("failed to create genai client: %w", err))
-		return
-	}
-	defer client.Close()
-
-	err = r.ParseMultipartForm(10 << 20)
-	if err != nil {
-		app.badRequest(w, r, fmt.Errorf("failed to parse multipart form: %w", err))
+	media, ok := app.identifyMedia(w, r)
+	if !ok {
 		return
 	}
 
 	prompt := r.FormValue("prompt")
-	if prompt == "" {
+	if prompt == "" && len(media) == 0 {
 		app.badRequest(w, r, fmt.Errorf("prompt cannot be empty"))
 		return
 	}
-	slog.Info("identifyBirdHandler called", "user", user.Email, "prompt", prompt)
-
-	var parts []genai.Part
+	slog.Info("identifyBirdHandler called", "user", user.Email, "prompt", prompt, "media", len(media))
+	language, err := profileLanguage(r.FormValue("lang"))
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
 
-	file, _, err := r.FormFile("image")
-	if err == nil {
-		defer file.Close()
-		imageData, readErr := io.ReadAll(file)
-		if readErr != nil {
-			app.serverError(w, r, fmt.Errorf("failed to read image data: %w", readErr))
-			return
+	var identification *ai.Identification
+	var imageData []byte
+	record := &store.Identification{UserID: user.Id, Prompt: prompt}
+	if len(media) > 0 {
+		// The first photo is kept with the record; recordings are not.
+		record.InputType = store.IdentificationInputAudio
+		for _, m := range media {
+			if !m.IsAudio() {
+				record.InputType = store.IdentificationInputImage
+				imageData = m.Data
+				break
+			}
 		}
-		parts = append(parts, genai.ImageData("jpeg", imageData))
-		parts = append(parts, genai.Text(geminiPromptIdentifyFromImage))
+		identification, err = app.ai.IdentifyMedia(ctx, media)
 	} else {
-		finalPrompt := fmt.Sprintf(geminiPromptExtractNameFromText, prompt)
-		parts = append(parts, genai.Text(finalPrompt))
+		record.InputType = store.IdentificationInputText
+		identification, err = app.ai.IdentifyText(ctx, prompt)
 	}
-
-	model := client.GenerativeModel("gemini-1.5-flash")
-	resp, err := model.GenerateContent(ctx, parts...)
 	if err != nil {
-		app.serverError(w, r, fmt.Errorf("failed to generate content from Gemini: %w", err))
+		app.aiError(w, r, err)
 		return
 	}
+	app.meterAI(r, identification.Usage)
+	record.Model = identification.Model
 
-	geminiTextResponse := app.extractTextFromGeminiResponse(resp)
-	if geminiTextResponse == "no bird" || strings.Contains(geminiTextResponse, "No bird name found") {
-		response.JSON(w, http.StatusOK, AIIdentifyResponse{ChatResponse: "Could not identify a bird from the provided input."}, false, "Identification failed")
+	if identification.NoBird() {
+		app.recordIdentification(r, record, imageData)
+		response.JSON(w, http.StatusOK, AIIdentifyResponse{IdentificationID: record.ID, ChatResponse: "Could not identify a bird from the provided input."}, false, "Identification failed")
 		return
 	}
 
-	if strings.Contains(geminiTextResponse, ",") {
-		possibilities := strings.Split(geminiTextResponse, ",")
-		for i, p := range possibilities {
-			possibilities[i] = strings.TrimSpace(p)
-		}
-		response.JSON(w, http.StatusOK, AIIdentifyResponse{Possibilities: possibilities}, false, "Multiple possibilities found")
+	candidates, err := app.resolveCandidates(ctx, user.Id, identification.Candidates)
+	if err != nil {
+		app.serverError(w, r, err)
 		return
 	}
+	for _, c := range candidates {
+		record.Candidates = append(record.Candidates, c.IdentificationCandidate)
+	}
 
-	identifiedBird := strings.TrimSpace(geminiTextResponse)
-	slog.Info("Gemini identified a single bird", "name", identifiedBird)
-
-	chatPrompt := fmt.Sprintf("Tell me about the %s.", identifiedBird)
-	chatResp, err := model.GenerateContent(ctx, genai.Text(chatPrompt))
-	if err != nil {
-		app.serverError(w, r, fmt.Errorf("failed to get chat response for '%s': %w", identifiedBird, err))
+	if len(candidates) > 1 && candidates[0].Confidence < aiConfidentIdentification {
+		app.recordIdentification(r, record, imageData)
+		possibilities := make([]string, len(candidates))
+		for i, c := range candidates {
+			possibilities[i] = c.CommonName
+		}
+		response.JSON(w, http.StatusOK, AIIdentifyResponse{IdentificationID: record.ID, Possibilities: possibilities, Candidates: candidates, Model: identification.Model}, false, "Multiple possibilities found")
 		return
 	}
-	chatResponseText := app.extractTextFromGeminiResponse(chatResp)
 
-	imageURL, err := app.getWikipediaImageURL(ctx, identifiedBird)
-	if err != nil {
-		slog.Warn("Could not fetch Wikipedia image", "bird", identifiedBird, "error", err)
+	identified := candidates[0]
+	record.ChosenName = &identified.CommonName
+	if identified.SpeciesCode != "" {
+		record.ChosenSpeciesCode = &identified.SpeciesCode
+	}
+	app.recordIdentification(r, record, imageData)
+	identifiedBird := identified.CommonName
+	slog.Info("AI identified a single bird", "name", identifiedBird, "species_code", identified.SpeciesCode, "confidence", identified.Confidence, "model", identification.Model)
+
+	var description, imageURL string
+	if identified.SpeciesCode != "" {
+		profile, ok := app.speciesProfile(w, r, &store.Species{
+			SpeciesCode:    identified.SpeciesCode,
+			CommonName:     identified.CommonName,
+			ScientificName: identified.ScientificName,
+		}, language)
+		if !ok {
+			return
+		}
+		description = profile.Description
+		if profile.ImageURL != nil {
+			imageURL = *profile.ImageURL
+		}
+	} else {
+		// Birds outside the taxonomy have no profile to keep.
+		reply, err := app.ai.Describe(ctx, identifiedBird, language)
+		if err != nil {
+			app.aiError(w, r, err)
+			return
+		}
+		app.meterAI(r, reply.Usage)
+		description = reply.Text
+
+		imageURL, err = app.getWikipediaImageURL(ctx, identifiedBird)
+		if err != nil {
+			slog.Warn("Could not fetch Wikipedia image", "bird", identifiedBird, "error", err)
+		}
 	}
 
 	finalResponse := AIIdentifyResponse{
-		IdentifiedBird: identifiedBird,
-		ChatResponse:   chatResponseText,
-		ImageURL:       imageURL,
+		IdentificationID: record.ID,
+		IdentifiedBird:   identifiedBird,
+		SpeciesCode:      identified.SpeciesCode,
+		Candidates:       candidates,
+		Model:            identification.Model,
+		ChatResponse:     description,
+		ImageURL:         imageURL,
 	}
 
 	response.JSON(w, http.StatusOK, finalResponse, false, "Identification successful")
 }
 
 func (app *application) askAiQuestionHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
 	var req AIQuestionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		app.badRequest(w, r, err)
 		return
 	}
 
-	client, err := genai.NewClient(ctx, option.WithAPIKey(app.config.gemini.apiKey))
-	if err != nil {
-		app.serverError(w, r, fmt.Errorf("failed to create genai client: %w", err))
-		return
-	}
-	defer client.Close()
-
-	model := client.GenerativeModel("gemini-1.5-flash")
-	chat := model.StartChat()
-
-	chat.History = make([]*genai.Content, 0, len(req.History))
+	history := make([]ai.Message, 0, len(req.History))
 	for _, msg := range req.History {
-		chat.History = append(chat.History, &genai.Content{
-			Role:  msg.Role,
-			Parts: []genai.Part{genai.Text(msg.Text)},
-		})
+		history = append(history, ai.Message{Role: msg.Role, Text: msg.Text})
 	}
 
-	resp, err := chat.SendMessage(ctx, genai.Text(req.Question))
+	answer, err := app.ai.Ask(r.Context(), history, req.Question)
 	if err != nil {
-		app.serverError(w, r, fmt.Errorf("failed to send message to Gemini: %w", err))
+		app.aiError(w, r, err)
 		return
 	}
-
-	chatResponseText := app.extractTextFromGeminiResponse(resp)
-	finalResponse := AIQuestionResponse{ChatResponse: chatResponseText}
+	app.meterAI(r, answer.Usage)
+	finalResponse := AIQuestionResponse{ChatResponse: answer.Text}
 
 	response.JSON(w, http.StatusOK, finalResponse, false, "Question answered")
 }
 
 // --- Helper Functions ---
 
-func (app *application) extractTextFromGeminiResponse(resp *genai.GenerateContentResponse) string {
-	var b strings.Builder
-	if resp != nil && len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
-		for _, part := range resp.Candidates[0].Content.Parts {
-			if txt, ok := part.(genai.Text); ok {
-				b.WriteString(string(txt))
-			}
+// resolveCandidates maps AI candidates to species codes by scientific name,
+// falling back to the common name, and takes the taxonomy's names for the
+// species it finds. Candidates naming the same species are merged, keeping
+// the first.
+func (app *application) resolveCandidates(ctx context.Context, userID int64, candidates []ai.Candidate) ([]IdentifiedSpecies, error) {
+	resolved := make([]IdentifiedSpecies, 0, len(candidates))
+	var codes []string
+	for _, c := range candidates {
+		code, err := app.store.Species.FindCodeByName(ctx, c.ScientificName, c.Name)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if code != "" && slices.Contains(codes, code) {
+			continue
 		}
+		if code != "" {
+			codes = append(codes, code)
+		}
+		resolved = append(resolved, IdentifiedSpecies{IdentificationCandidate: store.IdentificationCandidate{
+			SpeciesCode:    code,
+			CommonName:     c.Name,
+			ScientificName: c.ScientificName,
+			Confidence:     c.Confidence,
+			FieldMarks:     c.FieldMarks,
+		}})
+	}
+
+	species, err := app.store.Species.GetByCodes(ctx, codes)
+	if err != nil {
+		return nil, err
 	}
-	if b.Len() == 0 {
-		slog.Warn("Gemini response was empty or contained no text parts")
-		return "Sorry, I could not generate a response."
+	seen, err := app.store.Users.GetSeenSpeciesCodes(ctx, userID, codes)
+	if err != nil {
+		return nil, err
+	}
+	for i := range resolved {
+		if sp, ok := species[resolved[i].SpeciesCode]; ok {
+			resolved[i].CommonName = sp.CommonName
+			resolved[i].ScientificName = sp.ScientificName
+		}
+		resolved[i].OnLifeList = seen[resolved[i].SpeciesCode]
 	}
-	return b.String()
+	return resolved, nil
 }
 
+// aiError answers with 501 when the configured provider cannot serve the
+// request, and with a server error otherwise. A failed model call still
+// counts against the user's quota.
+func (app *application) aiError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, ai.ErrUnsupported) {
+		app.errorMessage(w, r, http.StatusNotImplemented, "This kind of identification is not available.", nil)
+		return
+	}
+	app.meterAI(r, ai.Usage{})
+	app.serverError(w, r, err)
+}
+
+// wikipediaClient is shared by Wikipedia lookups; the timeout keeps a
+// hung lookup from holding up identifications and profile generation.
+var wikipediaClient = &http.Client{Timeout: 10 * time.Second}
+
 type wikiQueryResponse struct {
 	Query struct {
 		Pages map[string]struct {
@@ -230,8 +311,7 @@ func (app *application) getWikipediaImageURL(ctx context.Context, title string)
 		return "", err
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := wikipediaClient.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -253,4 +333,4 @@ func (app *application) getWikipediaImageURL(ctx context.Context, title string)
 	}
 
 	return "", fmt.Errorf("no image found for %s", title)
-}
\ No newline at end of file
+}
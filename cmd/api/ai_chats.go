@@ -0,0 +1,300 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sixync/birdlens-be/internal/ai"
+	"github.com/sixync/birdlens-be/internal/request"
+	"github.com/sixync/birdlens-be/internal/response"
+	"github.com/sixync/birdlens-be/internal/store"
+	"github.com/sixync/birdlens-be/internal/validator"
+)
+
+const (
+	// maxChatMessages bounds a chat, opening messages included.
+	maxChatMessages = 40
+	chatFullMessage = "This chat is full. Start a new chat to keep asking."
+	// maxChatMessageLength is in characters.
+	maxChatMessageLength = 2000
+	// chatContextMessages is how many recent messages are sent to the
+	// model besides the opening ones, which always go.
+	chatContextMessages = 20
+	chatOpeningMessages = 2
+
+	// chatStreamTimeout replaces the server's write timeout while a reply
+	// streams.
+	chatStreamTimeout = 2 * time.Minute
+)
+
+// CreateChatRequest starts a chat about the bird of an identification.
+// SpeciesCode picks one of its candidates; it is required when the
+// identification did not settle on a species.
+type CreateChatRequest struct {
+	IdentificationID int64  `json:"identification_id"`
+	SpeciesCode      string `json:"species_code"`
+}
+
+type ChatMessageRequest struct {
+	Text string `json:"text" validate:"required"`
+}
+
+// ChatResponse is a chat with its messages.
+type ChatResponse struct {
+	*store.Chat
+	Messages []*store.ChatMessage `json:"messages"`
+}
+
+func (app *application) createChatHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	var req CreateChatRequest
+	if err := request.DecodeJSON(w, r, &req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	if req.IdentificationID <= 0 {
+		app.badRequest(w, r, errors.New("identification_id is required"))
+		return
+	}
+
+	ctx := r.Context()
+	identification, err := app.store.Identifications.GetByID(ctx, user.Id, req.IdentificationID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.badRequest(w, r, errors.New("identification not found"))
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	bird, err := chatBird(identification, req.SpeciesCode)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	chat := &store.Chat{
+		UserID:           user.Id,
+		IdentificationID: &identification.ID,
+		BirdName:         bird.CommonName,
+	}
+	if bird.SpeciesCode != "" {
+		chat.SpeciesCode = &bird.SpeciesCode
+	}
+	messages := chatOpening(bird)
+	if err := app.store.Chats.Create(ctx, chat, messages); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, ChatResponse{Chat: chat, Messages: messages}, false, "Chat created successfully")
+}
+
+// chatBird returns the candidate of identification the chat is about.
+func chatBird(identification *store.Identification, speciesCode string) (*store.IdentificationCandidate, error) {
+	if len(identification.Candidates) == 0 {
+		return nil, errors.New("the identification did not find a bird")
+	}
+	if speciesCode == "" && identification.ChosenSpeciesCode != nil {
+		speciesCode = *identification.ChosenSpeciesCode
+	}
+	for i, c := range identification.Candidates {
+		if speciesCode != "" && c.SpeciesCode == speciesCode {
+			return &identification.Candidates[i], nil
+		}
+		if speciesCode == "" && identification.ChosenName != nil && c.CommonName == *identification.ChosenName {
+			return &identification.Candidates[i], nil
+		}
+	}
+	if speciesCode != "" {
+		return nil, fmt.Errorf("species %q is not among the identification's candidates", speciesCode)
+	}
+	return nil, errors.New("species_code is required to pick one of the identification's candidates")
+}
+
+// chatOpening gives the model the bird the chat is about.
+func chatOpening(bird *store.IdentificationCandidate) []*store.ChatMessage {
+	name := bird.CommonName
+	if bird.ScientificName != "" {
+		name = fmt.Sprintf("%s (%s)", bird.CommonName, bird.ScientificName)
+	}
+	return []*store.ChatMessage{
+		{Role: store.ChatRoleUser, Text: fmt.Sprintf("I identified this bird as the %s. I'd like to ask you about it.", name)},
+		{Role: store.ChatRoleModel, Text: fmt.Sprintf("Happy to help. What would you like to know about the %s?", bird.CommonName)},
+	}
+}
+
+func (app *application) getChatsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	limit, offset := getPaginateFromCtx(r)
+	chats, err := app.store.Chats.GetByUserID(r.Context(), user.Id, limit, offset)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, chats, false, "Chats retrieved successfully")
+}
+
+func (app *application) getChatHandler(w http.ResponseWriter, r *http.Request) {
+	chat, ok := app.chatFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	messages, err := app.store.Chats.GetMessages(r.Context(), chat.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, ChatResponse{Chat: chat, Messages: messages}, false, "Chat retrieved successfully")
+}
+
+// createChatMessageHandler sends a question to the assistant and streams
+// the answer as server-sent events: "token" events carry pieces of text,
+// then a "done" event carries the stored answer, or an "error" event
+// reports a failure. Errors found before streaming starts are answered
+// with JSON as usual.
+func (app *application) createChatMessageHandler(w http.ResponseWriter, r *http.Request) {
+	chat, ok := app.chatFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req ChatMessageRequest
+	if err := request.DecodeJSON(w, r, &req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	if err := validator.Validate(req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	question := strings.TrimSpace(req.Text)
+	if question == "" || utf8.RuneCountInString(question) > maxChatMessageLength {
+		app.badRequest(w, r, fmt.Errorf("text must be between 1 and %d characters", maxChatMessageLength))
+		return
+	}
+	// A full chat is turned away before the model is called. AddMessages
+	// enforces the bound for requests racing on the same chat.
+	if chat.MessageCount+2 > maxChatMessages {
+		app.errorMessage(w, r, http.StatusConflict, chatFullMessage, nil)
+		return
+	}
+
+	ctx := r.Context()
+	messages, err := app.store.Chats.GetMessages(ctx, chat.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Now().Add(chatStreamTimeout)); err != nil {
+		app.serverError(w, r, fmt.Errorf("failed to extend the write deadline: %w", err))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string, data any) error {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	reply, err := ai.AskStream(ctx, app.ai.Assistant, chatHistory(messages), question, func(text string) error {
+		return send("token", map[string]string{"text": text})
+	})
+	if err != nil {
+		app.meterAI(r, ai.Usage{})
+		app.reportServerError(r, err)
+		send("error", map[string]string{"message": "The assistant could not answer. Please try again."})
+		return
+	}
+	app.meterAI(r, reply.Usage)
+
+	exchange := []*store.ChatMessage{
+		{Role: store.ChatRoleUser, Text: question},
+		{Role: store.ChatRoleModel, Text: reply.Text, InputTokens: reply.Usage.InputTokens, OutputTokens: reply.Usage.OutputTokens},
+	}
+	err = app.store.Chats.AddMessages(ctx, chat, exchange, maxChatMessages)
+	if errors.Is(err, store.ErrChatFull) {
+		// The response has started, so the conflict goes in the event.
+		send("error", map[string]any{"message": chatFullMessage, "status": http.StatusConflict})
+		return
+	}
+	if err != nil {
+		app.reportServerError(r, err)
+		send("error", map[string]string{"message": "The answer could not be saved."})
+		return
+	}
+
+	send("done", map[string]any{"message": exchange[1], "message_count": chat.MessageCount})
+}
+
+// chatHistory is what the model is given of a chat: the opening messages
+// and the most recent ones.
+func chatHistory(messages []*store.ChatMessage) []ai.Message {
+	kept := messages
+	if len(messages) > chatOpeningMessages+chatContextMessages {
+		kept = append(messages[:chatOpeningMessages:chatOpeningMessages], messages[len(messages)-chatContextMessages:]...)
+	}
+	history := make([]ai.Message, len(kept))
+	for i, msg := range kept {
+		history[i] = ai.Message{Role: msg.Role, Text: msg.Text}
+	}
+	return history
+}
+
+func (app *application) chatFromPath(w http.ResponseWriter, r *http.Request) (*store.Chat, bool) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return nil, false
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, errors.New("invalid chat id"))
+		return nil, false
+	}
+
+	chat, err := app.store.Chats.GetByID(r.Context(), user.Id, id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFound(w, r)
+		return nil, false
+	case err != nil:
+		app.serverError(w, r, err)
+		return nil, false
+	}
+	return chat, true
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/sixync/birdlens-be/internal/request"
+	"github.com/sixync/birdlens-be/internal/response"
+	"github.com/sixync/birdlens-be/internal/store"
+)
+
+// IdentificationFeedbackRequest is a user's verdict on an identification.
+// ActualSpeciesCode is the species it really was; it may be left out of a
+// correct verdict on an identification that chose a species.
+type IdentificationFeedbackRequest struct {
+	Correct           *bool  `json:"correct"`
+	ActualSpeciesCode string `json:"actual_species_code"`
+}
+
+// IdentificationAccuracyResponse is the accuracy of the identifications
+// overall and per chosen species.
+type IdentificationAccuracyResponse struct {
+	Overall *store.IdentificationAccuracy                              `json:"overall"`
+	Species *store.PaginatedList[*store.SpeciesIdentificationAccuracy] `json:"species"`
+}
+
+// recordIdentification stores an identification and uploads its image in
+// the background. A failure is logged rather than failing the request the
+// user already paid for with a model call; record.ID stays zero then.
+func (app *application) recordIdentification(r *http.Request, record *store.Identification, imageData []byte) {
+	if err := app.store.Identifications.Create(r.Context(), record); err != nil {
+		slog.Error("Failed to record AI identification", "user_id", record.UserID, "error", err)
+		return
+	}
+	if len(imageData) == 0 {
+		return
+	}
+
+	id := record.ID
+	app.backgroundTask(r, func() error {
+		ctx := context.Background()
+		filePath := fmt.Sprintf("ai/identifications/%d", id)
+		url, err := app.uploadFileToCloudinary(ctx, "images", id, filePath, imageData)
+		if err != nil {
+			return fmt.Errorf("failed to upload identification image: %w", err)
+		}
+		return app.store.Identifications.SetImageURL(ctx, id, url)
+	})
+}
+
+func (app *application) getIdentificationsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	limit, offset := getPaginateFromCtx(r)
+	identifications, err := app.store.Identifications.GetByUserID(r.Context(), user.Id, limit, offset)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, identifications, false, "Identifications retrieved successfully")
+}
+
+func (app *application) createIdentificationFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, errors.New("invalid identification id"))
+		return
+	}
+
+	var req IdentificationFeedbackRequest
+	if err := request.DecodeJSON(w, r, &req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	if req.Correct == nil {
+		app.badRequest(w, r, errors.New("correct is required"))
+		return
+	}
+
+	ctx := r.Context()
+	identification, err := app.store.Identifications.GetByID(ctx, user.Id, id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFound(w, r)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	feedback := store.IdentificationFeedbackIncorrect
+	if *req.Correct {
+		feedback = store.IdentificationFeedbackCorrect
+	}
+	actual := req.ActualSpeciesCode
+	if actual == "" && *req.Correct && identification.ChosenSpeciesCode != nil {
+		actual = *identification.ChosenSpeciesCode
+	}
+	if actual == "" && *req.Correct {
+		app.badRequest(w, r, errors.New("actual_species_code is required when the identification did not choose a species"))
+		return
+	}
+	if actual != "" {
+		exists, err := app.store.Species.Exists(ctx, actual)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		if !exists {
+			app.badRequest(w, r, fmt.Errorf("unknown species code %q", actual))
+			return
+		}
+	}
+	if *req.Correct && identification.ChosenSpeciesCode != nil && actual != *identification.ChosenSpeciesCode {
+		app.badRequest(w, r, errors.New("actual_species_code differs from the identified species; send correct: false"))
+		return
+	}
+
+	identification.Feedback = &feedback
+	identification.ActualSpeciesCode = nil
+	if actual != "" {
+		identification.ActualSpeciesCode = &actual
+	}
+	err = app.store.Identifications.SaveFeedback(ctx, identification)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFound(w, r)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, identification, false, "Feedback saved successfully")
+}
+
+// getIdentificationAccuracyHandler reports how often identifications were
+// right, overall and per chosen species. model restricts it to one model.
+func (app *application) getIdentificationAccuracyHandler(w http.ResponseWriter, r *http.Request) {
+	model := r.URL.Query().Get("model")
+	limit, offset := getPaginateFromCtx(r)
+
+	overall, err := app.store.Identifications.GetAccuracy(r.Context(), model)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	species, err := app.store.Identifications.GetAccuracyBySpecies(r.Context(), model, limit, offset)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, IdentificationAccuracyResponse{Overall: overall, Species: species}, false, "Identification accuracy retrieved successfully")
+}
+
+// getLabelledIdentificationsHandler lists the photos whose species users
+// have confirmed, for building a training dataset.
+func (app *application) getLabelledIdentificationsHandler(w http.ResponseWriter, r *http.Request) {
+	limit, offset := getPaginateFromCtx(r)
+	labelled, err := app.store.Identifications.GetLabelled(r.Context(), limit, offset)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, labelled, false, "Labelled identifications retrieved successfully")
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sixync/birdlens-be/internal/jwt"
+)
+
+func TestCreateIdentificationFeedbackHandlerRequiresVerdict(t *testing.T) {
+	app, _, _ := newIdentifyApplication(t)
+
+	for _, body := range []string{`{}`, `{"actual_species_code": "blujay"}`} {
+		req := httptest.NewRequest(http.MethodPost, "/ai/identifications/1/feedback", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.SetPathValue("id", "1")
+		req = req.WithContext(context.WithValue(req.Context(), UserClaimsKey, &jwt.FirebaseClaims{Uid: "uid-1"}))
+
+		rec := httptest.NewRecorder()
+		app.createIdentificationFeedbackHandler(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status of feedback %s = %d, want 400: %s", body, rec.Code, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/sixync/birdlens-be/internal/ai"
+)
+
+// Uploads of an identification request. Everything is sent to the model in
+// one request, which Gemini caps at 20 MB.
+const (
+	maxIdentifyImages     = 5
+	maxIdentifyRecordings = 1
+	maxIdentifyImageSize  = 10 << 20
+	maxIdentifyAudioSize  = 15 << 20
+	maxIdentifyUploadSize = 20 << 20
+
+	// The server's timeouts are meant for small JSON requests. Uploads from
+	// phones take longer to arrive, and the model and profile calls longer
+	// to answer.
+	identifyReadTimeout  = time.Minute
+	identifyWriteTimeout = 2 * time.Minute
+
+	// maxISOBoxDepth bounds how deep isoHandlers looks into nested boxes.
+	// Handlers sit three boxes deep, in moov, trak and mdia; crafted files
+	// can nest far deeper.
+	maxISOBoxDepth = 4
+)
+
+// identifyMedia reads the photos sent in "image" fields and the recording
+// sent in the "audio" field of an identification request, and answers with
+// an error when there are too many, they are too large or their format is
+// not supported. It returns no media for a text-only request. It extends
+// the request's deadlines for the rest of the identification.
+func (app *application) identifyMedia(w http.ResponseWriter, r *http.Request) ([]ai.Media, bool) {
+	rc := http.NewResponseController(w)
+	now := time.Now()
+	if err := rc.SetReadDeadline(now.Add(identifyReadTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		app.serverError(w, r, fmt.Errorf("failed to extend the read deadline: %w", err))
+		return nil, false
+	}
+	if err := rc.SetWriteDeadline(now.Add(identifyWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		app.serverError(w, r, fmt.Errorf("failed to extend the write deadline: %w", err))
+		return nil, false
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxIdentifyUploadSize)
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			app.errorMessage(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("Uploads must total at most %d MB.", maxIdentifyUploadSize>>20), nil)
+			return nil, false
+		}
+		app.badRequest(w, r, fmt.Errorf("failed to parse multipart form: %w", err))
+		return nil, false
+	}
+
+	images := r.MultipartForm.File["image"]
+	recordings := r.MultipartForm.File["audio"]
+	if len(images) > maxIdentifyImages {
+		app.badRequest(w, r, fmt.Errorf("at most %d images can be sent", maxIdentifyImages))
+		return nil, false
+	}
+	if len(recordings) > maxIdentifyRecordings {
+		app.badRequest(w, r, fmt.Errorf("at most %d audio recording can be sent", maxIdentifyRecordings))
+		return nil, false
+	}
+
+	media := make([]ai.Media, 0, len(images)+len(recordings))
+	for i, fh := range images {
+		name := "Image"
+		if len(images) > 1 {
+			name = fmt.Sprintf("Image %d", i+1)
+		}
+		m, ok := app.readIdentifyUpload(w, r, fh, name, false)
+		if !ok {
+			return nil, false
+		}
+		media = append(media, m)
+	}
+	for _, fh := range recordings {
+		m, ok := app.readIdentifyUpload(w, r, fh, "Audio", true)
+		if !ok {
+			return nil, false
+		}
+		media = append(media, m)
+	}
+	return media, true
+}
+
+func (app *application) readIdentifyUpload(w http.ResponseWriter, r *http.Request, fh *multipart.FileHeader, name string, audio bool) (ai.Media, bool) {
+	maxSize, formats := int64(maxIdentifyImageSize), "a JPEG, PNG, WebP or HEIC photo"
+	if audio {
+		maxSize, formats = maxIdentifyAudioSize, "a WAV, M4A or MP3 recording"
+	}
+	if fh.Size > maxSize {
+		app.errorMessage(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("%s is larger than %d MB.", name, maxSize>>20), nil)
+		return ai.Media{}, false
+	}
+
+	file, err := fh.Open()
+	if err != nil {
+		app.serverError(w, r, fmt.Errorf("failed to open upload %q: %w", name, err))
+		return ai.Media{}, false
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		app.serverError(w, r, fmt.Errorf("failed to read upload %q: %w", name, err))
+		return ai.Media{}, false
+	}
+	if len(data) == 0 {
+		app.badRequest(w, r, fmt.Errorf("%s is empty", name))
+		return ai.Media{}, false
+	}
+
+	m := ai.Media{Data: data, MIMEType: detectMediaType(data)}
+	if m.MIMEType == "" || m.IsAudio() != audio {
+		app.errorMessage(w, r, http.StatusUnsupportedMediaType, fmt.Sprintf("%s must be %s.", name, formats), nil)
+		return ai.Media{}, false
+	}
+	return m, true
+}
+
+// isoMediaType tells HEIF photos from MP4 audio among ISO base media files.
+// Photos and .m4a files are recognised by their major or compatible brands.
+// Recorders such as Android's MediaRecorder label .m4a files with generic
+// brands like isom or mp42, so a file whose tracks are all sound counts as
+// audio too.
+func isoMediaType(data []byte) string {
+	size := int(binary.BigEndian.Uint32(data))
+	if size < 16 || size > len(data) {
+		size = 16
+	}
+	brands := []string{string(data[8:12])}
+	for i := 16; i+4 <= size; i += 4 {
+		brands = append(brands, string(data[i:i+4]))
+	}
+	for _, brand := range brands {
+		if mimeType, ok := heifBrands[brand]; ok {
+			return mimeType
+		}
+	}
+	for _, brand := range brands {
+		if brand == "M4A " || brand == "M4B " {
+			return "audio/mp4"
+		}
+	}
+
+	handlers := map[string]bool{}
+	isoHandlers(data, handlers, 0)
+	if handlers["soun"] && !handlers["vide"] {
+		return "audio/mp4"
+	}
+	return ""
+}
+
+// isoHandlers collects the handler types of the tracks in boxes, e.g.
+// "soun" for sound and "vide" for video. depth is how many boxes enclose
+// boxes; those nested past maxISOBoxDepth are skipped.
+func isoHandlers(boxes []byte, handlers map[string]bool, depth int) {
+	for len(boxes) >= 8 {
+		size, header := uint64(binary.BigEndian.Uint32(boxes)), uint64(8)
+		switch size {
+		case 0: // the box extends to the end of the file
+			size = uint64(len(boxes))
+		case 1: // a 64-bit size follows the type
+			if len(boxes) < 16 {
+				return
+			}
+			size, header = binary.BigEndian.Uint64(boxes[8:16]), 16
+		}
+		if size < header || size > uint64(len(boxes)) {
+			return
+		}
+
+		body := boxes[header:size]
+		switch string(boxes[4:8]) {
+		case "moov", "trak", "mdia":
+			if depth < maxISOBoxDepth {
+				isoHandlers(body, handlers, depth+1)
+			}
+		case "hdlr":
+			// version and flags, pre_defined, then handler_type
+			if len(body) >= 12 {
+				handlers[string(body[8:12])] = true
+			}
+		}
+		boxes = boxes[size:]
+	}
+}
+
+// heifBrands are the ISO base media brands of HEIC and HEIF photos.
+var heifBrands = map[string]string{
+	"heic": "image/heic", "heix": "image/heic", "hevc": "image/heic", "hevx": "image/heic",
+	"mif1": "image/heif", "msf1": "image/heif",
+}
+
+// detectMediaType recognises the photo and recording formats identification
+// accepts by their leading bytes, which unlike the client's Content-Type
+// can be trusted, and returns "" for anything else. http.DetectContentType
+// knows neither HEIC, M4A nor MP3 without an ID3 tag.
+func detectMediaType(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xFF\xD8\xFF")):
+		return "image/jpeg"
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return "image/png"
+	case len(data) >= 12 && bytes.HasPrefix(data, []byte("RIFF")) && string(data[8:12]) == "WEBP":
+		return "image/webp"
+	case len(data) >= 12 && bytes.HasPrefix(data, []byte("RIFF")) && string(data[8:12]) == "WAVE":
+		return "audio/wav"
+	case len(data) >= 12 && string(data[4:8]) == "ftyp":
+		return isoMediaType(data)
+	case bytes.HasPrefix(data, []byte("ID3")):
+		return "audio/mpeg"
+	case len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0:
+		// An MPEG audio frame sync without a leading ID3 tag.
+		return "audio/mpeg"
+	}
+	return ""
+}
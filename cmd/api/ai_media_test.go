@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// isoBox builds an ISO base media box of type around body.
+func isoBox(boxType string, body ...[]byte) []byte {
+	size := 8
+	for _, b := range body {
+		size += len(b)
+	}
+	box := binary.BigEndian.AppendUint32(nil, uint32(size))
+	box = append(box, boxType...)
+	for _, b := range body {
+		box = append(box, b...)
+	}
+	return box
+}
+
+func ftyp(major string, compatible ...string) []byte {
+	body := append([]byte(major), 0, 0, 0, 0)
+	for _, brand := range compatible {
+		body = append(body, brand...)
+	}
+	return isoBox("ftyp", body)
+}
+
+func track(handler string) []byte {
+	hdlr := isoBox("hdlr", make([]byte, 8), []byte(handler), make([]byte, 12))
+	return isoBox("trak", isoBox("tkhd", make([]byte, 20)), isoBox("mdia", hdlr))
+}
+
+func TestDetectMediaType(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"jpeg", []byte("\xFF\xD8\xFF\xE0\x00\x10JFIF"), "image/jpeg"},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00"), "image/png"},
+		{"webp", []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), "image/webp"},
+		{"heic", ftyp("heic", "mif1", "heic"), "image/heic"},
+		{"heif", ftyp("mif1", "heic"), "image/heif"},
+		{"heic by compatible brand", ftyp("isom", "heic"), "image/heic"},
+		{"wav", []byte("RIFF\x00\x00\x00\x00WAVEfmt "), "audio/wav"},
+		{"mp3 with id3", []byte("ID3\x04\x00\x00"), "audio/mpeg"},
+		{"mp3 frame", []byte("\xFF\xFB\x90\x64"), "audio/mpeg"},
+		{"m4a", ftyp("M4A ", "M4A ", "isom"), "audio/mp4"},
+		{"m4a by compatible brand", ftyp("mp42", "isom", "M4A "), "audio/mp4"},
+		{"isom audio, moov last", append(append(ftyp("isom", "isom", "mp42"), isoBox("mdat", make([]byte, 64))...), isoBox("moov", track("soun"))...), "audio/mp4"},
+		{"mp42 audio", append(ftyp("mp42", "isom"), isoBox("moov", track("soun"))...), "audio/mp4"},
+		{"mp4 video", append(ftyp("isom", "mp42"), isoBox("moov", track("vide"), track("soun"))...), ""},
+		{"mp4 without tracks", ftyp("isom"), ""},
+		{"audio nested too deep", append(ftyp("isom"), isoBox("moov", isoBox("moov", isoBox("moov", track("soun"))))...), ""},
+		{"truncated box", append(ftyp("isom"), 0, 0, 1, 0, 'm', 'o'), ""},
+		{"gif", []byte("GIF89a"), ""},
+		{"empty", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectMediaType(tt.data); got != tt.want {
+				t.Errorf("detectMediaType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
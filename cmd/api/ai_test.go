@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sixync/birdlens-be/internal/ai"
+	"github.com/sixync/birdlens-be/internal/jwt"
+	"github.com/sixync/birdlens-be/internal/store"
+)
+
+// newIdentifyApplication returns an application identifying on the fake
+// provider, with a user "uid-1" who has seen the Blue Jay and fresh
+// profiles of the species the fake finds, so that nothing reaches
+// Wikipedia.
+func newIdentifyApplication(t *testing.T) (*application, *ai.Fake, *fakeIdentifications) {
+	t.Helper()
+	app := newTestApplication(t)
+	app.config.ai.profileTTLDays = 30
+
+	service, err := ai.Open(context.Background(), "fake", "fake", ai.Config{})
+	if err != nil {
+		t.Fatalf("ai.Open() error = %v", err)
+	}
+	t.Cleanup(func() { service.Close() })
+	app.ai = service
+
+	imageURL := "https://upload.wikimedia.org/blue-jay.jpg"
+	identifications := &fakeIdentifications{}
+	app.store = &store.Storage{
+		Users: &fakeUsers{
+			users: map[string]*store.User{"uid-1": {Id: 1, Email: "birder@example.com"}},
+			seen:  map[string]bool{"blujay": true},
+		},
+		Species: &fakeSpecies{species: map[string]*store.Species{
+			"blujay": {SpeciesCode: "blujay", CommonName: "Blue Jay", ScientificName: "Cyanocitta cristata"},
+			"houspa": {SpeciesCode: "houspa", CommonName: "House Sparrow", ScientificName: "Passer domesticus"},
+			"eutspa": {SpeciesCode: "eutspa", CommonName: "Eurasian Tree Sparrow", ScientificName: "Passer montanus"},
+		}},
+		Identifications: identifications,
+		SpeciesProfiles: &fakeSpeciesProfiles{profiles: map[string]*store.SpeciesProfile{
+			"blujay/en": {SpeciesCode: "blujay", Language: "en", Description: "A loud blue corvid.", ImageURL: &imageURL, Model: ai.FakeModel, GeneratedAt: time.Now()},
+			"houspa/en": {SpeciesCode: "houspa", Language: "en", Description: "A small brown sparrow.", Model: ai.FakeModel, GeneratedAt: time.Now()},
+		}},
+	}
+	return app, service.Identifier.(*ai.Fake), identifications
+}
+
+// newIdentifyRequest builds a multipart identification request by user
+// "uid-1" from form fields and uploads named by field.
+func newIdentifyRequest(t *testing.T, fields map[string]string, uploads map[string][]byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, value := range fields {
+		mw.WriteField(name, value)
+	}
+	for name, data := range uploads {
+		fw, err := mw.CreateFormFile(name, name+".bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(data)
+	}
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/ai/identify", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	ctx := context.WithValue(req.Context(), UserClaimsKey, &jwt.FirebaseClaims{Uid: "uid-1"})
+	return req.WithContext(ctx)
+}
+
+func TestIdentifyBirdHandlerText(t *testing.T) {
+	app, fake, identifications := newIdentifyApplication(t)
+
+	rec := httptest.NewRecorder()
+	app.identifyBirdHandler(rec, newIdentifyRequest(t, map[string]string{"prompt": "Blue Jay"}, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	var resp AIIdentifyResponse
+	if msg := decodeResponse(t, rec, &resp); msg != "Identification successful" {
+		t.Errorf("message = %q, want Identification successful", msg)
+	}
+	if resp.IdentifiedBird != "Blue Jay" || resp.SpeciesCode != "blujay" || resp.Model != ai.FakeModel {
+		t.Errorf("response = %+v, want the Blue Jay from the fake", resp)
+	}
+	if resp.ChatResponse != "A loud blue corvid." || resp.ImageURL != "https://upload.wikimedia.org/blue-jay.jpg" {
+		t.Errorf("response = %+v, want the stored profile", resp)
+	}
+	if len(resp.Candidates) != 1 || !resp.Candidates[0].OnLifeList || resp.Candidates[0].ScientificName != "Cyanocitta cristata" {
+		t.Errorf("candidates = %+v, want the Blue Jay from the taxonomy, on the life list", resp.Candidates)
+	}
+	if calls := fake.Calls(); !slices.Equal(calls, []string{"IdentifyText(Blue Jay)"}) {
+		t.Errorf("calls = %q, want only the identification: the profile is stored", calls)
+	}
+
+	if len(identifications.created) != 1 {
+		t.Fatalf("recorded %d identifications, want 1", len(identifications.created))
+	}
+	record := identifications.created[0]
+	if resp.IdentificationID != record.ID || record.UserID != 1 || record.InputType != store.IdentificationInputText || record.Prompt != "Blue Jay" {
+		t.Errorf("record = %+v, want the user's text identification", record)
+	}
+	if record.ChosenSpeciesCode == nil || *record.ChosenSpeciesCode != "blujay" || record.Model != ai.FakeModel {
+		t.Errorf("record = %+v, want the Blue Jay chosen by the fake", record)
+	}
+}
+
+func TestIdentifyBirdHandlerPossibilities(t *testing.T) {
+	app, _, identifications := newIdentifyApplication(t)
+
+	rec := httptest.NewRecorder()
+	app.identifyBirdHandler(rec, newIdentifyRequest(t, map[string]string{"prompt": "sparrow"}, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	var resp AIIdentifyResponse
+	if msg := decodeResponse(t, rec, &resp); msg != "Multiple possibilities found" {
+		t.Errorf("message = %q, want Multiple possibilities found", msg)
+	}
+	want := []string{"House Sparrow", "Eurasian Tree Sparrow", "Song Sparrow"}
+	if !slices.Equal(resp.Possibilities, want) || resp.IdentifiedBird != "" {
+		t.Errorf("response = %+v, want the possibilities %q", resp, want)
+	}
+	if len(resp.Candidates) != 3 || resp.Candidates[0].SpeciesCode != "houspa" || resp.Candidates[2].SpeciesCode != "" {
+		t.Errorf("candidates = %+v, want the sparrows, Song Sparrow outside the taxonomy", resp.Candidates)
+	}
+
+	if len(identifications.created) != 1 {
+		t.Fatalf("recorded %d identifications, want 1", len(identifications.created))
+	}
+	if record := identifications.created[0]; len(record.Candidates) != 3 || record.ChosenSpeciesCode != nil {
+		t.Errorf("record = %+v, want the candidates and no choice", record)
+	}
+}
+
+func TestIdentifyBirdHandlerNoBird(t *testing.T) {
+	app, _, identifications := newIdentifyApplication(t)
+
+	rec := httptest.NewRecorder()
+	app.identifyBirdHandler(rec, newIdentifyRequest(t, map[string]string{"prompt": "a red car"}, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	var resp AIIdentifyResponse
+	if msg := decodeResponse(t, rec, &resp); msg != "Identification failed" {
+		t.Errorf("message = %q, want Identification failed", msg)
+	}
+	if resp.IdentifiedBird != "" || len(resp.Candidates) != 0 {
+		t.Errorf("response = %+v, want no bird", resp)
+	}
+	if len(identifications.created) != 1 || len(identifications.created[0].Candidates) != 0 {
+		t.Errorf("recorded %+v, want one identification without candidates", identifications.created)
+	}
+}
+
+func TestIdentifyBirdHandlerAudio(t *testing.T) {
+	app, fake, identifications := newIdentifyApplication(t)
+
+	wav := append([]byte("RIFF\x24\x00\x00\x00WAVEfmt "), make([]byte, 28)...)
+	rec := httptest.NewRecorder()
+	app.identifyBirdHandler(rec, newIdentifyRequest(t, nil, map[string][]byte{"audio": wav}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	var resp AIIdentifyResponse
+	decodeResponse(t, rec, &resp)
+	if resp.SpeciesCode != "houspa" || resp.ChatResponse != "A small brown sparrow." || resp.ImageURL != "" {
+		t.Errorf("response = %+v, want the House Sparrow's profile", resp)
+	}
+	if calls := fake.Calls(); !slices.Equal(calls, []string{"IdentifyMedia(audio/wav, 44 bytes)"}) {
+		t.Errorf("calls = %q, want the recording sent as audio/wav", calls)
+	}
+	if len(identifications.created) != 1 || identifications.created[0].InputType != store.IdentificationInputAudio {
+		t.Errorf("recorded %+v, want one audio identification", identifications.created)
+	}
+}
+
+func TestIdentifyBirdHandlerErrors(t *testing.T) {
+	t.Run("unauthenticated", func(t *testing.T) {
+		app, fake, _ := newIdentifyApplication(t)
+		req := newIdentifyRequest(t, map[string]string{"prompt": "blue jay"}, nil)
+		req = req.WithContext(context.Background())
+
+		rec := httptest.NewRecorder()
+		app.identifyBirdHandler(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("status = %d, want 401", rec.Code)
+		}
+		if calls := fake.Calls(); len(calls) != 0 {
+			t.Errorf("calls = %q, want none", calls)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		app, _, _ := newIdentifyApplication(t)
+		rec := httptest.NewRecorder()
+		app.identifyBirdHandler(rec, newIdentifyRequest(t, nil, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", rec.Code)
+		}
+	})
+
+	t.Run("unsupported upload", func(t *testing.T) {
+		app, fake, _ := newIdentifyApplication(t)
+		rec := httptest.NewRecorder()
+		app.identifyBirdHandler(rec, newIdentifyRequest(t, nil, map[string][]byte{"image": []byte("not a photo")}))
+		if rec.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("status = %d, want 415", rec.Code)
+		}
+		if calls := fake.Calls(); len(calls) != 0 {
+			t.Errorf("calls = %q, want none", calls)
+		}
+	})
+
+	t.Run("provider error", func(t *testing.T) {
+		app, fake, identifications := newIdentifyApplication(t)
+		fake.Err = errors.New("model unavailable")
+		rec := httptest.NewRecorder()
+		app.identifyBirdHandler(rec, newIdentifyRequest(t, map[string]string{"prompt": "blue jay"}, nil))
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d, want 500", rec.Code)
+		}
+		if len(identifications.created) != 0 {
+			t.Errorf("recorded %d identifications, want none", len(identifications.created))
+		}
+	})
+
+	t.Run("unsupported by provider", func(t *testing.T) {
+		app, _, _ := newIdentifyApplication(t)
+		classifier, err := ai.NewClassifier("http://classifier.invalid")
+		if err != nil {
+			t.Fatal(err)
+		}
+		app.ai.Identifier = classifier
+
+		wav := append([]byte("RIFF\x24\x00\x00\x00WAVEfmt "), make([]byte, 28)...)
+		rec := httptest.NewRecorder()
+		app.identifyBirdHandler(rec, newIdentifyRequest(t, nil, map[string][]byte{"audio": wav}))
+		if rec.Code != http.StatusNotImplemented {
+			t.Errorf("status = %d, want 501: %s", rec.Code, rec.Body)
+		}
+	})
+}
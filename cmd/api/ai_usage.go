@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sixync/birdlens-be/internal/ai"
+	"github.com/sixync/birdlens-be/internal/response"
+	"github.com/sixync/birdlens-be/internal/store"
+)
+
+const (
+	aiTierFree   = "free"
+	aiTierExBird = "ExBird"
+
+	defaultAIUsageReportDays = 30
+	maxAIUsageReportDays     = 366
+)
+
+var aiMeterKey key = "ai_meter"
+
+// aiMeter collects the model usage of a request admitted by aiQuota.
+type aiMeter struct {
+	mu     sync.Mutex
+	called bool
+	usage  ai.Usage
+}
+
+// AIQuotaResponse is a user's allowance and usage for the current day.
+type AIQuotaResponse struct {
+	Tier    string         `json:"tier"`
+	Limits  store.AILimits `json:"limits"`
+	Usage   *store.AIUsage `json:"usage"`
+	ResetAt time.Time      `json:"reset_at"`
+}
+
+// AIUsageReportResponse is the AI usage of all users over a period.
+type AIUsageReportResponse struct {
+	From  string                                   `json:"from"`
+	To    string                                   `json:"to"`
+	Days  []*store.AIUsage                         `json:"days"`
+	Users *store.PaginatedList[*store.UserAIUsage] `json:"users"`
+}
+
+// aiDay returns the UTC day usage is counted on and when it ends.
+func aiDay(now time.Time) (day, resetAt time.Time) {
+	now = now.UTC()
+	day = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return day, day.AddDate(0, 0, 1)
+}
+
+// aiLimits returns the user's subscription tier and its limits.
+func (app *application) aiLimits(ctx context.Context, user *store.User) (string, store.AILimits, error) {
+	subscription, err := app.store.Subscriptions.GetUserSubscriptionByEmail(ctx, user.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", store.AILimits{}, err
+	}
+	if subscription != nil && subscription.Name == aiTierExBird {
+		return aiTierExBird, app.config.ai.exBirdLimits, nil
+	}
+	return aiTierFree, app.config.ai.freeLimits, nil
+}
+
+// aiQuota admits a request to an AI feature while the user's daily quota
+// lasts, and answers 429 once it is used up.
+func (app *application) aiQuota(feature string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := app.getUserFromFirebaseClaimsCtx(r)
+			if user == nil {
+				app.unauthorized(w, r)
+				return
+			}
+
+			r, settle, ok := app.reserveAI(w, r, user, feature)
+			if !ok {
+				return
+			}
+			next.ServeHTTP(w, r)
+			settle()
+		})
+	}
+}
+
+// reserveAI counts a call to feature against the user's daily quota and
+// sends the remaining quota in X-AI-Quota-* headers. Once the quota is used
+// up it answers 429 and returns false. Otherwise it returns the request
+// with a meter for meterAI, and settle, to be called when the request is
+// done with the model: the call is given back when the model was never
+// reached, e.g. on invalid input; otherwise the metered tokens are added to
+// the day's usage.
+func (app *application) reserveAI(w http.ResponseWriter, r *http.Request, user *store.User, feature string) (*http.Request, func(), bool) {
+	_, limits, err := app.aiLimits(r.Context(), user)
+	if err != nil {
+		app.serverError(w, r, err)
+		return r, nil, false
+	}
+
+	day, resetAt := aiDay(time.Now())
+	usage, admitted, err := app.store.AIUsage.Reserve(r.Context(), user.Id, day, feature, limits)
+	if err != nil {
+		app.serverError(w, r, err)
+		return r, nil, false
+	}
+
+	headers := w.Header()
+	headers.Set("X-AI-Quota-Limit", strconv.Itoa(limits.Calls(feature)))
+	headers.Set("X-AI-Quota-Remaining", strconv.Itoa(max(limits.Calls(feature)-usage.Calls(feature), 0)))
+	headers.Set("X-AI-Quota-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+	headers.Set("X-AI-Tokens-Remaining", strconv.FormatInt(max(int64(limits.DailyTokens)-usage.Tokens(), 0), 10))
+
+	if !admitted {
+		retryAfter := http.Header{}
+		retryAfter.Set("Retry-After", strconv.Itoa(int(time.Until(resetAt).Seconds())+1))
+		message := fmt.Sprintf("You have used your daily AI quota. It resets at %s.", resetAt.Format(time.RFC3339))
+		app.errorMessage(w, r, http.StatusTooManyRequests, message, retryAfter)
+		return r, nil, false
+	}
+
+	meter := &aiMeter{}
+	settle := func() {
+		// The request may be over; its context must not cut the
+		// accounting short.
+		ctx := context.Background()
+		meter.mu.Lock()
+		called, used := meter.called, meter.usage
+		meter.mu.Unlock()
+		var err error
+		if !called {
+			err = app.store.AIUsage.Release(ctx, user.Id, day, feature)
+		} else if used != (ai.Usage{}) {
+			err = app.store.AIUsage.AddTokens(ctx, user.Id, day, used.InputTokens, used.OutputTokens)
+		}
+		if err != nil {
+			slog.Error("Failed to record AI usage", "user_id", user.Id, "feature", feature, "error", err)
+		}
+	}
+	return r.WithContext(context.WithValue(r.Context(), aiMeterKey, meter)), settle, true
+}
+
+// metered reports whether the request's AI usage is metered, i.e. it was
+// admitted by aiQuota or reserveAI.
+func metered(r *http.Request) bool {
+	_, ok := r.Context().Value(aiMeterKey).(*aiMeter)
+	return ok
+}
+
+// meterAI records that the request called the model and what it cost.
+func (app *application) meterAI(r *http.Request, usage ai.Usage) {
+	meter, ok := r.Context().Value(aiMeterKey).(*aiMeter)
+	if !ok {
+		return
+	}
+	meter.mu.Lock()
+	defer meter.mu.Unlock()
+	meter.called = true
+	meter.usage = meter.usage.Add(usage)
+}
+
+func (app *application) getAIQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	tier, limits, err := app.aiLimits(r.Context(), user)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	day, resetAt := aiDay(time.Now())
+	usage, err := app.store.AIUsage.Get(r.Context(), user.Id, day)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, AIQuotaResponse{Tier: tier, Limits: limits, Usage: usage, ResetAt: resetAt}, false, "AI quota retrieved successfully")
+}
+
+// getAIUsageReportHandler reports AI usage per day and per user between
+// from and to (YYYY-MM-DD, inclusive), by default over the last 30 days.
+func (app *application) getAIUsageReportHandler(w http.ResponseWriter, r *http.Request) {
+	today, _ := aiDay(time.Now())
+	from, to := today.AddDate(0, 0, -(defaultAIUsageReportDays-1)), today
+
+	query := r.URL.Query()
+	var err error
+	if v := query.Get("from"); v != "" {
+		if from, err = time.Parse(time.DateOnly, v); err != nil {
+			app.badRequest(w, r, errors.New("from must be a date like 2006-01-02"))
+			return
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if to, err = time.Parse(time.DateOnly, v); err != nil {
+			app.badRequest(w, r, errors.New("to must be a date like 2006-01-02"))
+			return
+		}
+	}
+	if to.Before(from) || to.Sub(from) > maxAIUsageReportDays*24*time.Hour {
+		app.badRequest(w, r, fmt.Errorf("from must be before to and at most %d days apart", maxAIUsageReportDays))
+		return
+	}
+
+	days, err := app.store.AIUsage.GetDailyTotals(r.Context(), from, to)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	limit, offset := getPaginateFromCtx(r)
+	users, err := app.store.AIUsage.GetUserTotals(r.Context(), from, to, limit, offset)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	report := AIUsageReportResponse{
+		From:  from.Format(time.DateOnly),
+		To:    to.Format(time.DateOnly),
+		Days:  days,
+		Users: users,
+	}
+	response.JSON(w, http.StatusOK, report, false, "AI usage report retrieved successfully")
+}
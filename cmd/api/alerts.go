@@ -0,0 +1,495 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/sixync/birdlens-be/internal/ebird"
+	"github.com/sixync/birdlens-be/internal/request"
+	"github.com/sixync/birdlens-be/internal/response"
+	"github.com/sixync/birdlens-be/internal/store"
+	"github.com/sixync/birdlens-be/internal/validator"
+)
+
+const (
+	rareBirdAlertInterval = 15 * time.Minute
+	// rareBirdAlertWindow is how far back sightings raise alerts, and how
+	// long an alert held back by quiet hours or the daily cap stays deliverable.
+	rareBirdAlertWindow = 24 * time.Hour
+	// rareBirdDigestInterval is how often a user is emailed a digest.
+	rareBirdDigestInterval = 24 * time.Hour
+	// alertBookmarkRadiusKm is how close to a bookmarked hotspot a post must
+	// be to raise an alert; eBird sightings are matched by hotspot instead.
+	alertBookmarkRadiusKm = 5
+	// maxAlertHotspots bounds the bookmarked hotspots polled per user.
+	maxAlertHotspots = 25
+
+	notificationTypeRareBirdAlert = "rare_bird_alert"
+)
+
+// UpdateAlertSettingsRequest is checked by updateAlertSettingsHandler; the
+// validator's rules only measure strings.
+type UpdateAlertSettingsRequest struct {
+	Enabled         bool     `json:"enabled"`
+	NotifyNotable   bool     `json:"notify_notable"`
+	UseBookmarks    bool     `json:"use_bookmarks"`
+	HomeLatitude    *float64 `json:"home_latitude"`
+	HomeLongitude   *float64 `json:"home_longitude"`
+	HomeRadiusKm    int      `json:"home_radius_km"`
+	EmailDigest     bool     `json:"email_digest"`
+	QuietHoursStart *int     `json:"quiet_hours_start"`
+	QuietHoursEnd   *int     `json:"quiet_hours_end"`
+	Timezone        string   `json:"timezone" validate:"required"`
+	MaxAlertsPerDay int      `json:"max_alerts_per_day"`
+}
+
+// validate checks the ranges of the settings and that paired settings are
+// set together.
+func (req *UpdateAlertSettingsRequest) validate() error {
+	if (req.HomeLatitude == nil) != (req.HomeLongitude == nil) {
+		return errors.New("home_latitude and home_longitude must be set together")
+	}
+	if req.HomeLatitude != nil && !validator.Between(*req.HomeLatitude, -90, 90) {
+		return errors.New("home_latitude must be between -90 and 90")
+	}
+	if req.HomeLongitude != nil && !validator.Between(*req.HomeLongitude, -180, 180) {
+		return errors.New("home_longitude must be between -180 and 180")
+	}
+	if !validator.Between(req.HomeRadiusKm, 1, 50) {
+		return errors.New("home_radius_km must be between 1 and 50")
+	}
+	if (req.QuietHoursStart == nil) != (req.QuietHoursEnd == nil) {
+		return errors.New("quiet_hours_start and quiet_hours_end must be set together")
+	}
+	if req.QuietHoursStart != nil && (!validator.Between(*req.QuietHoursStart, 0, 23) || !validator.Between(*req.QuietHoursEnd, 0, 23)) {
+		return errors.New("quiet_hours_start and quiet_hours_end must be hours between 0 and 23")
+	}
+	if !validator.Between(req.MaxAlertsPerDay, 1, 100) {
+		return errors.New("max_alerts_per_day must be between 1 and 100")
+	}
+	if _, err := time.LoadLocation(req.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone: %v", err)
+	}
+	return nil
+}
+
+func (app *application) getAlertSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	settings, err := app.store.Alerts.GetSettings(r.Context(), user.Id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, settings, false, "get successful")
+}
+
+// updateAlertSettingsHandler replaces the current user's alert settings.
+func (app *application) updateAlertSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	var req UpdateAlertSettingsRequest
+	if err := request.DecodeJSON(w, r, &req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	if err := validator.Validate(req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	if err := req.validate(); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	settings := &store.AlertSettings{
+		UserID:          user.Id,
+		Enabled:         req.Enabled,
+		NotifyNotable:   req.NotifyNotable,
+		UseBookmarks:    req.UseBookmarks,
+		HomeLatitude:    req.HomeLatitude,
+		HomeLongitude:   req.HomeLongitude,
+		HomeRadiusKm:    req.HomeRadiusKm,
+		EmailDigest:     req.EmailDigest,
+		QuietHoursStart: req.QuietHoursStart,
+		QuietHoursEnd:   req.QuietHoursEnd,
+		Timezone:        req.Timezone,
+		MaxAlertsPerDay: req.MaxAlertsPerDay,
+	}
+	if err := app.store.Alerts.SaveSettings(r.Context(), settings); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, settings, false, "alert settings updated successfully")
+}
+
+func (app *application) getWatchlistHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	watchlist, err := app.store.Alerts.GetWatchlist(r.Context(), user.Id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, watchlist, false, "get successful")
+}
+
+func (app *application) addToWatchlistHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	ctx := r.Context()
+	species, err := app.store.Species.GetByCode(ctx, r.PathValue("code"))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFound(w, r)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	if err := app.store.Alerts.AddToWatchlist(ctx, user.Id, species.SpeciesCode); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, nil, false, "species added to watchlist")
+}
+
+func (app *application) removeFromWatchlistHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	err := app.store.Alerts.RemoveFromWatchlist(r.Context(), user.Id, r.PathValue("code"))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFound(w, r)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, nil, false, "species removed from watchlist")
+}
+
+func (app *application) getAlertsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	limit, offset := getPaginateFromCtx(r)
+	alerts, err := app.store.Alerts.GetAlerts(r.Context(), user.Id, limit, offset)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, alerts, false, "get successful")
+}
+
+// startRareBirdAlerts periodically raises and delivers rare bird alerts.
+func (app *application) startRareBirdAlerts() {
+	go func() {
+		ticker := time.NewTicker(rareBirdAlertInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			app.runRareBirdAlerts(context.Background())
+		}
+	}()
+	slog.Info("Started rare bird alerts", "interval", rareBirdAlertInterval)
+}
+
+// runRareBirdAlerts raises alerts from eBird's recent sightings and from new
+// posts, then delivers them. Sightings seen by an earlier run are skipped by
+// the alerts' dedupe keys, so runs can overlap the same window.
+func (app *application) runRareBirdAlerts(ctx context.Context) {
+	now := time.Now()
+
+	settings, err := app.store.Alerts.ListEnabledSettings(ctx)
+	if err != nil {
+		app.logger.Error("failed to list alert settings", "error", err)
+		return
+	}
+
+	if app.config.eBird.apiKey != "" {
+		for _, s := range settings {
+			app.raiseEbirdAlerts(ctx, s)
+		}
+	}
+
+	created, err := app.store.Alerts.CreatePostAlerts(ctx, now.Add(-rareBirdAlertWindow), alertBookmarkRadiusKm)
+	if err != nil {
+		app.logger.Error("failed to raise alerts for posts", "error", err)
+	} else if created > 0 {
+		slog.Info("Raised rare bird alerts for posts", "count", created)
+	}
+
+	for _, s := range settings {
+		if err := app.deliverAlerts(ctx, s, now); err != nil {
+			app.logger.Error("failed to deliver rare bird alerts", "user_id", s.UserID, "error", err)
+		}
+	}
+}
+
+// raiseEbirdAlerts raises alerts for the notable sightings, and the sightings
+// of watchlisted species, of the last day at the user's bookmarked hotspots
+// and within their home radius. A failing eBird request skips that place.
+func (app *application) raiseEbirdAlerts(ctx context.Context, s *store.AlertSettings) {
+	watchlist, err := app.store.Alerts.GetWatchlist(ctx, s.UserID)
+	if err != nil {
+		app.logger.Error("failed to get watchlist", "user_id", s.UserID, "error", err)
+		return
+	}
+	watched := make(map[string]bool, len(watchlist))
+	for _, entry := range watchlist {
+		watched[entry.SpeciesCode] = true
+	}
+	if !s.NotifyNotable && len(watched) == 0 {
+		return
+	}
+
+	opts := ebird.ObservationOptions{Back: 1}
+	// Notable sightings are raised first, so a watchlisted species that is
+	// also notable is raised as notable.
+	var fetches []func() ([]ebird.Observation, string, error)
+	addFetch := func(reason string, fetch func() ([]ebird.Observation, error)) {
+		fetches = append(fetches, func() ([]ebird.Observation, string, error) {
+			observations, err := fetch()
+			return observations, reason, err
+		})
+	}
+
+	if s.UseBookmarks {
+		bookmarks, err := app.store.Bookmarks.GetByUserID(ctx, s.UserID, maxAlertHotspots, 0)
+		if err != nil {
+			app.logger.Error("failed to get bookmarks", "user_id", s.UserID, "error", err)
+			return
+		}
+		for _, bookmark := range bookmarks.Items {
+			locId := bookmark.HotspotLocationId
+			if s.NotifyNotable {
+				addFetch(store.AlertReasonNotable, func() ([]ebird.Observation, error) {
+					return app.ebirdClient.NotableObservations(ctx, locId, opts)
+				})
+			}
+			if len(watched) > 0 {
+				addFetch(store.AlertReasonWatchlist, func() ([]ebird.Observation, error) {
+					return app.ebirdClient.RecentObservations(ctx, locId, opts)
+				})
+			}
+		}
+	}
+
+	if s.HomeLatitude != nil && s.HomeLongitude != nil {
+		// Rounded like the nearby hotspots, so neighbours share cached results.
+		lat := math.Round(*s.HomeLatitude*100) / 100
+		lng := math.Round(*s.HomeLongitude*100) / 100
+		if s.NotifyNotable {
+			addFetch(store.AlertReasonNotable, func() ([]ebird.Observation, error) {
+				return app.ebirdClient.NearbyNotableObservations(ctx, lat, lng, s.HomeRadiusKm, opts)
+			})
+		}
+		if len(watched) > 0 {
+			addFetch(store.AlertReasonWatchlist, func() ([]ebird.Observation, error) {
+				return app.ebirdClient.NearbyObservations(ctx, lat, lng, s.HomeRadiusKm, opts)
+			})
+		}
+	}
+
+	for _, fetch := range fetches {
+		observations, reason, err := fetch()
+		if err != nil {
+			app.logger.Warn("failed to fetch eBird sightings for alerts", "user_id", s.UserID, "error", err)
+			continue
+		}
+		for _, obs := range observations {
+			if reason == store.AlertReasonWatchlist && !watched[obs.SpeciesCode] {
+				continue
+			}
+			if _, err := app.store.Alerts.CreateAlert(ctx, ebirdAlert(s.UserID, reason, obs)); err != nil {
+				app.logger.Error("failed to raise rare bird alert", "user_id", s.UserID, "error", err)
+			}
+		}
+	}
+}
+
+// ebirdAlert builds the alert for an eBird sighting. A species reported at
+// one place on one day is one sighting, however many checklists report it.
+// eBird reports local times without a zone; they are kept as UTC.
+func ebirdAlert(userID int64, reason string, obs ebird.Observation) *store.RareBirdAlert {
+	observedAt, err := time.Parse("2006-01-02 15:04", obs.ObsDt)
+	if err != nil {
+		observedAt, _ = time.Parse("2006-01-02", obs.ObsDt)
+	}
+
+	locId := obs.LocID
+	return &store.RareBirdAlert{
+		UserID:       userID,
+		DedupeKey:    fmt.Sprintf("ebird:%s:%s:%s", obs.SpeciesCode, obs.LocID, observedAt.Format("2006-01-02")),
+		Source:       store.AlertSourceEbird,
+		Reason:       reason,
+		SpeciesCode:  obs.SpeciesCode,
+		CommonName:   obs.CommonName,
+		LocationName: obs.LocName,
+		LocID:        &locId,
+		Latitude:     obs.Lat,
+		Longitude:    obs.Lng,
+		HowMany:      obs.HowMany,
+		ObservedAt:   observedAt,
+	}
+}
+
+// deliverAlerts notifies a user of their pending alerts, up to their daily
+// cap, and emails the daily digest when it is due. Nothing is delivered
+// during quiet hours; held alerts go out when they end. Alerts over the cap
+// are only emailed.
+func (app *application) deliverAlerts(ctx context.Context, s *store.AlertSettings, now time.Time) error {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	if inQuietHours(s, now.In(loc)) {
+		return nil
+	}
+
+	pending, err := app.store.Alerts.GetPendingAlerts(ctx, s.UserID, now.Add(-rareBirdAlertWindow))
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		notified, err := app.store.Alerts.CountNotifiedSince(ctx, s.UserID, now.Add(-24*time.Hour))
+		if err != nil {
+			return err
+		}
+
+		ids := []int64{}
+		for _, alert := range pending[:max(0, min(len(pending), s.MaxAlertsPerDay-notified))] {
+			notification := &store.Notification{
+				UserID:  s.UserID,
+				Type:    notificationTypeRareBirdAlert,
+				Message: alertMessage(alert, loc),
+			}
+			if err := app.store.Notifications.Create(ctx, notification); err != nil {
+				return err
+			}
+			ids = append(ids, alert.ID)
+		}
+		if err := app.store.Alerts.MarkNotified(ctx, ids, now); err != nil {
+			return err
+		}
+	}
+
+	if !s.EmailDigest || (s.LastDigestAt != nil && now.Sub(*s.LastDigestAt) < rareBirdDigestInterval) {
+		return nil
+	}
+	return app.sendAlertDigest(ctx, s, loc, now)
+}
+
+// sendAlertDigest queues an email of the alerts of the last day that have
+// not been emailed yet.
+func (app *application) sendAlertDigest(ctx context.Context, s *store.AlertSettings, loc *time.Location, now time.Time) error {
+	alerts, err := app.store.Alerts.GetUndigestedAlerts(ctx, s.UserID, now.Add(-rareBirdDigestInterval))
+	if err != nil || len(alerts) == 0 {
+		return err
+	}
+
+	user, err := app.store.Users.GetById(ctx, s.UserID)
+	if err != nil {
+		return err
+	}
+
+	messages := make([]string, len(alerts))
+	ids := make([]int64, len(alerts))
+	for i, alert := range alerts {
+		messages[i] = alertMessage(alert, loc)
+		ids[i] = alert.ID
+	}
+
+	data := struct {
+		Username string
+		Alerts   []string
+	}{
+		Username: user.Username,
+		Alerts:   messages,
+	}
+
+	slog.Info("Queuing rare bird digest email job", "recipient", user.Email, "alert_count", len(alerts))
+	JobQueue <- EmailJob{
+		Recipient: user.Email,
+		Data:      data,
+		Patterns:  []string{"rare_bird_digest.tmpl"},
+	}
+
+	return app.store.Alerts.MarkDigested(ctx, s.UserID, ids, now)
+}
+
+// inQuietHours reports whether local falls in the quiet hours, which may
+// wrap past midnight (22 to 6).
+func inQuietHours(s *store.AlertSettings, local time.Time) bool {
+	if s.QuietHoursStart == nil || s.QuietHoursEnd == nil || *s.QuietHoursStart == *s.QuietHoursEnd {
+		return false
+	}
+
+	start, end, hour := *s.QuietHoursStart, *s.QuietHoursEnd, local.Hour()
+	if start < end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
+
+func alertMessage(alert *store.RareBirdAlert, loc *time.Location) string {
+	label := "Rare bird alert"
+	if alert.Reason == store.AlertReasonWatchlist {
+		label = "Watchlist alert"
+	}
+
+	name := alert.CommonName
+	if alert.HowMany != nil {
+		name = fmt.Sprintf("%s (%d)", name, *alert.HowMany)
+	}
+
+	place := alert.LocationName
+	if place == "" {
+		place = fmt.Sprintf("%.2f, %.2f", alert.Latitude, alert.Longitude)
+	}
+
+	// eBird times are stored as UTC but are local to the sighting.
+	observedAt := alert.ObservedAt.UTC()
+	if alert.Source == store.AlertSourcePost {
+		observedAt = alert.ObservedAt.In(loc)
+	}
+
+	return fmt.Sprintf("%s: %s at %s on %s", label, name, place, observedAt.Format("Jan 2, 15:04"))
+}
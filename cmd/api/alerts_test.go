@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/sixync/birdlens-be/internal/validator"
+)
+
+func TestUpdateAlertSettingsRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{"minimal", `{"home_radius_km": 10, "max_alerts_per_day": 5, "timezone": "Asia/Ho_Chi_Minh"}`, false},
+		{"home and quiet hours", `{"home_latitude": 10.77, "home_longitude": 106.7, "home_radius_km": 50, "quiet_hours_start": 22, "quiet_hours_end": 6, "max_alerts_per_day": 100, "timezone": "UTC"}`, false},
+		{"latitude alone", `{"home_latitude": 10.77, "home_radius_km": 10, "max_alerts_per_day": 5, "timezone": "UTC"}`, true},
+		{"latitude out of range", `{"home_latitude": 91, "home_longitude": 106.7, "home_radius_km": 10, "max_alerts_per_day": 5, "timezone": "UTC"}`, true},
+		{"longitude out of range", `{"home_latitude": 10.77, "home_longitude": -181, "home_radius_km": 10, "max_alerts_per_day": 5, "timezone": "UTC"}`, true},
+		{"no radius", `{"max_alerts_per_day": 5, "timezone": "UTC"}`, true},
+		{"radius too large", `{"home_radius_km": 51, "max_alerts_per_day": 5, "timezone": "UTC"}`, true},
+		{"quiet hours start alone", `{"home_radius_km": 10, "quiet_hours_start": 22, "max_alerts_per_day": 5, "timezone": "UTC"}`, true},
+		{"quiet hour out of range", `{"home_radius_km": 10, "quiet_hours_start": 22, "quiet_hours_end": 24, "max_alerts_per_day": 5, "timezone": "UTC"}`, true},
+		{"too many alerts", `{"home_radius_km": 10, "max_alerts_per_day": 101, "timezone": "UTC"}`, true},
+		{"unknown timezone", `{"home_radius_km": 10, "max_alerts_per_day": 5, "timezone": "Mars/Olympus_Mons"}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req UpdateAlertSettingsRequest
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatal(err)
+			}
+			err := validator.Validate(req)
+			if err == nil {
+				err = req.validate()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validating %s error = %v, want error %t", tt.body, err, tt.wantErr)
+			}
+		})
+	}
+}
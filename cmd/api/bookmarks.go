@@ -1,32 +1,76 @@
 package main
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
 
+	"github.com/sixync/birdlens-be/internal/ebird"
 	"github.com/sixync/birdlens-be/internal/request"
 	"github.com/sixync/birdlens-be/internal/response"
 	"github.com/sixync/birdlens-be/internal/store"
+	"github.com/sixync/birdlens-be/internal/validator"
+)
+
+const (
+	defaultTrendingDays = 7
+	maxTrendingDays     = 30
+
+	maxBookmarkNoteLength = 500
 )
 
 type CreateBookmarkRequest struct {
-	HotspotLocationID string `json:"hotspot_location_id" validate:"required"`
+	HotspotLocationID string  `json:"hotspot_location_id" validate:"required"`
+	Note              *string `json:"note"`
+}
+
+type UpdateBookmarkRequest struct {
+	Note *string `json:"note"`
 }
 
-func (app *application) CreateBookmarkHandler(w http.ResponseWriter, r *http.Request) {
+// validateBookmarkNote checks the length of an optional note, which the
+// validator's string rules cannot see through a pointer.
+func validateBookmarkNote(note *string) error {
+	if note != nil && utf8.RuneCountInString(*note) > maxBookmarkNoteLength {
+		return fmt.Errorf("note should be at most %d characters", maxBookmarkNoteLength)
+	}
+	return nil
+}
+
+// createBookmarkHandler bookmarks a hotspot for the current user. The
+// hotspot's name and position are copied from eBird when it answers; an
+// eBird outage does not stop the bookmark from being saved.
+func (app *application) createBookmarkHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	user, err := getUserFromCtx(r)
-	if err != nil {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
 		app.unauthorized(w, r)
+		return
 	}
 
 	var req CreateBookmarkRequest
 	if err := request.DecodeJSON(w, r, &req); err != nil {
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+		app.badRequest(w, r, err)
 		return
-
 	}
+	if err := validator.Validate(req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	if !locIdPattern.MatchString(req.HotspotLocationID) {
+		app.badRequest(w, r, errors.New("hotspot_location_id must be an eBird location id such as L123456"))
+		return
+	}
+	if err := validateBookmarkNote(req.Note); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
 	// check if user already has a bookmark for this hotspot location
 	exists, err := app.store.Bookmarks.Exists(ctx, user.Id, req.HotspotLocationID)
 	if err != nil {
@@ -34,30 +78,50 @@ func (app *application) CreateBookmarkHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 	if exists {
-		app.badRequest(w, r, fmt.Errorf("user already has a bookmark for this hotspot location"))
+		app.errorMessage(w, r, http.StatusConflict, "user already has a bookmark for this hotspot location", nil)
 		return
 	}
 
 	bookmark := req.toBookmark(user.Id)
 
-	if err := app.store.Bookmarks.Create(ctx, bookmark); err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	info, err := app.ebirdClient.HotspotInfo(ctx, req.HotspotLocationID)
+	switch {
+	case errors.Is(err, ebird.ErrNotFound):
+		app.badRequest(w, r, fmt.Errorf("hotspot %s does not exist", req.HotspotLocationID))
+		return
+	case err != nil:
+		app.logger.Warn("could not fetch hotspot for bookmark", "locId", req.HotspotLocationID, "error", err)
+	default:
+		bookmark.HotspotName = &info.Name
+		bookmark.Latitude = &info.Lat
+		bookmark.Longitude = &info.Lng
+	}
+
+	// A concurrent request can bookmark the hotspot after the check above.
+	err = app.store.Bookmarks.Create(ctx, bookmark)
+	switch {
+	case errors.Is(err, store.ErrBookmarkExists):
+		app.errorMessage(w, r, http.StatusConflict, "user already has a bookmark for this hotspot location", nil)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
 		return
 	}
 
 	response.JSON(w, http.StatusCreated, bookmark, false, "Bookmark created successfully")
 }
 
-func (app *application) GetByUserIdHandler(w http.ResponseWriter, r *http.Request) {
+func (app *application) getMyBookmarksHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	user, err := getUserFromCtx(r)
-	if err != nil {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
 		app.unauthorized(w, r)
 		return
 	}
 
-	bookmarks, err := app.store.Bookmarks.GetByUserID(ctx, user.Id)
+	limit, offset := getPaginateFromCtx(r)
+	bookmarks, err := app.store.Bookmarks.GetByUserID(ctx, user.Id, limit, offset)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
@@ -66,9 +130,103 @@ func (app *application) GetByUserIdHandler(w http.ResponseWriter, r *http.Reques
 	response.JSON(w, http.StatusOK, bookmarks, false, "Bookmarks retrieved successfully")
 }
 
+func (app *application) getMyBookmarkHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	bookmark, err := app.store.Bookmarks.Get(r.Context(), user.Id, r.PathValue("locId"))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFound(w, r)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, bookmark, false, "Bookmark retrieved successfully")
+}
+
+func (app *application) updateMyBookmarkHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	var req UpdateBookmarkRequest
+	if err := request.DecodeJSON(w, r, &req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	if err := validateBookmarkNote(req.Note); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	bookmark, err := app.store.Bookmarks.UpdateNote(r.Context(), user.Id, r.PathValue("locId"), req.Note)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFound(w, r)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, bookmark, false, "Bookmark updated successfully")
+}
+
+func (app *application) deleteMyBookmarkHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	err := app.store.Bookmarks.Delete(r.Context(), user.Id, r.PathValue("locId"))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFound(w, r)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, nil, false, "Bookmark deleted successfully")
+}
+
+// getTrendingHotspotsHandler ranks the hotspots bookmarked in the last days
+// (default 7, at most 30), favouring recent bookmarks.
+func (app *application) getTrendingHotspotsHandler(w http.ResponseWriter, r *http.Request) {
+	days := defaultTrendingDays
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		var err error
+		days, err = strconv.Atoi(daysStr)
+		if err != nil || days < 1 || days > maxTrendingDays {
+			app.badRequest(w, r, fmt.Errorf("days must be between 1 and %d", maxTrendingDays))
+			return
+		}
+	}
+
+	limit, offset := getPaginateFromCtx(r)
+	hotspots, err := app.store.Bookmarks.GetTrendingBookmarks(r.Context(), time.Duration(days)*24*time.Hour, limit, offset)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, hotspots, false, "get successful")
+}
+
 func (req *CreateBookmarkRequest) toBookmark(userID int64) *store.Bookmark {
 	return &store.Bookmark{
 		UserID:            userID,
 		HotspotLocationId: req.HotspotLocationID,
+		Note:              req.Note,
 	}
 }
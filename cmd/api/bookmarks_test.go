@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sixync/birdlens-be/internal/ebird"
+	"github.com/sixync/birdlens-be/internal/jwt"
+	"github.com/sixync/birdlens-be/internal/store"
+)
+
+func TestCreateBookmarkHandler(t *testing.T) {
+	fake := ebird.NewFakeServer()
+	defer fake.Close()
+	fake.AddHotspot(ebird.Hotspot{LocID: "L100", LocName: "Cat Tien"}, ebird.HotspotInfo{LocID: "L100", Name: "Cat Tien", Lat: 11.42, Lng: 107.43})
+
+	app := newTestApplication(t)
+	app.ebirdClient = fake.Client()
+	bookmarks := &fakeBookmarks{bookmarks: map[string]*store.Bookmark{}}
+	app.store = &store.Storage{
+		Users:     &fakeUsers{users: map[string]*store.User{"uid-1": {Id: 1}}},
+		Bookmarks: bookmarks,
+	}
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/bookmarks", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req = req.WithContext(context.WithValue(req.Context(), UserClaimsKey, &jwt.FirebaseClaims{Uid: "uid-1"}))
+		rec := httptest.NewRecorder()
+		app.createBookmarkHandler(rec, req)
+		return rec
+	}
+
+	if rec := create(`{"hotspot_location_id": "L100", "note": "` + strings.Repeat("é", maxBookmarkNoteLength+1) + `"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("status of a bookmark with a long note = %d, want 400: %s", rec.Code, rec.Body)
+	}
+
+	rec := create(`{"hotspot_location_id": "L100", "note": "` + strings.Repeat("é", maxBookmarkNoteLength) + `"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", rec.Code, rec.Body)
+	}
+	var bookmark store.Bookmark
+	decodeResponse(t, rec, &bookmark)
+	if bookmark.HotspotName == nil || *bookmark.HotspotName != "Cat Tien" || bookmark.Latitude == nil || *bookmark.Latitude != 11.42 {
+		t.Errorf("bookmark = %+v, want the hotspot's name and position from eBird", bookmark)
+	}
+
+	if rec := create(`{"hotspot_location_id": "L100"}`); rec.Code != http.StatusConflict {
+		t.Errorf("status of a second bookmark = %d, want 409: %s", rec.Code, rec.Body)
+	}
+
+	// A request that passes the existence check while another one creates
+	// the bookmark gets a conflict too, not a server error.
+	bookmarks.staleExists = true
+	if rec := create(`{"hotspot_location_id": "L100"}`); rec.Code != http.StatusConflict {
+		t.Errorf("status of a racing bookmark = %d, want 409: %s", rec.Code, rec.Body)
+	}
+}
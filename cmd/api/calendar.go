@@ -0,0 +1,190 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sixync/birdlens-be/internal/ical"
+	"github.com/sixync/birdlens-be/internal/response"
+	"github.com/sixync/birdlens-be/internal/store"
+)
+
+type CalendarFeedResponse struct {
+	URL string `json:"url"`
+}
+
+// getEventICSHandler exports a single event as an iCalendar file.
+func (app *application) getEventICSHandler(w http.ResponseWriter, r *http.Request) {
+	event, err := getEventFromCtx(r)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if event == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	cal := ical.NewCalendar(event.Title)
+	cal.Add(app.eventToICal(event))
+
+	filename := fmt.Sprintf("birdlens-event-%d.ics", event.ID)
+	app.writeICalendar(w, r, cal, filename)
+}
+
+// getCalendarFeedURLHandler returns the current user's subscribed calendar URL,
+// issuing a token on first use.
+func (app *application) getCalendarFeedURLHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	ctx := r.Context()
+	token, err := app.store.CalendarFeeds.GetToken(ctx, user.Id)
+	if errors.Is(err, sql.ErrNoRows) {
+		token, err = app.issueCalendarFeedToken(r, user.Id)
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, CalendarFeedResponse{URL: app.calendarFeedURL(token)}, false, "calendar feed url retrieved successfully")
+}
+
+// resetCalendarFeedURLHandler replaces the user's feed token, revoking the old URL.
+func (app *application) resetCalendarFeedURLHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	token, err := app.issueCalendarFeedToken(r, user.Id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, CalendarFeedResponse{URL: app.calendarFeedURL(token)}, false, "calendar feed url reset successfully")
+}
+
+// getCalendarFeedHandler serves the subscribed feed. It is public; the token in
+// the path identifies the user.
+func (app *application) getCalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.PathValue("token")
+	if token == "" {
+		app.notFound(w, r)
+		return
+	}
+
+	ctx := r.Context()
+	userId, err := app.store.CalendarFeeds.GetUserIDByToken(ctx, token)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFound(w, r)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	events, err := app.store.Events.GetRSVPedByUserID(ctx, userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	tours, err := app.store.Tours.GetBookedByUserID(ctx, userId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	cal := ical.NewCalendar("Birdlens")
+	for _, event := range events {
+		cal.Add(app.eventToICal(event))
+	}
+	for _, tour := range tours {
+		calEvent, err := app.tourToICal(tour)
+		if err != nil {
+			app.logger.Warn("skipping tour with unparseable dates in calendar feed", "tour_id", tour.ID, "error", err)
+			continue
+		}
+		cal.Add(calEvent)
+	}
+
+	app.writeICalendar(w, r, cal, "birdlens.ics")
+}
+
+func (app *application) issueCalendarFeedToken(r *http.Request, userId int64) (string, error) {
+	token, err := app.tokenMaker.CreateRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := app.store.CalendarFeeds.SetToken(r.Context(), userId, token); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (app *application) calendarFeedURL(token string) string {
+	return fmt.Sprintf("%s/calendar/feeds/%s.ics", app.config.baseURL, token)
+}
+
+func (app *application) eventToICal(event *store.Event) ical.Event {
+	return ical.Event{
+		UID:          fmt.Sprintf("event-%d@birdlens", event.ID),
+		Summary:      event.Title,
+		Description:  event.Description,
+		URL:          fmt.Sprintf("%s/events/%d", app.config.frontEndUrl, event.ID),
+		Start:        event.StartDate,
+		End:          event.EndDate,
+		AllDay:       true,
+		Created:      event.CreatedAt,
+		LastModified: event.UpdatedAt,
+	}
+}
+
+func (app *application) tourToICal(tour *store.Tour) (ical.Event, error) {
+	start, err := time.Parse(time.RFC3339, tour.StartDate)
+	if err != nil {
+		return ical.Event{}, err
+	}
+	end, err := time.Parse(time.RFC3339, tour.EndDate)
+	if err != nil {
+		return ical.Event{}, err
+	}
+
+	calEvent := ical.Event{
+		UID:          fmt.Sprintf("tour-%d@birdlens", tour.ID),
+		Summary:      tour.Name,
+		Description:  tour.Description,
+		URL:          fmt.Sprintf("%s/tours/%d", app.config.frontEndUrl, tour.ID),
+		Start:        start,
+		End:          end,
+		Created:      tour.CreatedAt,
+		LastModified: tour.UpdatedAt,
+	}
+	if tour.StoreName != nil {
+		calEvent.Description = fmt.Sprintf("%s\n\nOrganised by %s", tour.Description, *tour.StoreName)
+	}
+	return calEvent, nil
+}
+
+func (app *application) writeICalendar(w http.ResponseWriter, r *http.Request, cal *ical.Calendar, filename string) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := cal.WriteTo(w); err != nil {
+		app.reportServerError(r, err)
+	}
+}
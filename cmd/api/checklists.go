@@ -0,0 +1,465 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sixync/birdlens-be/internal/ebird"
+	"github.com/sixync/birdlens-be/internal/request"
+	"github.com/sixync/birdlens-be/internal/response"
+	"github.com/sixync/birdlens-be/internal/store"
+)
+
+var ChecklistKey key = "checklist"
+
+const maxChecklistSpecies = 1000
+
+type ChecklistSpeciesRequest struct {
+	SpeciesCode  string  `json:"species_code"`
+	Count        *int    `json:"count"`
+	BreedingCode *string `json:"breeding_code"`
+	BehaviorCode *string `json:"behavior_code"`
+	Notes        *string `json:"notes"`
+}
+
+// ChecklistRequest is used for both create and update. On update, omitted
+// fields keep their current value, and a species list, when given, replaces
+// the checklist's observations.
+type ChecklistRequest struct {
+	LocationName      *string                   `json:"location_name"`
+	Latitude          *float64                  `json:"latitude"`
+	Longitude         *float64                  `json:"longitude"`
+	StateCode         *string                   `json:"state_code"`
+	CountryCode       *string                   `json:"country_code"`
+	StartedAt         *string                   `json:"started_at"`
+	DurationMinutes   *int                      `json:"duration_minutes"`
+	DistanceKm        *float64                  `json:"distance_km"`
+	Protocol          *string                   `json:"protocol"`
+	NumberOfObservers *int                      `json:"number_of_observers"`
+	IsComplete        *bool                     `json:"is_complete"`
+	Comments          *string                   `json:"comments"`
+	Species           []ChecklistSpeciesRequest `json:"species"`
+}
+
+func (app *application) createChecklistHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	var req ChecklistRequest
+	if err := request.DecodeJSON(w, r, &req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if req.LocationName == nil || req.StartedAt == nil || req.Protocol == nil {
+		app.badRequest(w, r, errors.New("location_name, started_at and protocol are required"))
+		return
+	}
+	if req.Species == nil {
+		req.Species = []ChecklistSpeciesRequest{}
+	}
+
+	checklist := &store.Checklist{UserID: user.Id, NumberOfObservers: 1}
+	if err := app.applyChecklistRequest(r.Context(), user, checklist, &req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := app.store.Checklists.Create(r.Context(), checklist); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.locationJSON(w, r, http.StatusCreated, checklist, "checklist created successfully")
+}
+
+func (app *application) getChecklistsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	limit, offset := getPaginateFromCtx(r)
+	checklists, err := app.store.Checklists.GetByUserID(r.Context(), user.Id, limit, offset)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.locationJSON(w, r, http.StatusOK, checklists, "checklists retrieved successfully")
+}
+
+func (app *application) getChecklistHandler(w http.ResponseWriter, r *http.Request) {
+	checklist := getChecklistFromCtx(r)
+	if checklist == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	app.locationJSON(w, r, http.StatusOK, checklist, "checklist retrieved successfully")
+}
+
+func (app *application) updateChecklistHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	checklist := getChecklistFromCtx(r)
+	if checklist == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	var req ChecklistRequest
+	if err := request.DecodeJSON(w, r, &req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := app.applyChecklistRequest(r.Context(), user, checklist, &req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	err := app.store.Checklists.Update(r.Context(), checklist, req.Species != nil)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFound(w, r)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.locationJSON(w, r, http.StatusOK, checklist, "checklist updated successfully")
+}
+
+func (app *application) deleteChecklistHandler(w http.ResponseWriter, r *http.Request) {
+	checklist := getChecklistFromCtx(r)
+	if checklist == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	err := app.store.Checklists.Delete(r.Context(), checklist.ID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFound(w, r)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, nil, false, "checklist deleted successfully")
+}
+
+// exportChecklistHandler downloads a single checklist in eBird Record Format.
+// The optional tz query parameter (an IANA zone, default UTC) sets the local
+// time written to the date and start time columns.
+func (app *application) exportChecklistHandler(w http.ResponseWriter, r *http.Request) {
+	checklist := getChecklistFromCtx(r)
+	if checklist == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	loc, err := time.LoadLocation(r.URL.Query().Get("tz"))
+	if err != nil {
+		app.badRequest(w, r, fmt.Errorf("invalid tz: %v", err))
+		return
+	}
+
+	filename := fmt.Sprintf("birdlens-checklist-%d.csv", checklist.ID)
+	app.writeEbirdRecordFormat(w, r, []*store.Checklist{checklist}, loc, filename)
+}
+
+// exportChecklistsHandler downloads all of the user's checklists started
+// between from and to (YYYY-MM-DD, inclusive) in eBird Record Format.
+func (app *application) exportChecklistsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	loc, err := time.LoadLocation(query.Get("tz"))
+	if err != nil {
+		app.badRequest(w, r, fmt.Errorf("invalid tz: %v", err))
+		return
+	}
+
+	from := time.Time{}
+	to := time.Now()
+	if fromStr := query.Get("from"); fromStr != "" {
+		from, err = time.ParseInLocation("2006-01-02", fromStr, loc)
+		if err != nil {
+			app.badRequest(w, r, fmt.Errorf("invalid from: %v", err))
+			return
+		}
+	}
+	if toStr := query.Get("to"); toStr != "" {
+		to, err = time.ParseInLocation("2006-01-02", toStr, loc)
+		if err != nil {
+			app.badRequest(w, r, fmt.Errorf("invalid to: %v", err))
+			return
+		}
+		to = to.Add(24*time.Hour - time.Second)
+	}
+	if to.Before(from) {
+		app.badRequest(w, r, errors.New("to must not be before from"))
+		return
+	}
+
+	checklists, err := app.store.Checklists.GetWithObservationsByUserID(r.Context(), user.Id, from, to)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.writeEbirdRecordFormat(w, r, checklists, loc, "birdlens-checklists.csv")
+}
+
+// writeEbirdRecordFormat writes one row per observation, with species names
+// taken from the local taxonomy.
+func (app *application) writeEbirdRecordFormat(w http.ResponseWriter, r *http.Request, checklists []*store.Checklist, loc *time.Location, filename string) {
+	codes := []string{}
+	for _, checklist := range checklists {
+		for _, obs := range checklist.Observations {
+			codes = append(codes, obs.SpeciesCode)
+		}
+	}
+
+	taxa, err := app.store.Species.GetByCodes(r.Context(), codes)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	rows := []ebird.RecordFormatRow{}
+	for _, checklist := range checklists {
+		for _, obs := range checklist.Observations {
+			row := ebird.RecordFormatRow{
+				CommonName:        obs.SpeciesCode,
+				Count:             obs.Count,
+				LocationName:      checklist.LocationName,
+				Latitude:          checklist.Latitude,
+				Longitude:         checklist.Longitude,
+				ObservedAt:        checklist.StartedAt.In(loc),
+				Protocol:          checklist.Protocol,
+				NumberOfObservers: checklist.NumberOfObservers,
+				DurationMinutes:   checklist.DurationMinutes,
+				AllReported:       checklist.IsComplete,
+				DistanceKm:        checklist.DistanceKm,
+			}
+			if species, ok := taxa[obs.SpeciesCode]; ok {
+				row.CommonName = species.CommonName
+				row.Genus, row.Species, _ = strings.Cut(species.ScientificName, " ")
+			}
+			if obs.Notes != nil {
+				row.SpeciesComments = *obs.Notes
+			}
+			if checklist.StateCode != nil {
+				row.StateCode = *checklist.StateCode
+			}
+			if checklist.CountryCode != nil {
+				row.CountryCode = *checklist.CountryCode
+			}
+			if checklist.Comments != nil {
+				row.ChecklistComments = *checklist.Comments
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	if err := ebird.WriteRecordFormat(w, rows); err != nil {
+		app.reportServerError(r, err)
+	}
+}
+
+// applyChecklistRequest validates the request and copies every provided field
+// onto checklist, then checks the effort fields against the protocol.
+func (app *application) applyChecklistRequest(ctx context.Context, user *store.User, checklist *store.Checklist, req *ChecklistRequest) error {
+	if req.LocationName != nil {
+		name := strings.TrimSpace(*req.LocationName)
+		if name == "" || len(name) > 255 {
+			return errors.New("location_name must be between 1 and 255 characters")
+		}
+		checklist.LocationName = name
+	}
+
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		return errors.New("latitude and longitude must be provided together")
+	}
+	if req.Latitude != nil {
+		if *req.Latitude < -90 || *req.Latitude > 90 || *req.Longitude < -180 || *req.Longitude > 180 {
+			return errors.New("latitude or longitude is out of range")
+		}
+		checklist.Latitude = req.Latitude
+		checklist.Longitude = req.Longitude
+	}
+
+	if req.StateCode != nil {
+		checklist.StateCode = req.StateCode
+	}
+
+	if req.CountryCode != nil {
+		code := strings.ToUpper(strings.TrimSpace(*req.CountryCode))
+		if len(code) != 2 {
+			return errors.New("country_code must be a two-letter ISO code")
+		}
+		checklist.CountryCode = &code
+	}
+
+	if req.StartedAt != nil {
+		startedAt, err := time.Parse(time.RFC3339, *req.StartedAt)
+		if err != nil {
+			return fmt.Errorf("invalid started_at, expected RFC3339: %v", err)
+		}
+		if startedAt.After(time.Now().Add(time.Hour)) {
+			return errors.New("started_at cannot be in the future")
+		}
+		checklist.StartedAt = startedAt
+	}
+
+	if req.DurationMinutes != nil {
+		if *req.DurationMinutes <= 0 {
+			return errors.New("duration_minutes must be positive")
+		}
+		checklist.DurationMinutes = req.DurationMinutes
+	}
+
+	if req.DistanceKm != nil {
+		if *req.DistanceKm < 0 || math.IsNaN(*req.DistanceKm) {
+			return errors.New("distance_km cannot be negative")
+		}
+		checklist.DistanceKm = req.DistanceKm
+	}
+
+	if req.Protocol != nil {
+		checklist.Protocol = strings.ToLower(*req.Protocol)
+	}
+
+	if req.NumberOfObservers != nil {
+		if *req.NumberOfObservers <= 0 {
+			return errors.New("number_of_observers must be positive")
+		}
+		checklist.NumberOfObservers = *req.NumberOfObservers
+	}
+
+	if req.IsComplete != nil {
+		checklist.IsComplete = *req.IsComplete
+	}
+
+	if req.Comments != nil {
+		checklist.Comments = req.Comments
+	}
+
+	switch checklist.Protocol {
+	case store.ChecklistProtocolTraveling:
+		if checklist.DurationMinutes == nil || checklist.DistanceKm == nil {
+			return errors.New("traveling checklists require duration_minutes and distance_km")
+		}
+	case store.ChecklistProtocolStationary:
+		if checklist.DurationMinutes == nil {
+			return errors.New("stationary checklists require duration_minutes")
+		}
+		checklist.DistanceKm = nil
+	case store.ChecklistProtocolIncidental:
+		if checklist.IsComplete {
+			return errors.New("incidental checklists cannot be marked complete")
+		}
+		checklist.DurationMinutes = nil
+		checklist.DistanceKm = nil
+	default:
+		return errors.New("protocol must be one of stationary, traveling or incidental")
+	}
+
+	if req.Species != nil {
+		if len(req.Species) > maxChecklistSpecies {
+			return fmt.Errorf("a checklist can have at most %d species", maxChecklistSpecies)
+		}
+
+		seen := make(map[string]bool, len(req.Species))
+		observations := make([]*store.Observation, 0, len(req.Species))
+		for i, entry := range req.Species {
+			obs := &store.Observation{}
+			obsReq := ObservationRequest{
+				SpeciesCode:  &entry.SpeciesCode,
+				Count:        entry.Count,
+				BreedingCode: entry.BreedingCode,
+				BehaviorCode: entry.BehaviorCode,
+				Notes:        entry.Notes,
+			}
+			if err := app.applyObservationRequest(ctx, user, obs, &obsReq); err != nil {
+				return fmt.Errorf("species[%d]: %w", i, err)
+			}
+			if seen[obs.SpeciesCode] {
+				return fmt.Errorf("species[%d]: %s is listed more than once", i, obs.SpeciesCode)
+			}
+			seen[obs.SpeciesCode] = true
+			observations = append(observations, obs)
+		}
+		checklist.Observations = observations
+	}
+
+	return nil
+}
+
+// getChecklistMiddleware loads the checklist and only lets its owner through.
+func (app *application) getChecklistMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := app.getUserFromFirebaseClaimsCtx(r)
+		if user == nil {
+			app.unauthorized(w, r)
+			return
+		}
+
+		checklistId, err := strconv.ParseInt(r.PathValue("checklist_id"), 10, 64)
+		if err != nil {
+			app.badRequest(w, r, errors.New("invalid checklist_id"))
+			return
+		}
+
+		checklist, err := app.store.Checklists.GetByID(r.Context(), checklistId)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			app.notFound(w, r)
+			return
+		case err != nil:
+			app.serverError(w, r, err)
+			return
+		}
+
+		if checklist.UserID != user.Id {
+			app.notFound(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ChecklistKey, checklist)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getChecklistFromCtx(r *http.Request) *store.Checklist {
+	checklist, _ := r.Context().Value(ChecklistKey).(*store.Checklist)
+	return checklist
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
@@ -13,6 +14,7 @@ import (
 	"github.com/sixync/birdlens-be/internal/request"
 	"github.com/sixync/birdlens-be/internal/response"
 	"github.com/sixync/birdlens-be/internal/store"
+	"github.com/sixync/birdlens-be/internal/validator"
 )
 
 var EventKey key = "event"
@@ -36,6 +38,11 @@ func (app *application) createEventHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if err := validator.Validate(req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
 	startDate, err := time.Parse("2006-01-02", req.StartDate)
 	if err != nil {
 		app.badRequest(w, r, fmt.Errorf("invalid start_date: %v", err))
@@ -48,7 +55,13 @@ func (app *application) createEventHandler(w http.ResponseWriter, r *http.Reques
 		app.badRequest(w, r, fmt.Errorf("invalid end_date: %v", err))
 		return
 	}
+	if endDate.Before(startDate) {
+		app.badRequest(w, r, errors.New("end_date must not be before start_date"))
+		return
+	}
+
 	event := &store.Event{
+		Title:       req.Title,
 		Description: req.Description,
 		StartDate:   startDate,
 		EndDate:     endDate,
@@ -63,10 +76,159 @@ func (app *application) createEventHandler(w http.ResponseWriter, r *http.Reques
 	response.JSON(w, http.StatusCreated, event, false, "event created successfully")
 }
 
+type UpdateEventRequest struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	StartDate   *string `json:"start_date"`
+	EndDate     *string `json:"end_date"`
+}
+
+func (app *application) updateEventHandler(w http.ResponseWriter, r *http.Request) {
+	event, err := getEventFromCtx(r)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if event == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	var req UpdateEventRequest
+	if err := request.DecodeJSON(w, r, &req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if req.Title != nil {
+		if len(*req.Title) == 0 || len(*req.Title) > 100 {
+			app.badRequest(w, r, errors.New("title must be between 1 and 100 characters"))
+			return
+		}
+		event.Title = *req.Title
+	}
+
+	if req.Description != nil {
+		if len(*req.Description) == 0 || len(*req.Description) > 1000 {
+			app.badRequest(w, r, errors.New("description must be between 1 and 1000 characters"))
+			return
+		}
+		event.Description = *req.Description
+	}
+
+	if req.StartDate != nil {
+		startDate, err := time.Parse("2006-01-02", *req.StartDate)
+		if err != nil {
+			app.badRequest(w, r, fmt.Errorf("invalid start_date: %v", err))
+			return
+		}
+		event.StartDate = startDate
+	}
+
+	if req.EndDate != nil {
+		endDate, err := time.Parse("2006-01-02", *req.EndDate)
+		if err != nil {
+			app.badRequest(w, r, fmt.Errorf("invalid end_date: %v", err))
+			return
+		}
+		event.EndDate = endDate
+	}
+
+	if event.EndDate.Before(event.StartDate) {
+		app.badRequest(w, r, errors.New("end_date must not be before start_date"))
+		return
+	}
+
+	if err := app.store.Events.Update(r.Context(), event); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, event, false, "event updated successfully")
+}
+
+func (app *application) addEventCoverPhotoHandler(w http.ResponseWriter, r *http.Request) {
+	event, err := getEventFromCtx(r)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if event == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	err = r.ParseMultipartForm(MaxMultipartFileSize)
+	if err != nil {
+		app.badRequest(w, r, fmt.Errorf("failed to parse multipart form: %w", err))
+		return
+	}
+
+	file, _, err := r.FormFile("cover_photo")
+	if err != nil {
+		app.badRequest(w, r, fmt.Errorf("cover_photo file is required: %w", err))
+		return
+	}
+	defer file.Close()
+
+	fileContent, err := io.ReadAll(file)
+	if err != nil {
+		app.serverError(w, r, fmt.Errorf("failed to read file content: %w", err))
+		return
+	}
+
+	if len(fileContent) == 0 {
+		app.badRequest(w, r, errors.New("no file content found"))
+		return
+	}
+
+	contentType := http.DetectContentType(fileContent)
+	if contentType != "image/jpeg" && contentType != "image/png" {
+		app.badRequest(w, r, fmt.Errorf("unsupported file type: %s", contentType))
+		return
+	}
+
+	ctx := r.Context()
+	filePath := fmt.Sprintf("events/%v/cover", event.ID)
+	url, err := app.uploadFileToCloudinary(ctx, "images", event.ID, filePath, fileContent)
+	if err != nil {
+		app.serverError(w, r, fmt.Errorf("failed to upload cover photo: %w", err))
+		return
+	}
+
+	event.CoverPhotoUrl = &url
+	if err := app.store.Events.Update(ctx, event); err != nil {
+		app.serverError(w, r, fmt.Errorf("failed to update event cover photo url: %w", err))
+		return
+	}
+
+	response.JSON(w, http.StatusOK, url, false, "event cover photo added successfully")
+}
+
+// getEventsHandler lists events by creation date, or as a calendar of events
+// overlapping the from/to range (YYYY-MM-DD) when either is given.
 func (app *application) getEventsHandler(w http.ResponseWriter, r *http.Request) {
 	limit, offset := getPaginateFromCtx(r)
 
-	events, err := app.store.Events.GetAll(r.Context(), limit, offset)
+	fromStr := r.URL.Query().Get("from")
+	toStr := r.URL.Query().Get("to")
+
+	var (
+		events *store.PaginatedList[*store.Event]
+		err    error
+	)
+	if fromStr == "" && toStr == "" {
+		events, err = app.store.Events.GetAll(r.Context(), limit, offset)
+	} else {
+		from, to, parseErr := parseCalendarRange(fromStr, toStr)
+		if parseErr != nil {
+			app.badRequest(w, r, parseErr)
+			return
+		}
+		events, err = app.store.Events.GetInRange(r.Context(), from, to, limit, offset)
+	}
 	if err != nil {
 		app.serverError(w, r, err)
 		return
@@ -111,6 +273,143 @@ func (app *application) deleteEventHandler(w http.ResponseWriter, r *http.Reques
 	response.JSON(w, http.StatusOK, nil, false, "event deleted successfully")
 }
 
+// parseCalendarRange parses from/to dates. A missing bound defaults to one
+// month on the other side of the given one; to is inclusive of the whole day.
+func parseCalendarRange(fromStr, toStr string) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+
+	if fromStr != "" {
+		from, err = time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			return from, to, fmt.Errorf("invalid from: %v", err)
+		}
+	}
+
+	if toStr != "" {
+		to, err = time.Parse("2006-01-02", toStr)
+		if err != nil {
+			return from, to, fmt.Errorf("invalid to: %v", err)
+		}
+	}
+
+	switch {
+	case fromStr == "":
+		from = to.AddDate(0, -1, 0)
+	case toStr == "":
+		to = from.AddDate(0, 1, 0)
+	}
+
+	if to.Before(from) {
+		return from, to, errors.New("to must not be before from")
+	}
+
+	return from, to.Add(24*time.Hour - time.Second), nil
+}
+
+type RSVPRequest struct {
+	Status string `json:"status" validate:"required"`
+}
+
+func (app *application) rsvpEventHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	event, err := getEventFromCtx(r)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if event == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	var req RSVPRequest
+	if err := request.DecodeJSON(w, r, &req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if req.Status != store.RSVPGoing && req.Status != store.RSVPInterested {
+		app.badRequest(w, r, fmt.Errorf("status must be %q or %q", store.RSVPGoing, store.RSVPInterested))
+		return
+	}
+
+	rsvp := &store.EventRSVP{
+		EventID: event.ID,
+		UserID:  user.Id,
+		Status:  req.Status,
+	}
+	if err := app.store.Events.SetRSVP(r.Context(), rsvp); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, rsvp, false, "rsvp saved successfully")
+}
+
+func (app *application) getEventRSVPHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	event, err := getEventFromCtx(r)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if event == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	rsvp, err := app.store.Events.GetRSVP(r.Context(), event.ID, user.Id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFound(w, r)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, rsvp, false, "rsvp retrieved successfully")
+}
+
+func (app *application) deleteEventRSVPHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	event, err := getEventFromCtx(r)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if event == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	if err := app.store.Events.DeleteRSVP(r.Context(), event.ID, user.Id); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, nil, false, "rsvp removed successfully")
+}
+
 func getEventFromCtx(r *http.Request) (*store.Event, error) {
 	ctx := r.Context()
 	event, _ := ctx.Value(EventKey).(*store.Event)
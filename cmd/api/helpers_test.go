@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestApplication returns an application that logs nowhere, for handler
+// tests that set the dependencies they use.
+func newTestApplication(t *testing.T) *application {
+	t.Helper()
+	return &application{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+}
+
+// decodeResponse decodes the data of a response.JSON body into dst and
+// returns its message.
+func decodeResponse(t *testing.T, rec *httptest.ResponseRecorder, dst any) string {
+	t.Helper()
+	var body struct {
+		Error   bool            `json:"error"`
+		Data    json.RawMessage `json:"data"`
+		Message string          `json:"message"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding response %q: %v", rec.Body.String(), err)
+	}
+	if dst != nil {
+		if err := json.Unmarshal(body.Data, dst); err != nil {
+			t.Fatalf("decoding response data %q: %v", body.Data, err)
+		}
+	}
+	return body.Message
+}
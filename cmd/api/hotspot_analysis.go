@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sixync/birdlens-be/internal/response"
+	"github.com/sixync/birdlens-be/internal/store"
+)
+
+const (
+	// visitingTimesAnalysisVersion is bumped when VisitingTimesAnalysis
+	// changes, so that stored analyses are recomputed.
+	visitingTimesAnalysisVersion = 2
+	maxVisitingTimesHotspots     = 10
+	topSpeciesPerMonth           = 10
+	// weeksPerYear follows eBird's bar charts: four weeks in every month.
+	weeksPerYear = 48
+	// minBestHourObservations is the fewest observations an hour needs to be
+	// recommended, so a single early report does not make 4am the best hour.
+	minBestHourObservations = 10
+	// confidenceZ is the z-score of the 95% Wilson score interval used to
+	// rank weeks, so that a week surveyed once does not win with 100%.
+	confidenceZ = 1.96
+)
+
+const (
+	confidenceNone   = "none"
+	confidenceLow    = "low"
+	confidenceMedium = "medium"
+	confidenceHigh   = "high"
+)
+
+// WeeklyStat is the share of surveyed days in a week of the year on which a
+// species was reported, as on an eBird bar chart. Frequencies here are per
+// day rather than per checklist: eBird's historic endpoint returns one
+// observation per species and day, so a day with observations at a hotspot
+// is the sampling unit.
+type WeeklyStat struct {
+	Week         int     `json:"week"`      // 1-48, four per month
+	Label        string  `json:"label"`     // "Jan 1-7"
+	Frequency    float64 `json:"frequency"` // percent of surveyed days
+	SurveyedDays int     `json:"surveyed_days"`
+	DetectedDays int     `json:"detected_days"`
+	Confidence   string  `json:"confidence"`
+}
+
+type MonthTopSpecies struct {
+	Month        string       `json:"month"`
+	SurveyedDays int          `json:"surveyed_days"`
+	Confidence   string       `json:"confidence"`
+	Species      []TopSpecies `json:"species"`
+}
+
+type TopSpecies struct {
+	SpeciesCode  string  `json:"species_code"`
+	CommonName   string  `json:"common_name"`
+	Frequency    float64 `json:"frequency"` // percent of the month's surveyed days
+	DetectedDays int     `json:"detected_days"`
+}
+
+// BestTimeToVisit recommends when a species is most likely to be found.
+// Week is the week with the highest frequency that its sample supports; Hour
+// is the hour in which the species makes up the largest share of reports.
+type BestTimeToVisit struct {
+	Week           *WeeklyStat `json:"week,omitempty"`
+	Hour           *int        `json:"hour,omitempty"`
+	HourShare      float64     `json:"hour_share,omitempty"` // percent of the hour's reports
+	HourConfidence string      `json:"hour_confidence,omitempty"`
+}
+
+// MultiHotspotVisitingTimes is an analysis of several hotspots together.
+// Hotspots whose eBird history is still being cached are listed as pending
+// and left out.
+type MultiHotspotVisitingTimes struct {
+	*VisitingTimesAnalysis
+	LocIDs        []string `json:"loc_ids"`
+	PendingLocIDs []string `json:"pending_loc_ids"`
+}
+
+// getHotspotsVisitingTimesHandler analyses up to maxVisitingTimesHotspots
+// hotspots, given as locIds=L1,L2, together. The analysis is computed on
+// each request rather than stored. Hotspots seen for the first time start
+// caching in the background; if none is ready the response is 202.
+func (app *application) getHotspotsVisitingTimesHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := app.requireExBird(w, r); !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	speciesCode := query.Get("speciesCode")
+
+	locIds := []string{}
+	seen := map[string]bool{}
+	for _, locId := range strings.Split(query.Get("locIds"), ",") {
+		locId = strings.TrimSpace(locId)
+		if locId == "" || seen[locId] {
+			continue
+		}
+		if !locIdPattern.MatchString(locId) {
+			app.badRequest(w, r, fmt.Errorf("invalid hotspot id %q", locId))
+			return
+		}
+		seen[locId] = true
+		locIds = append(locIds, locId)
+	}
+	if len(locIds) == 0 || len(locIds) > maxVisitingTimesHotspots {
+		app.badRequest(w, r, fmt.Errorf("locIds must list 1 to %d hotspots", maxVisitingTimesHotspots))
+		return
+	}
+
+	ctx := r.Context()
+	ready := []string{}
+	pending := []string{}
+	for _, locId := range locIds {
+		hotspot, err := app.store.EbirdCache.GetHotspot(ctx, locId)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			app.serverError(w, r, err)
+			return
+		}
+		if hotspot != nil && hotspot.LastRefreshedAt != nil {
+			ready = append(ready, locId)
+			continue
+		}
+
+		if err := app.store.EbirdCache.TrackHotspot(ctx, locId); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		app.backgroundTask(r, func() error {
+			return app.refreshHotspot(context.Background(), locId)
+		})
+		pending = append(pending, locId)
+	}
+
+	if len(ready) == 0 {
+		response.JSON(w, http.StatusAccepted, MultiHotspotVisitingTimes{LocIDs: ready, PendingLocIDs: pending}, false, "Visiting times are being prepared for these hotspots. Please try again in a few minutes.")
+		return
+	}
+
+	analysis, err := app.analyzeVisitingTimes(ctx, ready, speciesCode)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if analysis == nil {
+		response.JSON(w, http.StatusNotFound, nil, true, "Not enough observation data to perform analysis for these locations/species.")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, MultiHotspotVisitingTimes{
+		VisitingTimesAnalysis: analysis,
+		LocIDs:                ready,
+		PendingLocIDs:         pending,
+	}, false, "Analysis successful")
+}
+
+// analyzeVisitingTimes aggregates the cached observations of hotspots into
+// an analysis. It returns nil when there are no observations to analyse.
+func (app *application) analyzeVisitingTimes(ctx context.Context, locIds []string, speciesCode string) (*VisitingTimesAnalysis, error) {
+	from, to := visitingTimesWindow()
+
+	activity, err := app.store.EbirdCache.GetHistoricActivity(ctx, locIds, speciesCode, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if activity.Observations == 0 {
+		return nil, nil
+	}
+
+	daysCovered := 0
+	for _, locId := range locIds {
+		cached, err := app.store.EbirdCache.GetCachedDates(ctx, locId, from, to)
+		if err != nil {
+			return nil, err
+		}
+		daysCovered += len(cached)
+	}
+
+	presence, err := app.store.EbirdCache.GetWeeklyPresence(ctx, locIds, speciesCode, from, to)
+	if err != nil {
+		return nil, err
+	}
+	surveyedDays := 0
+	for _, week := range presence {
+		surveyedDays += week.SurveyedDays
+	}
+
+	analysis := &VisitingTimesAnalysis{
+		MonthlyActivity: normalizeMonthCounts(activity.ByMonth),
+		HourlyActivity:  normalizeHourCounts(activity.ByHour),
+		DaysCovered:     daysCovered,
+		DaysMissing:     len(locIds)*visitingTimesWindowDays - daysCovered,
+		ComputedAt:      time.Now(),
+		Version:         visitingTimesAnalysisVersion,
+		SurveyedDays:    surveyedDays,
+		Confidence:      confidenceFor(surveyedDays),
+	}
+
+	if speciesCode != "" {
+		analysis.WeeklyFrequency = weeklyStats(presence)
+
+		all, err := app.store.EbirdCache.GetHistoricActivity(ctx, locIds, "", from, to)
+		if err != nil {
+			return nil, err
+		}
+		analysis.BestTime = bestTimeToVisit(analysis.WeeklyFrequency, activity.ByHour, all.ByHour)
+		return analysis, nil
+	}
+
+	top, err := app.store.EbirdCache.GetTopSpeciesByMonth(ctx, locIds, from, to, topSpeciesPerMonth)
+	if err != nil {
+		return nil, err
+	}
+	analysis.TopSpeciesByMonth, err = app.topSpeciesByMonth(ctx, top)
+	if err != nil {
+		return nil, err
+	}
+	return analysis, nil
+}
+
+// weeklyStats returns all 48 weeks, with zeros for weeks without data.
+func weeklyStats(presence []*store.WeeklyPresence) []WeeklyStat {
+	byWeek := make(map[int]*store.WeeklyPresence, len(presence))
+	for _, week := range presence {
+		byWeek[week.Week] = week
+	}
+
+	stats := make([]WeeklyStat, 0, weeksPerYear)
+	for week := 1; week <= weeksPerYear; week++ {
+		stat := WeeklyStat{Week: week, Label: weekLabel(week), Confidence: confidenceNone}
+		if p, ok := byWeek[week]; ok && p.SurveyedDays > 0 {
+			stat.SurveyedDays = p.SurveyedDays
+			stat.DetectedDays = p.DetectedDays
+			stat.Frequency = percent(p.DetectedDays, p.SurveyedDays)
+			stat.Confidence = confidenceFor(p.SurveyedDays)
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+// bestTimeToVisit picks the week whose frequency has the highest Wilson
+// lower bound, and the hour in which the species makes up the largest share
+// of all reports among hours with enough of them.
+func bestTimeToVisit(weeks []WeeklyStat, speciesByHour, allByHour map[int]int) *BestTimeToVisit {
+	best := &BestTimeToVisit{}
+
+	bestScore := 0.0
+	for i := range weeks {
+		score := wilsonLowerBound(weeks[i].DetectedDays, weeks[i].SurveyedDays)
+		if score > bestScore {
+			bestScore = score
+			best.Week = &weeks[i]
+		}
+	}
+
+	for hour := 0; hour < 24; hour++ {
+		total := allByHour[hour]
+		if total < minBestHourObservations || speciesByHour[hour] == 0 {
+			continue
+		}
+		share := percent(speciesByHour[hour], total)
+		if best.Hour == nil || share > best.HourShare {
+			h := hour
+			best.Hour = &h
+			best.HourShare = share
+			best.HourConfidence = confidenceFor(total)
+		}
+	}
+
+	if best.Week == nil && best.Hour == nil {
+		return nil
+	}
+	return best
+}
+
+// topSpeciesByMonth groups the ranked species by month and names them from
+// the local taxonomy.
+func (app *application) topSpeciesByMonth(ctx context.Context, presence []*store.MonthlySpeciesPresence) ([]MonthTopSpecies, error) {
+	codes := make([]string, 0, len(presence))
+	for _, p := range presence {
+		codes = append(codes, p.SpeciesCode)
+	}
+	taxa, err := app.store.Species.GetByCodes(ctx, codes)
+	if err != nil {
+		return nil, err
+	}
+
+	months := []MonthTopSpecies{}
+	for _, p := range presence {
+		if len(months) == 0 || months[len(months)-1].Month != p.Month.String() {
+			months = append(months, MonthTopSpecies{
+				Month:        p.Month.String(),
+				SurveyedDays: p.SurveyedDays,
+				Confidence:   confidenceFor(p.SurveyedDays),
+				Species:      []TopSpecies{},
+			})
+		}
+
+		species := TopSpecies{
+			SpeciesCode:  p.SpeciesCode,
+			CommonName:   p.SpeciesCode,
+			Frequency:    percent(p.DetectedDays, p.SurveyedDays),
+			DetectedDays: p.DetectedDays,
+		}
+		if taxon, ok := taxa[p.SpeciesCode]; ok {
+			species.CommonName = taxon.CommonName
+		}
+		month := &months[len(months)-1]
+		month.Species = append(month.Species, species)
+	}
+	return months, nil
+}
+
+// confidenceFor rates a frequency by the number of days it is based on. A
+// week at one hotspot has at most 7 days, so reaching high confidence for a
+// week takes several years or hotspots.
+func confidenceFor(days int) string {
+	switch {
+	case days >= 20:
+		return confidenceHigh
+	case days >= 7:
+		return confidenceMedium
+	case days > 0:
+		return confidenceLow
+	default:
+		return confidenceNone
+	}
+}
+
+// weekLabel names an eBird bar-chart week, such as "Jan 1-7" or "Feb 22-29".
+func weekLabel(week int) string {
+	month := time.Month((week-1)/4 + 1)
+	first := (week-1)%4*7 + 1
+	last := first + 6
+	if (week-1)%4 == 3 {
+		// The last week runs to the end of the month; February is shown with
+		// its leap day.
+		last = time.Date(2024, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	}
+	return fmt.Sprintf("%s %d-%d", month.String()[:3], first, last)
+}
+
+// wilsonLowerBound is the lower bound of the Wilson score interval for
+// successes out of trials.
+func wilsonLowerBound(successes, trials int) float64 {
+	if trials == 0 {
+		return 0
+	}
+	n := float64(trials)
+	p := float64(successes) / n
+	z2 := confidenceZ * confidenceZ
+	centre := p + z2/(2*n)
+	margin := confidenceZ * math.Sqrt(p*(1-p)/n+z2/(4*n*n))
+	return (centre - margin) / (1 + z2/n)
+}
+
+// percent rounds part/whole to one decimal place.
+func percent(part, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(whole)*1000) / 10
+}
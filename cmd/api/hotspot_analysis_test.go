@@ -0,0 +1,183 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sixync/birdlens-be/internal/store"
+)
+
+func TestWilsonLowerBound(t *testing.T) {
+	tests := []struct {
+		successes, trials int
+		want              float64
+	}{
+		{0, 0, 0},
+		{0, 5, 0},
+		{1, 1, 0.2065},
+		{8, 10, 0.4902},
+		{100, 100, 0.9630},
+	}
+	for _, tt := range tests {
+		if got := wilsonLowerBound(tt.successes, tt.trials); math.Abs(got-tt.want) > 0.0001 {
+			t.Errorf("wilsonLowerBound(%d, %d) = %.4f, want %.4f", tt.successes, tt.trials, got, tt.want)
+		}
+	}
+}
+
+func TestWeekLabel(t *testing.T) {
+	tests := []struct {
+		week int
+		want string
+	}{
+		{1, "Jan 1-7"},
+		{2, "Jan 8-14"},
+		{4, "Jan 22-31"},
+		{5, "Feb 1-7"},
+		{8, "Feb 22-29"},
+		{16, "Apr 22-30"},
+		{48, "Dec 22-31"},
+	}
+	for _, tt := range tests {
+		if got := weekLabel(tt.week); got != tt.want {
+			t.Errorf("weekLabel(%d) = %q, want %q", tt.week, got, tt.want)
+		}
+	}
+}
+
+func TestWeeklyStats(t *testing.T) {
+	stats := weeklyStats([]*store.WeeklyPresence{
+		{Week: 1, SurveyedDays: 3, DetectedDays: 1},
+		{Week: 10, SurveyedDays: 0, DetectedDays: 0},
+		{Week: 48, SurveyedDays: 20, DetectedDays: 20},
+	})
+	if len(stats) != weeksPerYear {
+		t.Fatalf("weeklyStats() returned %d weeks, want %d", len(stats), weeksPerYear)
+	}
+	for i, stat := range stats {
+		if stat.Week != i+1 || stat.Label != weekLabel(i+1) {
+			t.Errorf("stats[%d] = %+v, want week %d in order", i, stat, i+1)
+		}
+	}
+
+	tests := []struct {
+		week       int
+		frequency  float64
+		surveyed   int
+		confidence string
+	}{
+		{1, 33.3, 3, confidenceLow},
+		{2, 0, 0, confidenceNone},
+		{10, 0, 0, confidenceNone},
+		{48, 100, 20, confidenceHigh},
+	}
+	for _, tt := range tests {
+		stat := stats[tt.week-1]
+		if stat.Frequency != tt.frequency || stat.SurveyedDays != tt.surveyed || stat.Confidence != tt.confidence {
+			t.Errorf("week %d = %+v, want frequency %v over %d days, %s confidence", tt.week, stat, tt.frequency, tt.surveyed, tt.confidence)
+		}
+	}
+}
+
+func TestConfidenceFor(t *testing.T) {
+	tests := []struct {
+		days int
+		want string
+	}{
+		{0, confidenceNone},
+		{1, confidenceLow},
+		{6, confidenceLow},
+		{7, confidenceMedium},
+		{19, confidenceMedium},
+		{20, confidenceHigh},
+	}
+	for _, tt := range tests {
+		if got := confidenceFor(tt.days); got != tt.want {
+			t.Errorf("confidenceFor(%d) = %q, want %q", tt.days, got, tt.want)
+		}
+	}
+}
+
+func TestBestTimeToVisit(t *testing.T) {
+	weeks := func(presence ...*store.WeeklyPresence) []WeeklyStat {
+		return weeklyStats(presence)
+	}
+
+	tests := []struct {
+		name          string
+		weeks         []WeeklyStat
+		speciesByHour map[int]int
+		allByHour     map[int]int
+		wantNil       bool
+		wantWeek      int
+		wantHour      int
+		wantShare     float64
+		wantHourConf  string
+	}{
+		{
+			name:     "a single surveyed day does not beat a well surveyed week",
+			weeks:    weeks(&store.WeeklyPresence{Week: 3, SurveyedDays: 1, DetectedDays: 1}, &store.WeeklyPresence{Week: 20, SurveyedDays: 10, DetectedDays: 8}),
+			wantWeek: 20,
+			wantHour: -1,
+		},
+		{
+			name:     "a single surveyed day is still a best week on its own",
+			weeks:    weeks(&store.WeeklyPresence{Week: 3, SurveyedDays: 1, DetectedDays: 1}),
+			wantWeek: 3,
+			wantHour: -1,
+		},
+		{
+			name:          "hours with too few reports are skipped",
+			weeks:         weeks(),
+			speciesByHour: map[int]int{5: 2, 7: 6, 8: 3},
+			allByHour:     map[int]int{5: 2, 7: 20, 8: 5},
+			wantHour:      7,
+			wantShare:     30,
+			wantHourConf:  confidenceHigh,
+		},
+		{
+			name:          "the largest share wins over the most reports",
+			weeks:         weeks(),
+			speciesByHour: map[int]int{7: 6, 9: 4},
+			allByHour:     map[int]int{7: 20, 9: 10},
+			wantHour:      9,
+			wantShare:     40,
+			wantHourConf:  confidenceMedium,
+		},
+		{
+			name:    "never detected",
+			weeks:   weeks(&store.WeeklyPresence{Week: 3, SurveyedDays: 5}),
+			wantNil: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			best := bestTimeToVisit(tt.weeks, tt.speciesByHour, tt.allByHour)
+			if tt.wantNil {
+				if best != nil {
+					t.Errorf("bestTimeToVisit() = %+v, want nil", best)
+				}
+				return
+			}
+			if best == nil {
+				t.Fatal("bestTimeToVisit() = nil")
+			}
+
+			switch {
+			case tt.wantWeek == 0 && best.Week != nil:
+				t.Errorf("week = %+v, want none", best.Week)
+			case tt.wantWeek != 0 && (best.Week == nil || best.Week.Week != tt.wantWeek):
+				t.Errorf("week = %+v, want week %d", best.Week, tt.wantWeek)
+			}
+
+			switch {
+			case tt.wantHour < 0 && best.Hour != nil:
+				t.Errorf("hour = %d, want none", *best.Hour)
+			case tt.wantHour >= 0 && (best.Hour == nil || *best.Hour != tt.wantHour):
+				t.Errorf("hour = %v, want %d", best.Hour, tt.wantHour)
+			case tt.wantHour >= 0 && (best.HourShare != tt.wantShare || best.HourConfidence != tt.wantHourConf):
+				t.Errorf("hour share = %v with %s confidence, want %v with %s", best.HourShare, best.HourConfidence, tt.wantShare, tt.wantHourConf)
+			}
+		})
+	}
+}
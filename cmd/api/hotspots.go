@@ -2,27 +2,59 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
+	"regexp"
 	"sort"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/sixync/birdlens-be/internal/ebird"
 	"github.com/sixync/birdlens-be/internal/response"
+	"github.com/sixync/birdlens-be/internal/store"
 )
 
-// Represents a single observation from the eBird API
-type EbirdHistoricObservation struct {
-	SpeciesCode string `json:"speciesCode"`
-	ObsDt       string `json:"obsDt"`
-	HowMany     int    `json:"howMany"`
-}
+const (
+	// visitingTimesWindowDays is how many past days, ending yesterday, an
+	// analysis covers. Today is left out because its data is still changing.
+	visitingTimesWindowDays = 365
+	ebirdFetchWorkers       = 20
+	// hotspotRefreshInterval is how often cached hotspots pick up new days
+	// and retry the days that failed.
+	hotspotRefreshInterval = 6 * time.Hour
+)
+
+// hotspotRefreshes holds the hotspots being refreshed, so that concurrent
+// requests and the refresher do not fetch the same hotspot twice.
+var hotspotRefreshes sync.Map
 
 // The final analysis data structure we will send to the client
 type VisitingTimesAnalysis struct {
 	MonthlyActivity []MonthlyStat `json:"monthly_activity"`
 	HourlyActivity  []HourlyStat  `json:"hourly_activity"`
+	// DaysCovered and DaysMissing count the days of the window that are
+	// cached and that eBird has not returned yet; missing days are retried.
+	DaysCovered int       `json:"days_covered"`
+	DaysMissing int       `json:"days_missing"`
+	ComputedAt  time.Time `json:"computed_at"`
+	// Version is visitingTimesAnalysisVersion when the analysis was stored.
+	Version int `json:"version"`
+	// SurveyedDays counts the days with observations, the sample that
+	// frequencies and Confidence are based on.
+	SurveyedDays int    `json:"surveyed_days"`
+	Confidence   string `json:"confidence"`
+	// WeeklyFrequency and BestTime are set for a species, and
+	// TopSpeciesByMonth for all species.
+	WeeklyFrequency   []WeeklyStat      `json:"weekly_frequency,omitempty"`
+	BestTime          *BestTimeToVisit  `json:"best_time,omitempty"`
+	TopSpeciesByMonth []MonthTopSpecies `json:"top_species_by_month,omitempty"`
 }
 
 type MonthlyStat struct {
@@ -35,143 +67,273 @@ type HourlyStat struct {
 	RelativeFrequency float64 `json:"relative_frequency"`
 }
 
-// Logic: Create structs for the worker pool pattern to make the code cleaner.
-type fetchJob struct {
-	daysAgo int
-}
-
 type fetchResult struct {
-	observations []EbirdHistoricObservation
+	date         time.Time
+	observations []ebird.Observation
 	err          error
 }
 
+// getHotspotVisitingTimesHandler serves the stored analysis for a hotspot.
+// The first request for a hotspot starts caching its eBird history in the
+// background and answers 202; later requests are served from the cache.
 func (app *application) getHotspotVisitingTimesHandler(w http.ResponseWriter, r *http.Request) {
 	// 1. Authorization: Check for ExBird subscription (This part was correct)
-	user := app.getUserFromFirebaseClaimsCtx(r)
-	if user == nil {
-		app.unauthorized(w, r)
-		return
-	}
-
-	subscription, err := app.store.Subscriptions.GetUserSubscriptionByEmail(r.Context(), user.Email)
-	if err != nil || subscription == nil || subscription.Name != "ExBird" {
-		app.errorMessage(w, r, http.StatusForbidden, "This feature is exclusive to ExBird subscribers.", nil)
+	user, ok := app.requireExBird(w, r)
+	if !ok {
 		return
 	}
 
 	// 2. Get parameters from request (This part was correct)
 	locId := r.PathValue("locId")
 	speciesCode := r.URL.Query().Get("speciesCode")
+	ctx := r.Context()
+
+	// Every tracked hotspot costs a year of eBird calls and is refreshed
+	// for good, so only well-formed hotspot ids are accepted.
+	if !locIdPattern.MatchString(locId) {
+		app.notFound(w, r)
+		return
+	}
 
 	slog.Info("Starting visiting times analysis", "locId", locId, "speciesCode", speciesCode, "user", user.Email)
 
-	// 3. FIX: Fetch and Analyze Data using a Worker Pool to avoid overwhelming the system.
-	const numJobs = 365
-	const numWorkers = 20 // A reasonable number of concurrent workers
-
-	jobs := make(chan fetchJob, numJobs)
-	results := make(chan fetchResult, numJobs)
-	//var wg sync.WaitGroup
-
-	// Start the workers
-	for w := 1; w <= numWorkers; w++ {
-		go func(workerID int) {
-			for job := range jobs {
-				date := time.Now().AddDate(0, 0, -job.daysAgo)
-				url := fmt.Sprintf("https://api.ebird.org/v2/data/obs/%s/historic/%d/%d/%d", locId, date.Year(), date.Month(), date.Day())
-
-				req, _ := http.NewRequest("GET", url, nil)
-				req.Header.Set("X-eBirdApiToken", app.config.eBird.apiKey)
-
-				client := &http.Client{Timeout: 15 * time.Second} // Increased timeout slightly
-				resp, err := client.Do(req)
-
-				if err != nil {
-					results <- fetchResult{nil, fmt.Errorf("worker %d failed for date %s: %w", workerID, date.Format("2006-01-02"), err)}
-					continue
-				}
-
-				if resp.StatusCode != http.StatusOK {
-					// Don't treat "not found" as a fatal error for a single day.
-					if resp.StatusCode == http.StatusNotFound {
-						resp.Body.Close()
-						results <- fetchResult{nil, nil} // Send a success with no data
-						continue
-					}
-					resp.Body.Close()
-					results <- fetchResult{nil, fmt.Errorf("worker %d: eBird API returned status %d for date %s", workerID, resp.StatusCode, date.Format("2006-01-02"))}
-					continue
-				}
-
-				var observations []EbirdHistoricObservation
-				if err := json.NewDecoder(resp.Body).Decode(&observations); err != nil {
-					resp.Body.Close()
-					results <- fetchResult{nil, fmt.Errorf("worker %d failed to decode JSON for date %s: %w", workerID, date.Format("2006-01-02"), err)}
-					continue
-				}
-
-				resp.Body.Close()
-				results <- fetchResult{observations, nil}
-			}
-		}(w)
+	hotspot, err := app.store.EbirdCache.GetHotspot(ctx, locId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// 3. A hotspot that has never been fetched is cached in the background;
+	// fetching a year of eBird history takes longer than a request may.
+	if hotspot == nil || hotspot.LastRefreshedAt == nil {
+		if err := app.store.EbirdCache.TrackHotspot(ctx, locId); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		app.backgroundTask(r, func() error {
+			return app.refreshHotspot(context.Background(), locId)
+		})
+		response.JSON(w, http.StatusAccepted, nil, false, "Visiting times are being prepared for this hotspot. Please try again in a few minutes.")
+		return
 	}
 
-	// Send jobs to the workers
-	for i := 0; i < numJobs; i++ {
-		jobs <- fetchJob{daysAgo: i}
+	// 4. Serve the stored analysis, computing it from the cache when it is
+	// missing, older than the cache or from an older version.
+	stored, err := app.store.EbirdCache.GetVisitingTimes(ctx, locId, speciesCode)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		app.serverError(w, r, err)
+		return
 	}
-	close(jobs) // Close the jobs channel to signal workers to stop once they are done.
 
-	// Collect results
-	allObservations := []EbirdHistoricObservation{}
-	for i := 0; i < numJobs; i++ {
-		result := <-results
-		if result.err != nil {
-			// Fail on the first error to avoid long waits for potentially failing requests.
-			app.serverError(w, r, fmt.Errorf("failed during data fetch from eBird: %w", result.err))
+	var analysis *VisitingTimesAnalysis
+	if stored != nil && !stored.ComputedAt.Before(*hotspot.LastRefreshedAt) {
+		var storedAnalysis VisitingTimesAnalysis
+		if err := json.Unmarshal(stored.Analysis, &storedAnalysis); err != nil {
+			app.serverError(w, r, err)
 			return
 		}
-		if result.observations != nil {
-			allObservations = append(allObservations, result.observations...)
+		if storedAnalysis.Version == visitingTimesAnalysisVersion {
+			analysis = &storedAnalysis
 		}
 	}
-
-	// Filter observations by species code if provided
-	if speciesCode != "" {
-		filteredObs := []EbirdHistoricObservation{}
-		for _, obs := range allObservations {
-			if obs.SpeciesCode == speciesCode {
-				filteredObs = append(filteredObs, obs)
-			}
+	if analysis == nil {
+		analysis, err = app.computeVisitingTimes(ctx, locId, speciesCode)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
 		}
-		allObservations = filteredObs
 	}
 
-	if len(allObservations) == 0 {
+	if analysis == nil {
 		response.JSON(w, http.StatusNotFound, nil, true, "Not enough observation data to perform analysis for this location/species.")
 		return
 	}
 
-	// 4. Aggregate Data (This part was correct)
-	monthCounts := make(map[time.Month]int)
-	hourCounts := make(map[int]int)
+	response.JSON(w, http.StatusOK, analysis, false, "Analysis successful")
+}
+
+// requireExBird answers 401 or 403 unless the current user has the ExBird
+// subscription.
+func (app *application) requireExBird(w http.ResponseWriter, r *http.Request) (*store.User, bool) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return nil, false
+	}
 
-	for _, obs := range allObservations {
-		obsTime, err := time.Parse("2006-01-02 15:04", obs.ObsDt)
-		if err != nil {
-			continue // Skip records with invalid date format
+	subscription, err := app.store.Subscriptions.GetUserSubscriptionByEmail(r.Context(), user.Email)
+	if err != nil || subscription == nil || subscription.Name != "ExBird" {
+		app.errorMessage(w, r, http.StatusForbidden, "This feature is exclusive to ExBird subscribers.", nil)
+		return nil, false
+	}
+	return user, true
+}
+
+// visitingTimesWindow returns the first and last day of the analysis window.
+func visitingTimesWindow() (from, to time.Time) {
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return today.AddDate(0, 0, -visitingTimesWindowDays), today.AddDate(0, 0, -1)
+}
+
+// computeVisitingTimes analyses a hotspot's cached observations and stores
+// the analysis. It returns nil when there are no observations to analyse.
+func (app *application) computeVisitingTimes(ctx context.Context, locId, speciesCode string) (*VisitingTimesAnalysis, error) {
+	analysis, err := app.analyzeVisitingTimes(ctx, []string{locId}, speciesCode)
+	if err != nil || analysis == nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(analysis)
+	if err != nil {
+		return nil, err
+	}
+
+	vt := &store.HotspotVisitingTimes{
+		LocID:       locId,
+		SpeciesCode: speciesCode,
+		Analysis:    data,
+		DaysCovered: analysis.DaysCovered,
+		DaysMissing: analysis.DaysMissing,
+		ComputedAt:  analysis.ComputedAt,
+	}
+	if err := app.store.EbirdCache.SaveVisitingTimes(ctx, vt); err != nil {
+		return nil, err
+	}
+	return analysis, nil
+}
+
+// startHotspotRefresher periodically refreshes every cached hotspot.
+func (app *application) startHotspotRefresher() {
+	go func() {
+		ticker := time.NewTicker(hotspotRefreshInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			app.refreshHotspots(context.Background())
 		}
-		monthCounts[obsTime.Month()]++
-		hourCounts[obsTime.Hour()]++
+	}()
+	slog.Info("Started eBird hotspot cache refresher", "interval", hotspotRefreshInterval)
+}
+
+func (app *application) refreshHotspots(ctx context.Context) {
+	hotspots, err := app.store.EbirdCache.ListHotspots(ctx)
+	if err != nil {
+		app.logger.Error("failed to list cached hotspots", "error", err)
+		return
 	}
 
-	// 5. Normalize Data and respond (This part was correct)
-	analysis := VisitingTimesAnalysis{
-		MonthlyActivity: normalizeMonthCounts(monthCounts),
-		HourlyActivity:  normalizeHourCounts(hourCounts),
+	for _, hotspot := range hotspots {
+		if err := app.refreshHotspot(ctx, hotspot.LocID); err != nil {
+			app.logger.Error("failed to refresh hotspot", "locId", hotspot.LocID, "error", err)
+		}
 	}
+}
 
-	response.JSON(w, http.StatusOK, analysis, false, "Analysis successful")
+// refreshHotspot fetches the days of the window that are not cached yet and
+// recomputes the hotspot's stored analyses. Days that fail are left for the
+// next refresh; only a hotspot with nothing cached at all is an error.
+func (app *application) refreshHotspot(ctx context.Context, locId string) error {
+	if _, running := hotspotRefreshes.LoadOrStore(locId, struct{}{}); running {
+		return nil
+	}
+	defer hotspotRefreshes.Delete(locId)
+
+	fetched, failed, err := app.fillHistoricCache(ctx, locId)
+	if err != nil {
+		return err
+	}
+	slog.Info("Refreshed eBird history for hotspot", "locId", locId, "fetched_days", fetched, "failed_days", failed)
+
+	from, to := visitingTimesWindow()
+	cached, err := app.store.EbirdCache.GetCachedDates(ctx, locId, from, to)
+	if err != nil {
+		return err
+	}
+	if len(cached) == 0 {
+		return fmt.Errorf("no eBird history could be fetched for hotspot %s", locId)
+	}
+
+	if err := app.store.EbirdCache.MarkRefreshed(ctx, locId, time.Now()); err != nil {
+		return err
+	}
+
+	speciesCodes, err := app.store.EbirdCache.GetVisitingTimesSpecies(ctx, locId)
+	if err != nil {
+		return err
+	}
+	for _, speciesCode := range speciesCodes {
+		if _, err := app.computeVisitingTimes(ctx, locId, speciesCode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fillHistoricCache fetches the uncached days of the window through a pool of
+// workers and caches each day that succeeds.
+func (app *application) fillHistoricCache(ctx context.Context, locId string) (fetched, failed int, err error) {
+	from, to := visitingTimesWindow()
+	cached, err := app.store.EbirdCache.GetCachedDates(ctx, locId, from, to)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var missing []time.Time
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		if !cached[date.Format("2006-01-02")] {
+			missing = append(missing, date)
+		}
+	}
+	if len(missing) == 0 {
+		return 0, 0, nil
+	}
+
+	jobs := make(chan time.Time, len(missing))
+	results := make(chan fetchResult, len(missing))
+	for w := 1; w <= ebirdFetchWorkers; w++ {
+		go func() {
+			for date := range jobs {
+				observations, err := app.ebirdClient.HistoricObservations(ctx, locId, date)
+				results <- fetchResult{date: date, observations: observations, err: err}
+			}
+		}()
+	}
+	for _, date := range missing {
+		jobs <- date
+	}
+	close(jobs)
+
+	for range missing {
+		result := <-results
+		if result.err == nil {
+			result.err = app.store.EbirdCache.SaveHistoricDay(ctx, locId, result.date, toHistoricObservations(result.observations))
+		}
+		if result.err != nil {
+			failed++
+			slog.Warn("Failed to cache eBird history", "locId", locId, "date", result.date.Format("2006-01-02"), "error", result.err)
+			continue
+		}
+		fetched++
+	}
+
+	return fetched, failed, nil
+}
+
+// toHistoricObservations keeps the time of day when eBird reports one
+// ("2006-01-02 15:04") and treats a missing count ("X") as unknown.
+func toHistoricObservations(observations []ebird.Observation) []*store.HistoricObservation {
+	rows := make([]*store.HistoricObservation, 0, len(observations))
+	for _, obs := range observations {
+		row := &store.HistoricObservation{SpeciesCode: obs.SpeciesCode}
+		if obsTime, err := time.Parse("2006-01-02 15:04", obs.ObsDt); err == nil {
+			clock := obsTime.Format("15:04")
+			row.ObsTime = &clock
+		}
+		row.HowMany = obs.HowMany
+		rows = append(rows, row)
+	}
+	return rows
 }
 
 func normalizeMonthCounts(counts map[time.Month]int) []MonthlyStat {
@@ -224,4 +386,256 @@ func normalizeHourCounts(counts map[int]int) []HourlyStat {
 		return stats[i].Hour < stats[j].Hour
 	})
 	return stats
-}
\ No newline at end of file
+}
+
+const (
+	defaultNearbyHotspotsKm  = 10
+	maxNearbyHotspotsKm      = 50
+	defaultSightingsBackDays = 14
+	maxSightingsBackDays     = 30
+	maxSightingsResults      = 200
+)
+
+// locIdPattern matches eBird location ids, which are also region codes of
+// the observation endpoints, so other region codes are rejected. The length
+// bound keeps ids within the VARCHAR(20) cache columns.
+var locIdPattern = regexp.MustCompile(`^L\d{1,19}$`)
+
+type HotspotResponse struct {
+	LocID             string  `json:"loc_id"`
+	Name              string  `json:"name"`
+	Latitude          float64 `json:"latitude"`
+	Longitude         float64 `json:"longitude"`
+	CountryCode       string  `json:"country_code"`
+	Subnational1Code  string  `json:"subnational1_code"`
+	Subnational2Code  string  `json:"subnational2_code,omitempty"`
+	LatestObsDate     string  `json:"latest_obs_date,omitempty"`
+	NumSpeciesAllTime int     `json:"num_species_all_time,omitempty"`
+}
+
+type HotspotDetailResponse struct {
+	LocID            string  `json:"loc_id"`
+	Name             string  `json:"name"`
+	Latitude         float64 `json:"latitude"`
+	Longitude        float64 `json:"longitude"`
+	CountryCode      string  `json:"country_code"`
+	CountryName      string  `json:"country_name"`
+	Subnational1Code string  `json:"subnational1_code"`
+	Subnational1Name string  `json:"subnational1_name"`
+	Subnational2Code string  `json:"subnational2_code,omitempty"`
+	Subnational2Name string  `json:"subnational2_name,omitempty"`
+	HierarchicalName string  `json:"hierarchical_name"`
+	BookmarkCount    int     `json:"bookmark_count"`
+	IsBookmarked     bool    `json:"is_bookmarked"`
+}
+
+// HotspotSighting is an eBird observation with its species names taken from
+// the local taxonomy, falling back to eBird's when the code is unknown.
+type HotspotSighting struct {
+	SpeciesCode      string  `json:"species_code"`
+	CommonName       string  `json:"common_name"`
+	ScientificName   string  `json:"scientific_name"`
+	FamilyCommonName *string `json:"family_common_name,omitempty"`
+	Count            *int    `json:"count"` // nil when reported as present without a count
+	ObservedAt       string  `json:"observed_at"`
+	LocID            string  `json:"loc_id"`
+	LocName          string  `json:"loc_name"`
+	Latitude         float64 `json:"latitude"`
+	Longitude        float64 `json:"longitude"`
+	IsReviewed       bool    `json:"is_reviewed"`
+	IsValid          bool    `json:"is_valid"`
+}
+
+// getNearbyHotspotsHandler lists the eBird hotspots within dist km of a point.
+func (app *application) getNearbyHotspotsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	lat, lng, err := parseLatLng(query.Get("lat"), query.Get("lng"))
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	dist := defaultNearbyHotspotsKm
+	if distStr := query.Get("dist"); distStr != "" {
+		dist, err = strconv.Atoi(distStr)
+		if err != nil || dist <= 0 || dist > maxNearbyHotspotsKm {
+			app.badRequest(w, r, fmt.Errorf("dist must be between 1 and %d", maxNearbyHotspotsKm))
+			return
+		}
+	}
+
+	// Rounding to about a kilometre lets small map pans share cached results.
+	lat = math.Round(lat*100) / 100
+	lng = math.Round(lng*100) / 100
+
+	hotspots, err := app.ebirdClient.NearbyHotspots(r.Context(), lat, lng, dist)
+	if err != nil {
+		app.ebirdError(w, r, err)
+		return
+	}
+
+	items := make([]HotspotResponse, 0, len(hotspots))
+	for _, h := range hotspots {
+		items = append(items, HotspotResponse{
+			LocID:             h.LocID,
+			Name:              h.LocName,
+			Latitude:          h.Lat,
+			Longitude:         h.Lng,
+			CountryCode:       h.CountryCode,
+			Subnational1Code:  h.Subnational1Code,
+			Subnational2Code:  h.Subnational2Code,
+			LatestObsDate:     h.LatestObsDt,
+			NumSpeciesAllTime: h.NumSpeciesAllTime,
+		})
+	}
+
+	response.JSON(w, http.StatusOK, items, false, "get successful")
+}
+
+func (app *application) getHotspotHandler(w http.ResponseWriter, r *http.Request) {
+	locId := r.PathValue("locId")
+	if !locIdPattern.MatchString(locId) {
+		app.notFound(w, r)
+		return
+	}
+
+	ctx := r.Context()
+	info, err := app.ebirdClient.HotspotInfo(ctx, locId)
+	if err != nil {
+		app.ebirdError(w, r, err)
+		return
+	}
+
+	bookmarkCount, err := app.store.Bookmarks.CountByHotspot(ctx, locId)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	isBookmarked := false
+	if user := app.getUserFromFirebaseClaimsCtx(r); user != nil {
+		isBookmarked, err = app.store.Bookmarks.Exists(ctx, user.Id, locId)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	response.JSON(w, http.StatusOK, HotspotDetailResponse{
+		LocID:            info.LocID,
+		Name:             info.Name,
+		Latitude:         info.Lat,
+		Longitude:        info.Lng,
+		CountryCode:      info.CountryCode,
+		CountryName:      info.CountryName,
+		Subnational1Code: info.Subnational1Code,
+		Subnational1Name: info.Subnational1Name,
+		Subnational2Code: info.Subnational2Code,
+		Subnational2Name: info.Subnational2Name,
+		HierarchicalName: info.HierarchicalName,
+		BookmarkCount:    bookmarkCount,
+		IsBookmarked:     isBookmarked,
+	}, false, "get successful")
+}
+
+// getHotspotRecentHandler lists the latest sighting of each species at a
+// hotspot within the last back days (default 14, at most 30).
+func (app *application) getHotspotRecentHandler(w http.ResponseWriter, r *http.Request) {
+	app.hotspotSightings(w, r, app.ebirdClient.RecentObservations)
+}
+
+// getHotspotNotableHandler lists the rare or unusual sightings at a hotspot
+// within the last back days.
+func (app *application) getHotspotNotableHandler(w http.ResponseWriter, r *http.Request) {
+	app.hotspotSightings(w, r, app.ebirdClient.NotableObservations)
+}
+
+func (app *application) hotspotSightings(w http.ResponseWriter, r *http.Request, fetch func(context.Context, string, ebird.ObservationOptions) ([]ebird.Observation, error)) {
+	locId := r.PathValue("locId")
+	if !locIdPattern.MatchString(locId) {
+		app.notFound(w, r)
+		return
+	}
+
+	opts := ebird.ObservationOptions{Back: defaultSightingsBackDays, MaxResults: maxSightingsResults}
+	if backStr := r.URL.Query().Get("back"); backStr != "" {
+		back, err := strconv.Atoi(backStr)
+		if err != nil || back < 1 || back > maxSightingsBackDays {
+			app.badRequest(w, r, fmt.Errorf("back must be between 1 and %d", maxSightingsBackDays))
+			return
+		}
+		opts.Back = back
+	}
+
+	ctx := r.Context()
+	observations, err := fetch(ctx, locId, opts)
+	if err != nil {
+		app.ebirdError(w, r, err)
+		return
+	}
+
+	sightings, err := app.resolveSightings(ctx, observations)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, sightings, false, "get successful")
+}
+
+// resolveSightings converts eBird observations, naming their species from
+// the local taxonomy.
+func (app *application) resolveSightings(ctx context.Context, observations []ebird.Observation) ([]HotspotSighting, error) {
+	codes := make([]string, 0, len(observations))
+	for _, obs := range observations {
+		codes = append(codes, obs.SpeciesCode)
+	}
+
+	taxa, err := app.store.Species.GetByCodes(ctx, codes)
+	if err != nil {
+		return nil, err
+	}
+
+	sightings := make([]HotspotSighting, 0, len(observations))
+	for _, obs := range observations {
+		sighting := HotspotSighting{
+			SpeciesCode:    obs.SpeciesCode,
+			CommonName:     obs.CommonName,
+			ScientificName: obs.ScientificName,
+			Count:          obs.HowMany,
+			ObservedAt:     obs.ObsDt,
+			LocID:          obs.LocID,
+			LocName:        obs.LocName,
+			Latitude:       obs.Lat,
+			Longitude:      obs.Lng,
+			IsReviewed:     obs.ObsReviewed,
+			IsValid:        obs.ObsValid,
+		}
+		if species, ok := taxa[obs.SpeciesCode]; ok {
+			sighting.CommonName = species.CommonName
+			sighting.ScientificName = species.ScientificName
+			sighting.FamilyCommonName = species.FamilyCommonName
+		}
+		sightings = append(sightings, sighting)
+	}
+	return sightings, nil
+}
+
+// ebirdError answers a failed eBird request: 404 when eBird does not know
+// the hotspot, and 502 when eBird is failing, so that clients can tell an
+// upstream outage from a bug here.
+func (app *application) ebirdError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, ebird.ErrNotFound) {
+		app.notFound(w, r)
+		return
+	}
+
+	var apiErr *ebird.APIError
+	if errors.As(err, &apiErr) {
+		app.reportServerError(r, err)
+		app.errorMessage(w, r, http.StatusBadGateway, "eBird is unavailable, please try again later", nil)
+		return
+	}
+
+	app.serverError(w, r, err)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sixync/birdlens-be/internal/ebird"
+)
+
+func TestGetNearbyHotspotsHandler(t *testing.T) {
+	fake := ebird.NewFakeServer()
+	defer fake.Close()
+	fake.AddHotspot(ebird.Hotspot{LocID: "L100", LocName: "Cat Tien", CountryCode: "VN", Lat: 11.42, Lng: 107.43, NumSpeciesAllTime: 350}, ebird.HotspotInfo{})
+	fake.AddHotspot(ebird.Hotspot{LocID: "L200", LocName: "Xuan Thuy", CountryCode: "VN", Lat: 20.22, Lng: 106.53}, ebird.HotspotInfo{})
+
+	app := newTestApplication(t)
+	app.ebirdClient = fake.Client()
+
+	rec := httptest.NewRecorder()
+	app.getNearbyHotspotsHandler(rec, httptest.NewRequest(http.MethodGet, "/hotspots/nearby?lat=11.4&lng=107.4&dist=20", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var hotspots []HotspotResponse
+	decodeResponse(t, rec, &hotspots)
+	if len(hotspots) != 1 || hotspots[0].LocID != "L100" || hotspots[0].Name != "Cat Tien" || hotspots[0].NumSpeciesAllTime != 350 {
+		t.Errorf("hotspots = %+v, want only Cat Tien", hotspots)
+	}
+}
+
+func TestGetNearbyHotspotsHandlerErrors(t *testing.T) {
+	fake := ebird.NewFakeServer()
+	defer fake.Close()
+
+	app := newTestApplication(t)
+	app.ebirdClient = fake.Client(ebird.WithRetries(0, 0))
+
+	tests := []struct {
+		name   string
+		target string
+		fail   int
+		want   int
+	}{
+		{"missing coordinates", "/hotspots/nearby", 0, http.StatusBadRequest},
+		{"distance too large", "/hotspots/nearby?lat=11&lng=107&dist=1000", 0, http.StatusBadRequest},
+		{"eBird down", "/hotspots/nearby?lat=11&lng=107", http.StatusServiceUnavailable, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.fail != 0 {
+				fake.FailNext(tt.fail)
+			}
+			rec := httptest.NewRecorder()
+			app.getNearbyHotspotsHandler(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
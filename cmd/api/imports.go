@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sixync/birdlens-be/internal/ebird"
+	"github.com/sixync/birdlens-be/internal/response"
+	"github.com/sixync/birdlens-be/internal/store"
+)
+
+const (
+	maxImportFileSize = 50 << 20
+	// importSaveEvery is how many rows are processed between progress updates.
+	importSaveEvery = 500
+)
+
+// createEbirdImportHandler accepts a My eBird Data CSV as the multipart field
+// "file" and imports it in the background. The optional "tz" field (an IANA
+// zone, default UTC) is the local time zone of the file's dates and times.
+// The file is parsed up front so that a wrong file is rejected immediately;
+// species mapping and inserts happen in the job.
+func (app *application) createEbirdImportHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize+1<<20)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		app.badRequest(w, r, fmt.Errorf("failed to parse form, files must be under %d MB: %w", maxImportFileSize>>20, err))
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		app.badRequest(w, r, errors.New("file is required"))
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxImportFileSize {
+		app.badRequest(w, r, fmt.Errorf("file must be under %d MB", maxImportFileSize>>20))
+		return
+	}
+
+	loc, err := time.LoadLocation(r.FormValue("tz"))
+	if err != nil {
+		app.badRequest(w, r, fmt.Errorf("invalid tz: %v", err))
+		return
+	}
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	rows, rowErrors, err := ebird.ReadMyData(bytes.NewReader(content))
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	filename := header.Filename
+	job := &store.ImportJob{
+		UserID:   user.Id,
+		Source:   store.ImportSourceEbird,
+		Filename: &filename,
+	}
+	if err := app.store.ImportJobs.Create(r.Context(), job); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// The job keeps its own copy so the response below is not raced.
+	running := *job
+	app.backgroundTask(r, func() error {
+		return app.runEbirdImport(context.Background(), &running, rows, rowErrors, loc)
+	})
+
+	response.JSON(w, http.StatusAccepted, job, false, "import started")
+}
+
+func (app *application) getImportsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	limit, offset := getPaginateFromCtx(r)
+	jobs, err := app.store.ImportJobs.GetByUserID(r.Context(), user.Id, limit, offset)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, jobs, false, "imports retrieved successfully")
+}
+
+// getImportHandler returns a job's status, progress counters and error report.
+func (app *application) getImportHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	importId, err := strconv.ParseInt(r.PathValue("import_id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, errors.New("invalid import_id"))
+		return
+	}
+
+	job, err := app.store.ImportJobs.GetByID(r.Context(), importId)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFound(w, r)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	if job.UserID != user.Id {
+		app.notFound(w, r)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, job, false, "import retrieved successfully")
+}
+
+// runEbirdImport turns each eBird submission into a checklist with private
+// observations. Submissions that were imported before are skipped as
+// duplicates, so re-uploading a newer export only adds what is new.
+func (app *application) runEbirdImport(ctx context.Context, job *store.ImportJob, rows []ebird.MyDataRow, rowErrors []ebird.RowError, loc *time.Location) error {
+	now := time.Now()
+	job.Status = store.ImportStatusRunning
+	job.StartedAt = &now
+	job.TotalRows = len(rows) + len(rowErrors)
+	job.ProcessedRows = len(rowErrors)
+	for _, rowErr := range rowErrors {
+		job.AddError(rowErr.Line, rowErr.Message)
+	}
+	if err := app.store.ImportJobs.Save(ctx, job); err != nil {
+		return err
+	}
+
+	err := app.importEbirdSubmissions(ctx, job, rows, loc)
+
+	finished := time.Now()
+	job.FinishedAt = &finished
+	job.Status = store.ImportStatusCompleted
+	if err != nil {
+		reason := err.Error()
+		job.Status = store.ImportStatusFailed
+		job.FailureReason = &reason
+	}
+
+	if saveErr := app.store.ImportJobs.Save(ctx, job); saveErr != nil {
+		return errors.Join(err, saveErr)
+	}
+	return err
+}
+
+func (app *application) importEbirdSubmissions(ctx context.Context, job *store.ImportJob, rows []ebird.MyDataRow, loc *time.Location) error {
+	var submissionIDs []string
+	submissions := map[string][]ebird.MyDataRow{}
+	for _, row := range rows {
+		if _, ok := submissions[row.SubmissionID]; !ok {
+			submissionIDs = append(submissionIDs, row.SubmissionID)
+		}
+		submissions[row.SubmissionID] = append(submissions[row.SubmissionID], row)
+	}
+
+	speciesCodes := map[string]string{}
+	lastSaved := job.ProcessedRows
+
+	for _, submissionID := range submissionIDs {
+		group := submissions[submissionID]
+
+		if err := app.importEbirdSubmission(ctx, job, submissionID, group, speciesCodes, loc); err != nil {
+			return err
+		}
+		job.ProcessedRows += len(group)
+
+		if job.ProcessedRows-lastSaved >= importSaveEvery {
+			if err := app.store.ImportJobs.Save(ctx, job); err != nil {
+				return err
+			}
+			lastSaved = job.ProcessedRows
+		}
+	}
+
+	return nil
+}
+
+// importEbirdSubmission records row-level problems on the job and only
+// returns an error when the import cannot continue.
+func (app *application) importEbirdSubmission(ctx context.Context, job *store.ImportJob, submissionID string, group []ebird.MyDataRow, speciesCodes map[string]string, loc *time.Location) error {
+	exists, err := app.store.Checklists.ExistsByExternalID(ctx, job.UserID, submissionID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		job.DuplicateRows += len(group)
+		return nil
+	}
+
+	checklist, err := ebirdRowToChecklist(group[0], loc)
+	if err != nil {
+		for _, row := range group {
+			job.AddError(row.Line, err.Error())
+		}
+		return nil
+	}
+	checklist.UserID = job.UserID
+
+	seen := map[string]bool{}
+	for _, row := range group {
+		key := row.ScientificName + "|" + row.CommonName
+		code, ok := speciesCodes[key]
+		if !ok {
+			code, err = app.store.Species.FindCodeByName(ctx, row.ScientificName, row.CommonName)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			speciesCodes[key] = code
+		}
+		if code == "" {
+			job.AddError(row.Line, fmt.Sprintf("unknown species %q (%s)", row.CommonName, row.ScientificName))
+			continue
+		}
+		if seen[code] {
+			job.DuplicateRows++
+			continue
+		}
+		seen[code] = true
+
+		obs := &store.Observation{
+			SpeciesCode: code,
+			Count:       row.Count,
+			IsPrivate:   true,
+		}
+		if fields := strings.Fields(row.BreedingCode); len(fields) > 0 {
+			breedingCode := strings.ToUpper(fields[0])
+			if _, ok := store.BreedingCodes[breedingCode]; ok {
+				obs.BreedingCode = &breedingCode
+			}
+		}
+		if row.ObservationDetails != "" {
+			notes := row.ObservationDetails
+			obs.Notes = &notes
+		}
+		checklist.Observations = append(checklist.Observations, obs)
+	}
+
+	if len(checklist.Observations) == 0 {
+		return nil
+	}
+
+	if err := app.store.Checklists.Create(ctx, checklist); err != nil {
+		for _, row := range group {
+			job.AddError(row.Line, fmt.Sprintf("failed to save checklist %s: %v", submissionID, err))
+		}
+		return nil
+	}
+
+	job.ChecklistsCreated++
+	job.ObservationsCreated += len(checklist.Observations)
+	return nil
+}
+
+// ebirdRowToChecklist builds the checklist header from any row of a submission;
+// eBird repeats the effort fields on every row.
+func ebirdRowToChecklist(row ebird.MyDataRow, loc *time.Location) (*store.Checklist, error) {
+	layout, value := "2006-01-02", row.Date
+	if row.Time != "" {
+		layout, value = "2006-01-02 03:04 PM", row.Date+" "+row.Time
+	}
+	startedAt, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date or time %q", value)
+	}
+
+	submissionID := row.SubmissionID
+	checklist := &store.Checklist{
+		ExternalID:        &submissionID,
+		LocationName:      row.Location,
+		StartedAt:         startedAt,
+		DurationMinutes:   row.DurationMinutes,
+		DistanceKm:        row.DistanceKm,
+		NumberOfObservers: 1,
+		IsComplete:        row.AllObsReported,
+	}
+
+	if checklist.LocationName == "" {
+		checklist.LocationName = "eBird location"
+		if row.LocationID != "" {
+			checklist.LocationName = row.LocationID
+		}
+	}
+	if name := []rune(checklist.LocationName); len(name) > 255 {
+		checklist.LocationName = string(name[:255])
+	}
+
+	if row.Latitude != nil && row.Longitude != nil {
+		checklist.Latitude = row.Latitude
+		checklist.Longitude = row.Longitude
+	}
+
+	if row.StateProvince != "" {
+		state := row.StateProvince
+		checklist.StateCode = &state
+		if country, _, _ := strings.Cut(state, "-"); len(country) == 2 {
+			country = strings.ToUpper(country)
+			checklist.CountryCode = &country
+		}
+	}
+
+	if row.NumberOfObservers != nil && *row.NumberOfObservers > 0 {
+		checklist.NumberOfObservers = *row.NumberOfObservers
+	}
+	if checklist.DurationMinutes != nil && *checklist.DurationMinutes <= 0 {
+		checklist.DurationMinutes = nil
+	}
+
+	if row.ChecklistComments != "" {
+		comments := row.ChecklistComments
+		checklist.Comments = &comments
+	}
+
+	protocol := strings.ToLower(row.Protocol)
+	switch {
+	case strings.Contains(protocol, "traveling"):
+		checklist.Protocol = store.ChecklistProtocolTraveling
+	case strings.Contains(protocol, "stationary"):
+		checklist.Protocol = store.ChecklistProtocolStationary
+		checklist.DistanceKm = nil
+	default:
+		checklist.Protocol = store.ChecklistProtocolIncidental
+		checklist.DurationMinutes = nil
+		checklist.DistanceKm = nil
+		checklist.IsComplete = false
+	}
+
+	return checklist, nil
+}
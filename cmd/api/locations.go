@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/sixync/birdlens-be/internal/response"
+	"github.com/sixync/birdlens-be/internal/store"
+)
+
+// maxLocationWalkDepth bounds how deep obfuscateSensitiveLocations looks
+// into a response; API responses are only a few levels deep.
+const maxLocationWalkDepth = 10
+
+// locationJSON writes data like response.JSON, after obfuscating every
+// sensitive-species location in it that the caller may not see exactly.
+// Every handler that returns posts or sightings responds through it, so the
+// policy lives in one place instead of in each handler.
+func (app *application) locationJSON(w http.ResponseWriter, r *http.Request, status int, data any, message string) {
+	data, err := app.obfuscateSensitiveLocations(r, data)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, status, data, false, message)
+}
+
+// obfuscateSensitiveLocations finds every store.SensitiveLocation in data and
+// snaps those of sensitive species to a grid, except for the record's owner
+// and for admins. Records are changed in place; data that is not a pointer is
+// copied first so that its nested values can be changed.
+func (app *application) obfuscateSensitiveLocations(r *http.Request, data any) (any, error) {
+	v := reflect.ValueOf(data)
+	if !v.IsValid() {
+		return data, nil
+	}
+	if v.Kind() != reflect.Pointer {
+		copied := reflect.New(v.Type())
+		copied.Elem().Set(v)
+		v = copied
+		data = copied.Interface()
+	}
+
+	var records []store.SensitiveLocation
+	collectSensitiveLocations(v, &records, 0)
+
+	viewer := app.getUserFromFirebaseClaimsCtx(r)
+	var candidates []store.SensitiveLocation
+	for _, record := range records {
+		if record.LocationSpeciesCode() == "" {
+			continue
+		}
+		if viewer != nil && record.LocationOwnerID() == viewer.Id {
+			continue
+		}
+		candidates = append(candidates, record)
+	}
+	if len(candidates) == 0 {
+		return data, nil
+	}
+
+	if viewer != nil {
+		isAdmin, err := app.userHasAnyRole(r.Context(), viewer.Id, store.ADMIN)
+		if err != nil {
+			return nil, err
+		}
+		if isAdmin {
+			return data, nil
+		}
+	}
+
+	sensitive, err := app.store.Species.GetSensitiveCodes(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range candidates {
+		if sensitive[record.LocationSpeciesCode()] {
+			record.ObfuscateLocation()
+		}
+	}
+
+	return data, nil
+}
+
+// collectSensitiveLocations walks v through pointers, interfaces, exported
+// struct fields, slices, arrays and map values. A struct that implements
+// store.SensitiveLocation is collected and not descended into, so a type that
+// embeds one is only collected once.
+func collectSensitiveLocations(v reflect.Value, records *[]store.SensitiveLocation, depth int) {
+	if depth > maxLocationWalkDepth {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			collectSensitiveLocations(v.Elem(), records, depth+1)
+		}
+	case reflect.Struct:
+		if v.CanAddr() {
+			if record, ok := v.Addr().Interface().(store.SensitiveLocation); ok {
+				*records = append(*records, record)
+				return
+			}
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				collectSensitiveLocations(v.Field(i), records, depth+1)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectSensitiveLocations(v.Index(i), records, depth+1)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			collectSensitiveLocations(iter.Value(), records, depth+1)
+		}
+	}
+}
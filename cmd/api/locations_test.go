@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sixync/birdlens-be/internal/store"
+)
+
+func TestObfuscateSensitiveLocations(t *testing.T) {
+	app := newTestApplication(t)
+	app.store = &store.Storage{Species: &fakeSpecies{sensitive: map[string]bool{"yelcar1": true}}}
+
+	ptr := func(v float64) *float64 { return &v }
+	str := func(v string) *string { return &v }
+	sensitive, common := "yelcar1", "houspa"
+	data := map[string]any{
+		"life_list": []*store.LifeListEntry{
+			{SpeciesCode: sensitive, UserID: 2, FirstLocationName: str("Nest tree, Cuc Phuong"), FirstLatitude: ptr(20.3141), FirstLongitude: ptr(105.6062)},
+			{SpeciesCode: common, UserID: 2, FirstLocationName: str("Hoan Kiem Lake"), FirstLatitude: ptr(21.0288), FirstLongitude: ptr(105.8525)},
+		},
+		"posts": []PostResponse{
+			{TaggedSpeciesCode: &sensitive, LocationName: "Nest tree, Cuc Phuong", Latitude: 20.3141, Longitude: 105.6062, userID: 2},
+			// A name alone is hidden too.
+			{TaggedSpeciesCode: &sensitive, LocationName: "Nest tree, Cuc Phuong", userID: 2},
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	obfuscated, err := app.obfuscateSensitiveLocations(req, data)
+	if err != nil {
+		t.Fatalf("obfuscateSensitiveLocations() error = %v", err)
+	}
+	got := *obfuscated.(*map[string]any)
+
+	lifeList := got["life_list"].([]*store.LifeListEntry)
+	if e := lifeList[0]; e.FirstLocationName != nil || !e.LocationObfuscated || *e.FirstLatitude != store.SnapToSensitiveGrid(20.3141) || *e.FirstLongitude != store.SnapToSensitiveGrid(105.6062) {
+		t.Errorf("sensitive life list entry = %+v, want its location snapped and its name dropped", e)
+	}
+	if e := lifeList[1]; e.FirstLocationName == nil || *e.FirstLocationName != "Hoan Kiem Lake" || e.LocationObfuscated || *e.FirstLatitude != 21.0288 {
+		t.Errorf("common life list entry = %+v, want it untouched", e)
+	}
+
+	posts := got["posts"].([]PostResponse)
+	if p := posts[0]; p.LocationName != "" || !p.LocationObfuscated || p.Latitude != store.SnapToSensitiveGrid(20.3141) {
+		t.Errorf("sensitive post = %+v, want its location snapped and its name dropped", p)
+	}
+	if p := posts[1]; p.LocationName != "" || !p.LocationObfuscated || p.Latitude != 0 {
+		t.Errorf("sensitive post without coordinates = %+v, want its name dropped", p)
+	}
+}
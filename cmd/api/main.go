@@ -16,13 +16,16 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/lmittmann/tint"
 	"github.com/sixync/birdlens-be/auth"
+	"github.com/sixync/birdlens-be/internal/ai"
 	"github.com/sixync/birdlens-be/internal/database"
+	"github.com/sixync/birdlens-be/internal/ebird"
 	"github.com/sixync/birdlens-be/internal/env"
 	"github.com/sixync/birdlens-be/internal/jwt"
 	"github.com/sixync/birdlens-be/internal/smtp"
 	"github.com/sixync/birdlens-be/internal/store"
 	mediamanager "github.com/sixync/birdlens-be/internal/store/media_manager"
 	"github.com/sixync/birdlens-be/internal/version"
+	"golang.org/x/sync/singleflight"
 	"google.golang.org/api/option"
 )
 
@@ -56,7 +59,17 @@ type config struct {
 		apiKey string
 	}
 	gemini struct {
-		apiKey string
+		apiKey        string
+		identifyModel string
+		chatModel     string
+	}
+	ai struct {
+		identifierProvider string
+		assistantProvider  string
+		classifierURL      string
+		freeLimits         store.AILimits
+		exBirdLimits       store.AILimits
+		profileTTLDays     int
 	}
 	payos struct {
 		clientID    string
@@ -79,6 +92,11 @@ type application struct {
 	authService *auth.AuthService
 	tokenMaker  *jwt.JWTMaker
 	mediaClient mediamanager.MediaClient
+	ebirdClient ebird.Client
+	ai          *ai.Service
+	// profiles lets concurrent requests for a missing species profile
+	// share one generation.
+	profiles singleflight.Group
 }
 
 var JobQueue = make(chan EmailJob, 100)
@@ -167,6 +185,22 @@ func run(logger *slog.Logger) error {
 	// Logic: The reading of Stripe keys is removed.
 	cfg.eBird.apiKey = env.GetString("EBIRD_API_KEY", "")
 	cfg.gemini.apiKey = env.GetString("GEMINI_API_KEY", "")
+	cfg.gemini.identifyModel = env.GetString("GEMINI_IDENTIFY_MODEL", ai.DefaultGeminiModel)
+	cfg.gemini.chatModel = env.GetString("GEMINI_CHAT_MODEL", ai.DefaultGeminiModel)
+	cfg.ai.identifierProvider = env.GetString("AI_IDENTIFIER_PROVIDER", "gemini")
+	cfg.ai.assistantProvider = env.GetString("AI_ASSISTANT_PROVIDER", "gemini")
+	cfg.ai.classifierURL = env.GetString("AI_CLASSIFIER_URL", "")
+	cfg.ai.freeLimits = store.AILimits{
+		DailyIdentifications: env.GetInt("AI_FREE_DAILY_IDENTIFICATIONS", 10),
+		DailyQuestions:       env.GetInt("AI_FREE_DAILY_QUESTIONS", 30),
+		DailyTokens:          env.GetInt("AI_FREE_DAILY_TOKENS", 100000),
+	}
+	cfg.ai.exBirdLimits = store.AILimits{
+		DailyIdentifications: env.GetInt("AI_EXBIRD_DAILY_IDENTIFICATIONS", 100),
+		DailyQuestions:       env.GetInt("AI_EXBIRD_DAILY_QUESTIONS", 300),
+		DailyTokens:          env.GetInt("AI_EXBIRD_DAILY_TOKENS", 1000000),
+	}
+	cfg.ai.profileTTLDays = env.GetInt("AI_PROFILE_TTL_DAYS", 30)
 	cfg.payos.clientID = env.GetString("PAYOS_CLIENT_ID", "")
 	cfg.payos.apiKey = env.GetString("PAYOS_API_KEY", "")
 	cfg.payos.checksumKey = env.GetString("PAYOS_CHECKSUM_KEY", "")
@@ -177,7 +211,8 @@ func run(logger *slog.Logger) error {
 	if cfg.eBird.apiKey == "" {
 		slog.Warn("EBIRD_API_KEY is not set. Premium features for hotspot analysis will fail.")
 	}
-	if cfg.gemini.apiKey == "" {
+	usesGemini := cfg.ai.identifierProvider == "gemini" || cfg.ai.assistantProvider == "gemini"
+	if usesGemini && cfg.gemini.apiKey == "" {
 		slog.Error("CRITICAL: GEMINI_API_KEY is not set. AI features will fail.")
 		return errors.New("GEMINI_API_KEY is not configured")
 	}
@@ -268,6 +303,20 @@ func run(logger *slog.Logger) error {
 	}
 	slog.Info("Cloudinary client initialized.")
 
+	slog.Info("Initializing AI providers...", "identifier", cfg.ai.identifierProvider, "assistant", cfg.ai.assistantProvider)
+	aiService, err := ai.Open(context.Background(), cfg.ai.identifierProvider, cfg.ai.assistantProvider, ai.Config{
+		APIKey:        cfg.gemini.apiKey,
+		IdentifyModel: cfg.gemini.identifyModel,
+		ChatModel:     cfg.gemini.chatModel,
+		Endpoint:      cfg.ai.classifierURL,
+	})
+	if err != nil {
+		slog.Error("Failed to initialize AI providers", "error", err)
+		return fmt.Errorf("ai.Open failed: %w", err)
+	}
+	defer aiService.Close()
+	slog.Info("AI providers initialized.")
+
 	app := &application{
 		config:      cfg,
 		store:       store,
@@ -276,9 +325,16 @@ func run(logger *slog.Logger) error {
 		tokenMaker:  tokenMaker,
 		authService: authService,
 		mediaClient: cldClient,
+		ebirdClient: ebird.NewCachingClient(ebird.NewClient(cfg.eBird.apiKey)),
+		ai:          aiService,
 	}
 	slog.Info("Application struct fully initialized.")
 
+	if cfg.eBird.apiKey != "" {
+		app.startHotspotRefresher()
+	}
+	app.startRareBirdAlerts()
+
 	slog.Info("Starting HTTP server...")
 	return app.serveHTTP()
 }
@@ -129,6 +129,17 @@ func (app *application) authMiddleware(next http.Handler) http.Handler {
 // adminOnlyMiddleware checks if the authenticated user has the 'admin' role.
 // It must run *after* the authMiddleware.
 func (app *application) adminOnlyMiddleware(next http.Handler) http.Handler {
+	return app.requireAnyRole(next, store.ADMIN)
+}
+
+// operatorOnlyMiddleware lets through admins and users with the 'tour_operator' role.
+// Per-tour ownership is checked separately in getTourMiddleware.
+// It must run *after* the authMiddleware.
+func (app *application) operatorOnlyMiddleware(next http.Handler) http.Handler {
+	return app.requireAnyRole(next, store.ADMIN, store.TOUR_OPERATOR)
+}
+
+func (app *application) requireAnyRole(next http.Handler, allowed ...string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := app.getUserFromFirebaseClaimsCtx(r)
 		if user == nil {
@@ -136,25 +147,33 @@ func (app *application) adminOnlyMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		roles, err := app.store.Roles.GetUserRoles(r.Context(), user.Id)
+		ok, err := app.userHasAnyRole(r.Context(), user.Id, allowed...)
 		if err != nil {
 			app.serverError(w, r, err)
 			return
 		}
 
-		isAdmin := false
-		for _, role := range roles {
-			if role == store.ADMIN {
-				isAdmin = true
-				break
-			}
-		}
-
-		if !isAdmin {
+		if !ok {
 			app.errorMessage(w, r, http.StatusForbidden, "You do not have permission to access this resource.", nil)
 			return
 		}
 
 		next.ServeHTTP(w, r)
 	})
-}
\ No newline at end of file
+}
+
+func (app *application) userHasAnyRole(ctx context.Context, userID int64, allowed ...string) (bool, error) {
+	roles, err := app.store.Roles.GetUserRoles(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, role := range roles {
+		for _, a := range allowed {
+			if role == a {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
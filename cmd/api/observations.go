@@ -0,0 +1,423 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sixync/birdlens-be/internal/request"
+	"github.com/sixync/birdlens-be/internal/response"
+	"github.com/sixync/birdlens-be/internal/store"
+)
+
+var ObservationKey key = "observation"
+
+// ObservationRequest is used for both create and update. On update, omitted
+// fields keep their current value.
+type ObservationRequest struct {
+	SpeciesCode  *string  `json:"species_code"`
+	Count        *int     `json:"count"`
+	Latitude     *float64 `json:"latitude"`
+	Longitude    *float64 `json:"longitude"`
+	LocationName *string  `json:"location_name"`
+	CountryCode  *string  `json:"country_code"`
+	ObservedAt   *string  `json:"observed_at"`
+	BreedingCode *string  `json:"breeding_code"`
+	BehaviorCode *string  `json:"behavior_code"`
+	Notes        *string  `json:"notes"`
+	IsPrivate    *bool    `json:"is_private"`
+	PostID       *int64   `json:"post_id"`
+	ChecklistID  *int64   `json:"checklist_id"`
+}
+
+func (app *application) createObservationHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	var req ObservationRequest
+	if err := request.DecodeJSON(w, r, &req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if req.SpeciesCode == nil || *req.SpeciesCode == "" {
+		app.badRequest(w, r, errors.New("species_code is required"))
+		return
+	}
+	if req.ObservedAt == nil {
+		app.badRequest(w, r, errors.New("observed_at is required"))
+		return
+	}
+
+	obs := &store.Observation{UserID: user.Id}
+	if err := app.applyObservationRequest(r.Context(), user, obs, &req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := app.store.Observations.Create(r.Context(), obs); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.locationJSON(w, r, http.StatusCreated, obs, "observation created successfully")
+}
+
+func (app *application) getObservationsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := store.ObservationFilter{SpeciesCode: query.Get("species_code")}
+
+	if checklistStr := query.Get("checklist_id"); checklistStr != "" {
+		checklistId, err := strconv.ParseInt(checklistStr, 10, 64)
+		if err != nil {
+			app.badRequest(w, r, errors.New("invalid checklist_id"))
+			return
+		}
+		filter.ChecklistID = &checklistId
+	}
+
+	if fromStr := query.Get("from"); fromStr != "" {
+		from, err := time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			app.badRequest(w, r, fmt.Errorf("invalid from: %v", err))
+			return
+		}
+		filter.From = &from
+	}
+
+	if toStr := query.Get("to"); toStr != "" {
+		to, err := time.Parse("2006-01-02", toStr)
+		if err != nil {
+			app.badRequest(w, r, fmt.Errorf("invalid to: %v", err))
+			return
+		}
+		to = to.Add(24*time.Hour - time.Second)
+		filter.To = &to
+	}
+
+	limit, offset := getPaginateFromCtx(r)
+	observations, err := app.store.Observations.GetByUserID(r.Context(), user.Id, filter, limit, offset)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.locationJSON(w, r, http.StatusOK, observations, "observations retrieved successfully")
+}
+
+func (app *application) getObservationHandler(w http.ResponseWriter, r *http.Request) {
+	obs := getObservationFromCtx(r)
+	if obs == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	app.locationJSON(w, r, http.StatusOK, obs, "observation retrieved successfully")
+}
+
+func (app *application) updateObservationHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	obs := getObservationFromCtx(r)
+	if obs == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	var req ObservationRequest
+	if err := request.DecodeJSON(w, r, &req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := app.applyObservationRequest(r.Context(), user, obs, &req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := app.store.Observations.Update(r.Context(), obs); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.locationJSON(w, r, http.StatusOK, obs, "observation updated successfully")
+}
+
+func (app *application) deleteObservationHandler(w http.ResponseWriter, r *http.Request) {
+	obs := getObservationFromCtx(r)
+	if obs == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	err := app.store.Observations.Delete(r.Context(), obs.ID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFound(w, r)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, nil, false, "observation deleted successfully")
+}
+
+// applyObservationRequest validates the request and copies every provided field onto obs.
+func (app *application) applyObservationRequest(ctx context.Context, user *store.User, obs *store.Observation, req *ObservationRequest) error {
+	if req.SpeciesCode != nil {
+		code := strings.TrimSpace(*req.SpeciesCode)
+		if code == "" || len(code) > 20 {
+			return errors.New("species_code must be between 1 and 20 characters")
+		}
+		exists, err := app.store.Species.Exists(ctx, code)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("unknown species_code: %s", code)
+		}
+		obs.SpeciesCode = code
+	}
+
+	if req.Count != nil {
+		if *req.Count <= 0 {
+			return errors.New("count must be a positive number; omit it to record the species as present")
+		}
+		obs.Count = req.Count
+	}
+
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		return errors.New("latitude and longitude must be provided together")
+	}
+	if req.Latitude != nil {
+		if *req.Latitude < -90 || *req.Latitude > 90 || *req.Longitude < -180 || *req.Longitude > 180 {
+			return errors.New("latitude or longitude is out of range")
+		}
+		obs.Latitude = req.Latitude
+		obs.Longitude = req.Longitude
+	}
+
+	if req.LocationName != nil {
+		obs.LocationName = req.LocationName
+	}
+
+	if req.CountryCode != nil {
+		code := strings.ToUpper(strings.TrimSpace(*req.CountryCode))
+		if len(code) != 2 {
+			return errors.New("country_code must be a two-letter ISO code")
+		}
+		obs.CountryCode = &code
+	}
+
+	if req.ObservedAt != nil {
+		observedAt, err := time.Parse(time.RFC3339, *req.ObservedAt)
+		if err != nil {
+			return fmt.Errorf("invalid observed_at, expected RFC3339: %v", err)
+		}
+		if observedAt.After(time.Now().Add(time.Hour)) {
+			return errors.New("observed_at cannot be in the future")
+		}
+		obs.ObservedAt = observedAt
+	}
+
+	if req.BreedingCode != nil {
+		code := strings.ToUpper(strings.TrimSpace(*req.BreedingCode))
+		if _, ok := store.BreedingCodes[code]; !ok {
+			return fmt.Errorf("unknown breeding_code: %s", *req.BreedingCode)
+		}
+		obs.BreedingCode = &code
+	}
+
+	if req.BehaviorCode != nil {
+		code := strings.ToLower(strings.TrimSpace(*req.BehaviorCode))
+		if !store.BehaviorCodes[code] {
+			return fmt.Errorf("unknown behavior_code: %s", *req.BehaviorCode)
+		}
+		obs.BehaviorCode = &code
+	}
+
+	if req.Notes != nil {
+		obs.Notes = req.Notes
+	}
+
+	if req.IsPrivate != nil {
+		obs.IsPrivate = *req.IsPrivate
+	}
+
+	if req.PostID != nil {
+		post, err := app.store.Posts.GetById(ctx, *req.PostID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.New("post not found")
+			}
+			return err
+		}
+		if post.UserId != user.Id {
+			return errors.New("observations can only be linked to your own posts")
+		}
+		obs.PostID = req.PostID
+	}
+
+	if req.ChecklistID != nil {
+		checklist, err := app.store.Checklists.GetByID(ctx, *req.ChecklistID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.New("checklist not found")
+			}
+			return err
+		}
+		if checklist.UserID != user.Id {
+			return errors.New("observations can only be added to your own checklists")
+		}
+		obs.ChecklistID = req.ChecklistID
+	}
+
+	return nil
+}
+
+// observationForPost is the observation of the species tagged on a sighting
+// post. Without a sighting date it is dated when the post is created.
+func observationForPost(post *store.Post) *store.Observation {
+	obs := &store.Observation{
+		UserID:      post.UserId,
+		SpeciesCode: *post.TaggedSpeciesCode,
+		IsPrivate:   post.PrivacyLevel != "" && post.PrivacyLevel != "public",
+	}
+	if post.SightingDate != nil {
+		obs.ObservedAt = *post.SightingDate
+	}
+	if post.Latitude != 0 || post.Longitude != 0 {
+		obs.Latitude = &post.Latitude
+		obs.Longitude = &post.Longitude
+	}
+	if post.LocationName != "" {
+		obs.LocationName = &post.LocationName
+	}
+	return obs
+}
+
+// getObservationMiddleware loads the observation and only lets its owner through.
+func (app *application) getObservationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := app.getUserFromFirebaseClaimsCtx(r)
+		if user == nil {
+			app.unauthorized(w, r)
+			return
+		}
+
+		observationId, err := strconv.ParseInt(r.PathValue("observation_id"), 10, 64)
+		if err != nil {
+			app.badRequest(w, r, errors.New("invalid observation_id"))
+			return
+		}
+
+		obs, err := app.store.Observations.GetByID(r.Context(), observationId)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			app.notFound(w, r)
+			return
+		case err != nil:
+			app.serverError(w, r, err)
+			return
+		}
+
+		if obs.UserID != user.Id {
+			app.notFound(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ObservationKey, obs)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getObservationFromCtx(r *http.Request) *store.Observation {
+	obs, _ := r.Context().Value(ObservationKey).(*store.Observation)
+	return obs
+}
+
+const (
+	// heatmapGridCells is how many cells span the longer side of the bbox.
+	heatmapGridCells      = 64
+	minHeatmapCellDegrees = 0.001
+)
+
+type HeatmapResponse struct {
+	CellDegrees float64              `json:"cell_degrees"`
+	MaxCount    int                  `json:"max_count"`
+	Cells       []*store.HeatmapCell `json:"cells"`
+}
+
+// getSightingsHeatmapHandler aggregates public observations inside
+// bbox=minLng,minLat,maxLng,maxLat into a grid sized to the box. species (a
+// species code) and from/to (YYYY-MM-DD, inclusive) narrow the sightings.
+func (app *application) getSightingsHeatmapHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	bbox, err := parseBBox(query.Get("bbox"))
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	if bbox.MaxLng-bbox.MinLng > maxRangeBBoxDegrees || bbox.MaxLat-bbox.MinLat > maxRangeBBoxDegrees {
+		app.badRequest(w, r, fmt.Errorf("bbox sides must be at most %d degrees", maxRangeBBoxDegrees))
+		return
+	}
+
+	filter := store.HeatmapFilter{
+		BBox:        bbox,
+		CellDegrees: max(bbox.MaxLng-bbox.MinLng, bbox.MaxLat-bbox.MinLat) / heatmapGridCells,
+		SpeciesCode: query.Get("species"),
+	}
+	filter.CellDegrees = max(filter.CellDegrees, minHeatmapCellDegrees)
+
+	if fromStr := query.Get("from"); fromStr != "" {
+		from, err := time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			app.badRequest(w, r, fmt.Errorf("invalid from: %v", err))
+			return
+		}
+		filter.From = &from
+	}
+
+	if toStr := query.Get("to"); toStr != "" {
+		to, err := time.Parse("2006-01-02", toStr)
+		if err != nil {
+			app.badRequest(w, r, fmt.Errorf("invalid to: %v", err))
+			return
+		}
+		to = to.Add(24*time.Hour - time.Second)
+		filter.To = &to
+	}
+
+	cells, err := app.store.Observations.GetHeatmap(r.Context(), filter)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	res := HeatmapResponse{CellDegrees: filter.CellDegrees, Cells: cells}
+	for _, cell := range cells {
+		res.MaxCount = max(res.MaxCount, cell.Count)
+	}
+
+	response.JSON(w, http.StatusOK, res, false, "heatmap retrieved successfully")
+}
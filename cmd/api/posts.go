@@ -10,6 +10,7 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -37,6 +38,33 @@ type PostResponse struct {
 	LocationName      string     `json:"location_name,omitempty"`
 	Latitude          float64    `json:"latitude,omitempty"`
 	Longitude         float64    `json:"longitude,omitempty"`
+	// LocationObfuscated is set when the coordinates were snapped to a grid
+	// because the tagged species is sensitive.
+	LocationObfuscated bool `json:"location_obfuscated,omitempty"`
+
+	userID int64
+}
+
+func (p *PostResponse) LocationOwnerID() int64 { return p.userID }
+
+func (p *PostResponse) LocationSpeciesCode() string {
+	if p.TaggedSpeciesCode == nil {
+		return ""
+	}
+	return *p.TaggedSpeciesCode
+}
+
+func (p *PostResponse) ObfuscateLocation() {
+	if p.LocationName != "" {
+		p.LocationName = ""
+		p.LocationObfuscated = true
+	}
+	if p.Latitude == 0 && p.Longitude == 0 {
+		return
+	}
+	p.Latitude = store.SnapToSensitiveGrid(p.Latitude)
+	p.Longitude = store.SnapToSensitiveGrid(p.Longitude)
+	p.LocationObfuscated = true
 }
 
 func (app *application) getPostsHandler(w http.ResponseWriter, r *http.Request) {
@@ -67,52 +95,11 @@ func (app *application) getPostsHandler(w http.ResponseWriter, r *http.Request)
 	var postResponses []PostResponse
 
 	for _, post := range posts.Items {
-		var postResponse PostResponse
-
-		postResponse.ID = post.Id
-		poster, err := app.store.Users.GetById(ctx, post.UserId)
+		postResponse, err := app.buildPostResponse(ctx, currentUser.Id, post)
 		if err != nil {
 			app.serverError(w, r, err)
 			return
 		}
-
-		postResponse.PosterAvatarUrl = poster.AvatarUrl
-		postResponse.PosterName = poster.Username
-		postResponse.CreatedAt = post.CreatedAt
-		postResponse.Content = post.Content
-		postResponse.Type = post.Type
-		postResponse.SightingDate = post.SightingDate
-		postResponse.TaggedSpeciesCode = post.TaggedSpeciesCode
-		postResponse.LocationName = post.LocationName
-		postResponse.Latitude = post.Latitude
-		postResponse.Longitude = post.Longitude
-		likes, err := app.store.Posts.GetLikeCounts(ctx, post.Id)
-		if err != nil {
-			app.serverError(w, r, err)
-			return
-		}
-		log.Println("likes count for post", post.Id, "is", likes)
-		postResponse.LikesCount = likes
-		comments, err := app.store.Posts.GetCommentCounts(ctx, post.Id)
-		if err != nil {
-			app.serverError(w, r, err)
-			return
-		}
-		log.Println("comments count for post", post.Id, "is", comments)
-		postResponse.CommentsCount = comments
-		imageUrls, err := app.store.Posts.GetMediaUrlsById(ctx, post.Id)
-		if err != nil {
-			app.serverError(w, r, err)
-			return
-		}
-		log.Println("image urls for post", post.Id, "are", imageUrls)
-		postResponse.ImagesUrls = imageUrls
-		isLiked, err := app.store.Posts.UserLiked(ctx, currentUser.Id, post.Id)
-		if err != nil {
-			app.serverError(w, r, err)
-		}
-		log.Println("is liked by user", currentUser.Id, "for post", post.Id, "is", isLiked)
-		postResponse.IsLiked = isLiked
 		postResponses = append(postResponses, postResponse)
 	}
 	res := store.PaginatedList[PostResponse]{
@@ -125,9 +112,57 @@ func (app *application) getPostsHandler(w http.ResponseWriter, r *http.Request)
 
 	log.Println("post responses", postResponses)
 
-	response.JSON(w, http.StatusOK, res, false, "get successful")
+	app.locationJSON(w, r, http.StatusOK, res, "get successful")
 }
 
+// buildPostResponse adds the poster, counts, media and the viewer's like to a post.
+func (app *application) buildPostResponse(ctx context.Context, viewerID int64, post *store.Post) (PostResponse, error) {
+	var postResponse PostResponse
+
+	postResponse.ID = post.Id
+	postResponse.userID = post.UserId
+	postResponse.LocationObfuscated = post.LocationObfuscated
+	poster, err := app.store.Users.GetById(ctx, post.UserId)
+	if err != nil {
+		return postResponse, err
+	}
+
+	postResponse.PosterAvatarUrl = poster.AvatarUrl
+	postResponse.PosterName = poster.Username
+	postResponse.CreatedAt = post.CreatedAt
+	postResponse.Content = post.Content
+	postResponse.Type = post.Type
+	postResponse.SightingDate = post.SightingDate
+	postResponse.TaggedSpeciesCode = post.TaggedSpeciesCode
+	postResponse.LocationName = post.LocationName
+	postResponse.Latitude = post.Latitude
+	postResponse.Longitude = post.Longitude
+	likes, err := app.store.Posts.GetLikeCounts(ctx, post.Id)
+	if err != nil {
+		return postResponse, err
+	}
+	log.Println("likes count for post", post.Id, "is", likes)
+	postResponse.LikesCount = likes
+	comments, err := app.store.Posts.GetCommentCounts(ctx, post.Id)
+	if err != nil {
+		return postResponse, err
+	}
+	log.Println("comments count for post", post.Id, "is", comments)
+	postResponse.CommentsCount = comments
+	imageUrls, err := app.store.Posts.GetMediaUrlsById(ctx, post.Id)
+	if err != nil {
+		return postResponse, err
+	}
+	log.Println("image urls for post", post.Id, "are", imageUrls)
+	postResponse.ImagesUrls = imageUrls
+	isLiked, err := app.store.Posts.UserLiked(ctx, viewerID, post.Id)
+	if err != nil {
+		return postResponse, err
+	}
+	log.Println("is liked by user", viewerID, "for post", post.Id, "is", isLiked)
+	postResponse.IsLiked = isLiked
+	return postResponse, nil
+}
 
 func (app *application) addUserReactionHandler(w http.ResponseWriter, r *http.Request) {
 	currentUser := app.getUserFromFirebaseClaimsCtx(r)
@@ -185,10 +220,37 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 	post.IsFeatured = r.FormValue("is_featured") == "true"
 	post.UserId = currentUser.Id
 
+	if code := strings.TrimSpace(r.FormValue("tagged_species_code")); code != "" {
+		exists, err := app.store.Species.Exists(r.Context(), code)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		if !exists {
+			app.badRequest(w, r, fmt.Errorf("unknown tagged_species_code: %s", code))
+			return
+		}
+		post.TaggedSpeciesCode = &code
+	}
+	if sightingDateStr := r.FormValue("sighting_date"); sightingDateStr != "" {
+		sightingDate, err := time.Parse(time.RFC3339, sightingDateStr)
+		if err != nil {
+			app.badRequest(w, r, fmt.Errorf("invalid sighting_date, expected RFC3339: %v", err))
+			return
+		}
+		post.SightingDate = &sightingDate
+	}
+
 	log.Println("post is", post)
 
 	ctx := r.Context()
-	if err := app.store.Posts.Create(ctx, &post); err != nil {
+	var err error
+	if post.Type == "sighting" && post.TaggedSpeciesCode != nil {
+		err = app.store.Posts.CreateWithObservation(ctx, &post, observationForPost(&post))
+	} else {
+		err = app.store.Posts.Create(ctx, &post)
+	}
+	if err != nil {
 		log.Println("error creating post:", err)
 		app.serverError(w, r, fmt.Errorf("failed to create post: %w", err))
 		return
@@ -285,7 +347,7 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 
 	log.Println("uploaded media urls", urls)
 
-	response.JSON(w, http.StatusCreated, post, false, "post created successfully")
+	app.locationJSON(w, r, http.StatusCreated, post, "post created successfully")
 }
 
 // Logic: This function now checks the post count and creates a notification.
@@ -401,4 +463,78 @@ func (app *application) getPostRetrievalStrategy(strategy string) services.PostR
 	}
 
 	return postRetrievalStrategy
-}
\ No newline at end of file
+}
+const (
+	defaultNearbyRadiusKm = 10
+	maxNearbyRadiusKm     = 50
+)
+
+type NearbyPostResponse struct {
+	PostResponse
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// getNearbyPostsHandler lists the posts within radius_km (default 10, at most
+// 50) of lat/lng, nearest first. Only public posts and the caller's own are
+// included. Sensitive species posted by others get a coarse location, which
+// the distance is also measured from, unless the caller is an admin.
+func (app *application) getNearbyPostsHandler(w http.ResponseWriter, r *http.Request) {
+	currentUser := app.getUserFromFirebaseClaimsCtx(r)
+	if currentUser == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	lat, lng, err := parseLatLng(query.Get("lat"), query.Get("lng"))
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	radiusKm := float64(defaultNearbyRadiusKm)
+	if radiusStr := query.Get("radius_km"); radiusStr != "" {
+		radiusKm, err = strconv.ParseFloat(radiusStr, 64)
+		if err != nil || radiusKm <= 0 || radiusKm > maxNearbyRadiusKm {
+			app.badRequest(w, r, fmt.Errorf("radius_km must be greater than 0 and at most %d", maxNearbyRadiusKm))
+			return
+		}
+	}
+
+	ctx := r.Context()
+	isAdmin, err := app.userHasAnyRole(ctx, currentUser.Id, store.ADMIN)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	limit, offset := getPaginateFromCtx(r)
+	posts, err := app.store.Posts.GetNearby(ctx, currentUser.Id, lat, lng, radiusKm, isAdmin, limit, offset)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	items := make([]NearbyPostResponse, 0, len(posts.Items))
+	for _, post := range posts.Items {
+		postResponse, err := app.buildPostResponse(ctx, currentUser.Id, &post.Post)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		items = append(items, NearbyPostResponse{
+			PostResponse: postResponse,
+			DistanceKm:   post.DistanceKm,
+		})
+	}
+
+	res := store.PaginatedList[NearbyPostResponse]{
+		Items:      items,
+		TotalCount: posts.TotalCount,
+		TotalPages: posts.TotalPages,
+		Page:       posts.Page,
+		PageSize:   posts.PageSize,
+	}
+
+	app.locationJSON(w, r, http.StatusOK, res, "nearby posts retrieved successfully")
+}
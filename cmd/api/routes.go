@@ -6,6 +6,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
+	"github.com/sixync/birdlens-be/internal/store"
 )
 
 func (app *application) routes() http.Handler {
@@ -21,7 +22,7 @@ func (app *application) routes() http.Handler {
 		AllowedOrigins:   []string{"https://birdlens.netlify.app", "http://localhost:5173"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", "Retry-After", "X-AI-Quota-Limit", "X-AI-Quota-Remaining", "X-AI-Quota-Reset", "X-AI-Tokens-Remaining"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -32,11 +33,14 @@ func (app *application) routes() http.Handler {
 	mux.Route("/posts", func(r chi.Router) {
 		r.With(app.authMiddleware).With(app.paginate).Get("/", app.getPostsHandler)
 		r.With(app.authMiddleware).Post("/", app.createPostHandler)
+		r.With(app.authMiddleware).With(app.paginate).Get("/nearby", app.getNearbyPostsHandler)
 		r.With(app.authMiddleware).With(app.getPostMiddleware).With(app.paginate).Get("/{post_id}/comments", app.getPostCommentsHandler)
 		r.With(app.authMiddleware).With(app.getPostMiddleware).Post("/{post_id}/reactions", app.addUserReactionHandler)
 		r.With(app.authMiddleware).With(app.getPostMiddleware).Post("/{post_id}/comments", app.createCommentHandler)
 	})
 
+	mux.With(app.authMiddleware).Get("/sightings/heatmap", app.getSightingsHeatmapHandler)
+
 	mux.Route("/comments", func(r chi.Router) {
 		r.With(app.authMiddleware).With(app.paginate).Get("/", app.getPostsHandler)
 		r.With(app.authMiddleware).Post("/", app.createPostHandler)
@@ -52,6 +56,43 @@ func (app *application) routes() http.Handler {
 		r.With(app.authMiddleware).With(app.paginate).Get("/me/notifications", app.getNotificationsHandler)
 		r.Post("/", app.createUserHandler)
 		r.With(app.authMiddleware).Get("/me", app.getCurrentUserProfileHandler)
+		r.With(app.authMiddleware).Get("/me/operators", app.getMyTourOperatorsHandler)
+		r.With(app.authMiddleware).Get("/me/calendar-feed", app.getCalendarFeedURLHandler)
+		r.With(app.authMiddleware).Post("/me/calendar-feed/reset", app.resetCalendarFeedURLHandler)
+		r.With(app.authMiddleware).With(app.paginate).Get("/me/imports", app.getImportsHandler)
+		r.With(app.authMiddleware).Post("/me/imports/ebird", app.createEbirdImportHandler)
+		r.With(app.authMiddleware).Get("/me/imports/{import_id}", app.getImportHandler)
+		r.With(app.authMiddleware).With(app.paginate).Get("/me/bookmarks", app.getMyBookmarksHandler)
+		r.With(app.authMiddleware).Post("/me/bookmarks", app.createBookmarkHandler)
+		r.With(app.authMiddleware).Get("/me/bookmarks/{locId}", app.getMyBookmarkHandler)
+		r.With(app.authMiddleware).Patch("/me/bookmarks/{locId}", app.updateMyBookmarkHandler)
+		r.With(app.authMiddleware).Delete("/me/bookmarks/{locId}", app.deleteMyBookmarkHandler)
+		r.With(app.authMiddleware).Get("/me/alert-settings", app.getAlertSettingsHandler)
+		r.With(app.authMiddleware).Put("/me/alert-settings", app.updateAlertSettingsHandler)
+		r.With(app.authMiddleware).Get("/me/watchlist", app.getWatchlistHandler)
+		r.With(app.authMiddleware).Put("/me/watchlist/{code}", app.addToWatchlistHandler)
+		r.With(app.authMiddleware).Delete("/me/watchlist/{code}", app.removeFromWatchlistHandler)
+		r.With(app.authMiddleware).With(app.paginate).Get("/me/alerts", app.getAlertsHandler)
+	})
+
+	mux.Route("/observations", func(r chi.Router) {
+		r.Use(app.authMiddleware)
+		r.With(app.paginate).Get("/", app.getObservationsHandler)
+		r.Post("/", app.createObservationHandler)
+		r.With(app.getObservationMiddleware).Get("/{observation_id}", app.getObservationHandler)
+		r.With(app.getObservationMiddleware).Patch("/{observation_id}", app.updateObservationHandler)
+		r.With(app.getObservationMiddleware).Delete("/{observation_id}", app.deleteObservationHandler)
+	})
+
+	mux.Route("/checklists", func(r chi.Router) {
+		r.Use(app.authMiddleware)
+		r.With(app.paginate).Get("/", app.getChecklistsHandler)
+		r.Post("/", app.createChecklistHandler)
+		r.Get("/export/ebird.csv", app.exportChecklistsHandler)
+		r.With(app.getChecklistMiddleware).Get("/{checklist_id}", app.getChecklistHandler)
+		r.With(app.getChecklistMiddleware).Patch("/{checklist_id}", app.updateChecklistHandler)
+		r.With(app.getChecklistMiddleware).Delete("/{checklist_id}", app.deleteChecklistHandler)
+		r.With(app.getChecklistMiddleware).Get("/{checklist_id}/ebird.csv", app.exportChecklistHandler)
 	})
 
 	mux.Route("/auth", func(r chi.Router) {
@@ -66,9 +107,35 @@ func (app *application) routes() http.Handler {
 	mux.Route("/tours", func(r chi.Router) {
 		r.With(app.paginate).Get("/", app.getToursHandler)
 		r.With(app.getTourMiddleware).Get("/{tour_id}", app.getTourHandler)
-		r.Post("/", app.createTourHandler)
-		r.With(app.getTourMiddleware).Put("/{tour_id}/images", app.addTourImagesHandler)
-		r.With(app.getTourMiddleware).Put("/{tour_id}/thumbnail", app.addTourThumbnailHandler)
+
+		// Tour authoring is restricted to admins and tour operators.
+		// getTourMiddleware checks that operators only touch their own tours.
+		r.Group(func(r chi.Router) {
+			r.Use(app.authMiddleware)
+			r.Use(app.operatorOnlyMiddleware)
+			r.Post("/", app.createTourHandler)
+			r.With(app.getTourMiddleware).Delete("/{tour_id}", app.deleteTourHandler)
+			r.With(app.getTourMiddleware).Put("/{tour_id}/images", app.addTourImagesHandler)
+			r.With(app.getTourMiddleware).Delete("/{tour_id}/images", app.removeTourImageHandler)
+			r.With(app.getTourMiddleware).Put("/{tour_id}/thumbnail", app.addTourThumbnailHandler)
+		})
+	})
+
+	mux.Route("/operators", func(r chi.Router) {
+		r.With(app.authMiddleware).With(app.adminOnlyMiddleware).Post("/", app.createTourOperatorHandler)
+		r.With(app.getTourOperatorMiddleware).Get("/{operator_id}", app.getTourOperatorHandler)
+		r.With(app.getTourOperatorMiddleware).With(app.paginate).Get("/{operator_id}/tours", app.getTourOperatorToursHandler)
+
+		r.Group(func(r chi.Router) {
+			r.Use(app.authMiddleware)
+			r.Use(app.getTourOperatorMiddleware)
+			r.With(app.operatorMemberMiddleware).With(app.paginate).Get("/{operator_id}/bookings", app.getTourOperatorBookingsHandler)
+			r.With(app.operatorMemberMiddleware).Get("/{operator_id}/revenue", app.getTourOperatorRevenueHandler)
+			r.With(app.operatorMemberMiddleware).Get("/{operator_id}/staff", app.getTourOperatorStaffHandler)
+			r.With(app.operatorOwnerMiddleware).Put("/{operator_id}", app.updateTourOperatorHandler)
+			r.With(app.operatorOwnerMiddleware).Post("/{operator_id}/staff", app.addTourOperatorStaffHandler)
+			r.With(app.operatorOwnerMiddleware).Delete("/{operator_id}/staff/{user_id}", app.removeTourOperatorStaffHandler)
+		})
 	})
 
 	mux.Route("/subscriptions", func(r chi.Router) {
@@ -78,23 +145,61 @@ func (app *application) routes() http.Handler {
 
 	mux.Route("/events", func(r chi.Router) {
 		r.With(app.paginate).Get("/", app.getEventsHandler)
-		r.Post("/", app.createEventHandler)
 		r.With(app.getEventMiddleware).Get("/{event_id}", app.getEventHandler)
-		r.With(app.getEventMiddleware).Delete("/{event_id}", app.deleteEventHandler)
+		r.With(app.getEventMiddleware).Get("/{event_id}/calendar.ics", app.getEventICSHandler)
+		r.With(app.authMiddleware).With(app.getEventMiddleware).Get("/{event_id}/rsvp", app.getEventRSVPHandler)
+		r.With(app.authMiddleware).With(app.getEventMiddleware).Put("/{event_id}/rsvp", app.rsvpEventHandler)
+		r.With(app.authMiddleware).With(app.getEventMiddleware).Delete("/{event_id}/rsvp", app.deleteEventRSVPHandler)
+
+		// Event authoring is restricted to admins.
+		r.Group(func(r chi.Router) {
+			r.Use(app.authMiddleware)
+			r.Use(app.adminOnlyMiddleware)
+			r.Post("/", app.createEventHandler)
+			r.With(app.getEventMiddleware).Put("/{event_id}", app.updateEventHandler)
+			r.With(app.getEventMiddleware).Delete("/{event_id}", app.deleteEventHandler)
+			r.With(app.getEventMiddleware).Put("/{event_id}/cover-photo", app.addEventCoverPhotoHandler)
+		})
 	})
 
+	// Subscribed calendar feeds are fetched by calendar apps, so the secret
+	// token in the URL stands in for authentication.
+	mux.Get("/calendar/feeds/{token}.ics", app.getCalendarFeedHandler)
+
 	mux.Route("/hotspots", func(r chi.Router) {
-		r.With(app.authMiddleware).Get("/{locId}/visiting-times", app.getHotspotVisitingTimesHandler)
+		r.Use(app.authMiddleware)
+		r.Get("/nearby", app.getNearbyHotspotsHandler)
+		r.With(app.paginate).Get("/trending", app.getTrendingHotspotsHandler)
+		r.Get("/visiting-times", app.getHotspotsVisitingTimesHandler)
+		r.Get("/{locId}", app.getHotspotHandler)
+		r.Get("/{locId}/recent", app.getHotspotRecentHandler)
+		r.Get("/{locId}/notable", app.getHotspotNotableHandler)
+		r.Get("/{locId}/visiting-times", app.getHotspotVisitingTimesHandler)
 	})
 
 	mux.Route("/species", func(r chi.Router) {
 		r.With(app.authMiddleware).Get("/range", app.getSpeciesRangeHandler)
+		r.With(app.paginate).Get("/", app.getSpeciesListHandler)
+		r.Get("/search", app.searchSpeciesHandler)
+		r.With(app.paginate).Get("/at", app.getSpeciesAtPointHandler)
+		r.With(app.paginate).Get("/in-bbox", app.getSpeciesInBBoxHandler)
+		r.Get("/{code}", app.getSpeciesHandler)
+		r.With(app.authMiddleware).Get("/{code}/profile", app.getSpeciesProfileHandler)
+		// Tiles are public so that browsers and CDNs can cache them.
+		r.Get("/{sci_name}/tiles/{z}/{x}/{y}.mvt", app.getSpeciesRangeTileHandler)
 	})
 
 	mux.Route("/ai", func(r chi.Router) {
 		r.Use(app.authMiddleware)
-		r.Post("/identify-bird", app.identifyBirdHandler)
-		r.Post("/ask-question", app.askAiQuestionHandler)
+		r.With(app.aiQuota(store.AIFeatureIdentify)).Post("/identify-bird", app.identifyBirdHandler)
+		r.With(app.aiQuota(store.AIFeatureQuestion)).Post("/ask-question", app.askAiQuestionHandler)
+		r.Get("/quota", app.getAIQuotaHandler)
+		r.With(app.paginate).Get("/chats", app.getChatsHandler)
+		r.Post("/chats", app.createChatHandler)
+		r.Get("/chats/{id}", app.getChatHandler)
+		r.With(app.aiQuota(store.AIFeatureQuestion)).Post("/chats/{id}/messages", app.createChatMessageHandler)
+		r.With(app.paginate).Get("/identifications", app.getIdentificationsHandler)
+		r.Post("/identifications/{id}/feedback", app.createIdentificationFeedbackHandler)
 	})
 
 	mux.With(app.authMiddleware).Post("/payos/create-payment-link", app.createPayOSPaymentLinkHandler)
@@ -107,6 +212,12 @@ func (app *application) routes() http.Handler {
 		r.Use(app.adminOnlyMiddleware)
 		// Logic: Define the admin-triggered newsletter endpoint.
 		r.Post("/admin/services/send-newsletter", app.handleSendNewsletter)
+		r.With(app.paginate).Get("/admin/sensitive-species", app.getSensitiveSpeciesHandler)
+		r.Put("/admin/sensitive-species/{code}", app.addSensitiveSpeciesHandler)
+		r.Delete("/admin/sensitive-species/{code}", app.removeSensitiveSpeciesHandler)
+		r.With(app.paginate).Get("/admin/ai/accuracy", app.getIdentificationAccuracyHandler)
+		r.With(app.paginate).Get("/admin/ai/labelled-identifications", app.getLabelledIdentificationsHandler)
+		r.With(app.paginate).Get("/admin/ai/usage", app.getAIUsageReportHandler)
 	})
 
 	return mux
@@ -2,11 +2,18 @@
 package main
 
 import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/sixync/birdlens-be/internal/response"
+	"github.com/sixync/birdlens-be/internal/store"
 )
 
 func (app *application) getSpeciesRangeHandler(w http.ResponseWriter, r *http.Request) {
@@ -56,4 +63,267 @@ func (app *application) getSpeciesRangeHandler(w http.ResponseWriter, r *http.Re
 
 	log.Printf("[HANDLER] Success: Returning %d polygons for scientific name: '%s'", len(speciesRanges), scientificName)
 	response.JSON(w, http.StatusOK, speciesRanges, false, "Species range data retrieved successfully")
-}
\ No newline at end of file
+}
+
+const (
+	defaultSpeciesSearchLimit = 10
+	maxSpeciesSearchLimit     = 50
+)
+
+// getSpeciesListHandler pages through the taxonomy in taxonomic order,
+// optionally filtered by category and family.
+func (app *application) getSpeciesListHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := store.SpeciesFilter{
+		Category: query.Get("category"),
+		Family:   query.Get("family"),
+	}
+
+	limit, offset := getPaginateFromCtx(r)
+	species, err := app.store.Species.List(r.Context(), filter, limit, offset)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, species, false, "species retrieved successfully")
+}
+
+func (app *application) getSpeciesHandler(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+
+	species, err := app.store.Species.GetByCode(r.Context(), code)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFound(w, r)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, species, false, "species retrieved successfully")
+}
+
+// searchSpeciesHandler is the species autocomplete. q matches common,
+// scientific and localized names regardless of accents; locale (e.g. vi)
+// restricts localized matches to that language.
+func (app *application) searchSpeciesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		app.badRequest(w, r, errors.New("q is required"))
+		return
+	}
+
+	limit := defaultSpeciesSearchLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxSpeciesSearchLimit {
+			app.badRequest(w, r, fmt.Errorf("limit must be between 1 and %d", maxSpeciesSearchLimit))
+			return
+		}
+	}
+
+	results, err := app.store.Species.Search(r.Context(), q, strings.ToLower(query.Get("locale")), limit)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, results, false, "species search completed successfully")
+}
+
+// maxRangeBBoxDegrees bounds each side of a bbox query parameter; larger
+// boxes touch so many ranges or sightings that they belong to map tiles instead.
+const maxRangeBBoxDegrees = 20
+
+// getSpeciesAtPointHandler lists the species expected at lat/lng, i.e. whose
+// range polygons contain the point.
+func (app *application) getSpeciesAtPointHandler(w http.ResponseWriter, r *http.Request) {
+	lat, lng, err := parseLatLng(r.URL.Query().Get("lat"), r.URL.Query().Get("lng"))
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	limit, offset := getPaginateFromCtx(r)
+	species, err := app.store.Species.GetRangeSpeciesAtPoint(r.Context(), lat, lng, limit, offset)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, species, false, "species retrieved successfully")
+}
+
+// getSpeciesInBBoxHandler lists the species whose ranges intersect
+// bbox=minLng,minLat,maxLng,maxLat.
+func (app *application) getSpeciesInBBoxHandler(w http.ResponseWriter, r *http.Request) {
+	bbox, err := parseBBox(r.URL.Query().Get("bbox"))
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	if bbox.MaxLng-bbox.MinLng > maxRangeBBoxDegrees || bbox.MaxLat-bbox.MinLat > maxRangeBBoxDegrees {
+		app.badRequest(w, r, fmt.Errorf("bbox sides must be at most %d degrees", maxRangeBBoxDegrees))
+		return
+	}
+
+	limit, offset := getPaginateFromCtx(r)
+	species, err := app.store.Species.GetRangeSpeciesInBBox(r.Context(), bbox, limit, offset)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, species, false, "species retrieved successfully")
+}
+
+func parseLatLng(latStr, lngStr string) (lat, lng float64, err error) {
+	if latStr == "" || lngStr == "" {
+		return 0, 0, errors.New("lat and lng are required")
+	}
+	lat, err = strconv.ParseFloat(latStr, 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, errors.New("lat must be between -90 and 90")
+	}
+	lng, err = strconv.ParseFloat(lngStr, 64)
+	if err != nil || lng < -180 || lng > 180 {
+		return 0, 0, errors.New("lng must be between -180 and 180")
+	}
+	return lat, lng, nil
+}
+
+// parseBBox parses "minLng,minLat,maxLng,maxLat" in degrees.
+func parseBBox(s string) (store.BoundingBox, error) {
+	var bbox store.BoundingBox
+	parts := strings.Split(s, ",")
+	if s == "" || len(parts) != 4 {
+		return bbox, errors.New("bbox must be minLng,minLat,maxLng,maxLat")
+	}
+
+	values := make([]float64, 4)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return bbox, fmt.Errorf("invalid bbox value %q", part)
+		}
+		values[i] = v
+	}
+	bbox = store.BoundingBox{MinLng: values[0], MinLat: values[1], MaxLng: values[2], MaxLat: values[3]}
+
+	if bbox.MinLng < -180 || bbox.MaxLng > 180 || bbox.MinLat < -90 || bbox.MaxLat > 90 {
+		return bbox, errors.New("bbox is outside -180,-90,180,90")
+	}
+	if bbox.MinLng >= bbox.MaxLng || bbox.MinLat >= bbox.MaxLat {
+		return bbox, errors.New("bbox min values must be less than max values")
+	}
+	return bbox, nil
+}
+
+const (
+	maxRangeTileZoom = 22
+	// rangeTileCacheControl lets browsers and CDNs keep tiles for a day; range
+	// data only changes when the range table is reloaded.
+	rangeTileCacheControl = "public, max-age=86400, stale-while-revalidate=604800"
+)
+
+// getSpeciesRangeTileHandler serves a species' range as Mapbox Vector Tiles at
+// /species/{sci_name}/tiles/{z}/{x}/{y}.mvt. Spaces in the scientific name may
+// be URL-encoded or written as underscores. Tiles carry an ETag, so revalidation
+// after max-age answers 304 without resending the tile.
+func (app *application) getSpeciesRangeTileHandler(w http.ResponseWriter, r *http.Request) {
+	scientificName := strings.TrimSpace(strings.ReplaceAll(r.PathValue("sci_name"), "_", " "))
+	if scientificName == "" {
+		app.badRequest(w, r, errors.New("scientific name is required"))
+		return
+	}
+
+	z, err := strconv.Atoi(r.PathValue("z"))
+	if err != nil || z < 0 || z > maxRangeTileZoom {
+		app.badRequest(w, r, fmt.Errorf("z must be between 0 and %d", maxRangeTileZoom))
+		return
+	}
+	tiles := 1 << z
+	x, err := strconv.Atoi(r.PathValue("x"))
+	if err != nil || x < 0 || x >= tiles {
+		app.badRequest(w, r, fmt.Errorf("x must be between 0 and %d at zoom %d", tiles-1, z))
+		return
+	}
+	y, err := strconv.Atoi(r.PathValue("y"))
+	if err != nil || y < 0 || y >= tiles {
+		app.badRequest(w, r, fmt.Errorf("y must be between 0 and %d at zoom %d", tiles-1, z))
+		return
+	}
+
+	tile, err := app.store.Species.GetRangeTile(r.Context(), scientificName, z, x, y)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	sum := sha256.Sum256(tile)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("Cache-Control", rangeTileCacheControl)
+	w.Header().Set("ETag", etag)
+
+	if match := r.Header.Get("If-None-Match"); match != "" && (match == etag || match == "*") {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	// Map clients treat 204 as an empty tile rather than an error.
+	if len(tile) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.mapbox-vector-tile")
+	w.Header().Set("Content-Length", strconv.Itoa(len(tile)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(tile)
+}
+
+// getSensitiveSpeciesHandler lists the species whose locations are obfuscated.
+func (app *application) getSensitiveSpeciesHandler(w http.ResponseWriter, r *http.Request) {
+	limit, offset := getPaginateFromCtx(r)
+	species, err := app.store.Species.List(r.Context(), store.SpeciesFilter{SensitiveOnly: true}, limit, offset)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, species, false, "sensitive species retrieved successfully")
+}
+
+func (app *application) addSensitiveSpeciesHandler(w http.ResponseWriter, r *http.Request) {
+	app.setSpeciesSensitive(w, r, true)
+}
+
+func (app *application) removeSensitiveSpeciesHandler(w http.ResponseWriter, r *http.Request) {
+	app.setSpeciesSensitive(w, r, false)
+}
+
+func (app *application) setSpeciesSensitive(w http.ResponseWriter, r *http.Request, sensitive bool) {
+	code := r.PathValue("code")
+
+	err := app.store.Species.SetSpeciesSensitive(r.Context(), code, sensitive)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFound(w, r)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	species, err := app.store.Species.GetByCode(r.Context(), code)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, species, false, "sensitive species updated successfully")
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sixync/birdlens-be/internal/ai"
+	"github.com/sixync/birdlens-be/internal/response"
+	"github.com/sixync/birdlens-be/internal/store"
+)
+
+const (
+	defaultProfileLanguage = "en"
+
+	// speciesProfileTimeout bounds generating a profile, so that a hung
+	// model or Wikipedia call cannot hold its species and language for
+	// good.
+	speciesProfileTimeout = time.Minute
+)
+
+// SpeciesProfileResponse is a species with its profile.
+type SpeciesProfileResponse struct {
+	*store.SpeciesProfile
+	CommonName     string `json:"common_name"`
+	ScientificName string `json:"scientific_name"`
+	LocalizedName  string `json:"localized_name,omitempty"`
+}
+
+// getSpeciesProfileHandler returns the description and picture of a species
+// in lang (en by default). Profiles are shared by all users, so only the
+// request that generates a profile reaches the model and counts against the
+// caller's question quota; stored profiles are served whatever the quota.
+func (app *application) getSpeciesProfileHandler(w http.ResponseWriter, r *http.Request) {
+	language, err := profileLanguage(r.URL.Query().Get("lang"))
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	species, err := app.store.Species.GetByCode(r.Context(), r.PathValue("code"))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFound(w, r)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	profile, ok := app.speciesProfile(w, r, species, language)
+	if !ok {
+		return
+	}
+
+	resp := SpeciesProfileResponse{
+		SpeciesProfile: profile,
+		CommonName:     species.CommonName,
+		ScientificName: species.ScientificName,
+		LocalizedName:  species.LocalizedNames[language],
+	}
+	response.JSON(w, http.StatusOK, resp, false, "Species profile retrieved successfully")
+}
+
+// profileLanguage validates a requested profile language, defaulting to
+// English.
+func profileLanguage(language string) (string, error) {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language == "" {
+		return defaultProfileLanguage, nil
+	}
+	if _, ok := ai.Languages[language]; !ok {
+		supported := make([]string, 0, len(ai.Languages))
+		for code := range ai.Languages {
+			supported = append(supported, code)
+		}
+		slices.Sort(supported)
+		return "", fmt.Errorf("lang must be one of %s", strings.Join(supported, ", "))
+	}
+	return language, nil
+}
+
+// speciesProfile returns the stored profile of species, generating it when
+// there is none, and answers the error itself when it fails. A profile older
+// than the configured TTL is still returned while a fresh one is generated
+// in the background. Generating is metered to the caller whose request runs
+// it; outside aiQuota the call is reserved against the caller's question
+// quota first.
+func (app *application) speciesProfile(w http.ResponseWriter, r *http.Request, species *store.Species, language string) (*store.SpeciesProfile, bool) {
+	profile, err := app.store.SpeciesProfiles.Get(r.Context(), species.SpeciesCode, language)
+	switch {
+	case err == nil:
+		if time.Since(profile.GeneratedAt) > time.Duration(app.config.ai.profileTTLDays)*24*time.Hour {
+			app.refreshSpeciesProfile(r, species, language)
+		}
+		return profile, true
+	case !errors.Is(err, sql.ErrNoRows):
+		app.serverError(w, r, err)
+		return nil, false
+	}
+
+	if !metered(r) {
+		user := app.getUserFromFirebaseClaimsCtx(r)
+		if user == nil {
+			app.unauthorized(w, r)
+			return nil, false
+		}
+		var settle func()
+		var admitted bool
+		r, settle, admitted = app.reserveAI(w, r, user, store.AIFeatureQuestion)
+		if !admitted {
+			return nil, false
+		}
+		defer settle()
+	}
+
+	// The generation outlives a caller that goes away, so that requests
+	// sharing it still get the profile and it is stored. Only the caller
+	// running it is charged; the others are given their call back.
+	ctx := context.WithoutCancel(r.Context())
+	v, err, _ := app.profiles.Do(profileKey(species, language), func() (any, error) {
+		profile, usage, err := app.generateSpeciesProfile(ctx, species, language)
+		app.meterAI(r, usage)
+		return profile, err
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return nil, false
+	}
+	return v.(*store.SpeciesProfile), true
+}
+
+// refreshSpeciesProfile regenerates a stale profile in the background. The
+// refresh is charged as a question to the caller whose request runs it, and
+// skipped when that caller's quota is used up; requests that find it in
+// flight are not charged.
+func (app *application) refreshSpeciesProfile(r *http.Request, species *store.Species, language string) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		return
+	}
+	app.backgroundTask(r, func() error {
+		_, err, _ := app.profiles.Do(profileKey(species, language), func() (any, error) {
+			ctx := context.Background()
+			_, limits, err := app.aiLimits(ctx, user)
+			if err != nil {
+				return nil, err
+			}
+			day, _ := aiDay(time.Now())
+			_, admitted, err := app.store.AIUsage.Reserve(ctx, user.Id, day, store.AIFeatureQuestion, limits)
+			if err != nil || !admitted {
+				return nil, err
+			}
+
+			profile, usage, err := app.generateSpeciesProfile(ctx, species, language)
+			if usage != (ai.Usage{}) {
+				if err := app.store.AIUsage.AddTokens(ctx, user.Id, day, usage.InputTokens, usage.OutputTokens); err != nil {
+					slog.Error("Failed to record AI usage", "user_id", user.Id, "error", err)
+				}
+			}
+			return profile, err
+		})
+		return err
+	})
+}
+
+func profileKey(species *store.Species, language string) string {
+	return species.SpeciesCode + "/" + language
+}
+
+// generateSpeciesProfile has the assistant describe species, looks up its
+// picture and stores the profile. Failing to store it is only logged, as
+// the profile can still be served.
+func (app *application) generateSpeciesProfile(ctx context.Context, species *store.Species, language string) (*store.SpeciesProfile, ai.Usage, error) {
+	ctx, cancel := context.WithTimeout(ctx, speciesProfileTimeout)
+	defer cancel()
+
+	description, err := app.ai.Describe(ctx, species.CommonName, language)
+	if err != nil {
+		return nil, ai.Usage{}, err
+	}
+
+	profile := &store.SpeciesProfile{
+		SpeciesCode: species.SpeciesCode,
+		Language:    language,
+		Description: description.Text,
+		Model:       description.Model,
+		GeneratedAt: time.Now(),
+	}
+	if imageURL := app.speciesImageURL(ctx, species); imageURL != "" {
+		profile.ImageURL = &imageURL
+	}
+
+	if err := app.store.SpeciesProfiles.Upsert(ctx, profile); err != nil {
+		slog.Error("Failed to store species profile", "species_code", species.SpeciesCode, "language", language, "error", err)
+	}
+	return profile, description.Usage, nil
+}
+
+// speciesImageURL looks the species up on Wikipedia by scientific name,
+// which is less ambiguous, then by common name. It returns "" when neither
+// has a picture.
+func (app *application) speciesImageURL(ctx context.Context, species *store.Species) string {
+	for _, title := range []string{species.ScientificName, species.CommonName} {
+		if title == "" {
+			continue
+		}
+		imageURL, err := app.getWikipediaImageURL(ctx, title)
+		if err == nil {
+			return imageURL
+		}
+		slog.Warn("Could not fetch Wikipedia image", "title", title, "error", err)
+	}
+	return ""
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sixync/birdlens-be/internal/ai"
+	"github.com/sixync/birdlens-be/internal/jwt"
+	"github.com/sixync/birdlens-be/internal/store"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// newProfileApplication returns an application generating profiles with
+// the fake provider for user "uid-1" on the free tier, allowing one question
+// a day. Wikipedia is unreachable, so profiles have no picture.
+func newProfileApplication(t *testing.T) (*application, *ai.Fake, *fakeSpeciesProfiles, *fakeAIUsage) {
+	t.Helper()
+	app := newTestApplication(t)
+	app.config.ai.profileTTLDays = 30
+	app.config.ai.freeLimits = store.AILimits{DailyQuestions: 1, DailyTokens: 1000}
+
+	service, err := ai.Open(context.Background(), "fake", "fake", ai.Config{})
+	if err != nil {
+		t.Fatalf("ai.Open() error = %v", err)
+	}
+	t.Cleanup(func() { service.Close() })
+	app.ai = service
+
+	client := wikipediaClient
+	wikipediaClient = &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("offline")
+	})}
+	t.Cleanup(func() { wikipediaClient = client })
+
+	profiles := &fakeSpeciesProfiles{profiles: map[string]*store.SpeciesProfile{}}
+	usage := &fakeAIUsage{}
+	app.store = &store.Storage{
+		Users: &fakeUsers{users: map[string]*store.User{"uid-1": {Id: 1, Email: "birder@example.com"}}},
+		Species: &fakeSpecies{species: map[string]*store.Species{
+			"blujay": {SpeciesCode: "blujay", CommonName: "Blue Jay", ScientificName: "Cyanocitta cristata"},
+		}},
+		SpeciesProfiles: profiles,
+		AIUsage:         usage,
+		Subscriptions:   &fakeSubscriptions{},
+	}
+	return app, service.Assistant.(*ai.Fake), profiles, usage
+}
+
+func getSpeciesProfile(app *application, code string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/species/"+code+"/profile", nil)
+	req.SetPathValue("code", code)
+	req = req.WithContext(context.WithValue(req.Context(), UserClaimsKey, &jwt.FirebaseClaims{Uid: "uid-1"}))
+	rec := httptest.NewRecorder()
+	app.getSpeciesProfileHandler(rec, req)
+	return rec
+}
+
+func TestGetSpeciesProfileHandler(t *testing.T) {
+	t.Run("stored", func(t *testing.T) {
+		app, fake, profiles, usage := newProfileApplication(t)
+		usage.usage.QuestionCalls = 1
+		profiles.profiles["blujay/en"] = &store.SpeciesProfile{SpeciesCode: "blujay", Language: "en", Description: "A loud blue corvid.", GeneratedAt: time.Now()}
+
+		rec := getSpeciesProfile(app, "blujay")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200 with the quota used up: %s", rec.Code, rec.Body)
+		}
+		var resp SpeciesProfileResponse
+		decodeResponse(t, rec, &resp)
+		if resp.Description != "A loud blue corvid." || resp.CommonName != "Blue Jay" {
+			t.Errorf("response = %+v, want the stored profile", resp)
+		}
+		if calls := fake.Calls(); len(calls) != 0 {
+			t.Errorf("calls = %q, want none", calls)
+		}
+		if rec.Header().Get("X-AI-Quota-Limit") != "" || usage.usage.QuestionCalls != 1 {
+			t.Errorf("usage = %+v, want a stored profile not to be metered", usage.usage)
+		}
+	})
+
+	t.Run("generated", func(t *testing.T) {
+		app, fake, profiles, usage := newProfileApplication(t)
+
+		rec := getSpeciesProfile(app, "blujay")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+		}
+		var resp SpeciesProfileResponse
+		decodeResponse(t, rec, &resp)
+		if resp.Description != "The Blue Jay is a bird." || resp.Model != ai.FakeModel {
+			t.Errorf("response = %+v, want the profile from the fake", resp)
+		}
+		if calls := fake.Calls(); !slices.Equal(calls, []string{"Describe(Blue Jay, en)"}) {
+			t.Errorf("calls = %q, want one description", calls)
+		}
+		if _, ok := profiles.profiles["blujay/en"]; !ok {
+			t.Error("the generated profile was not stored")
+		}
+		if usage.usage.QuestionCalls != 1 || usage.usage.Tokens() == 0 || usage.released != 0 {
+			t.Errorf("usage = %+v, released %d, want one question and its tokens", usage.usage, usage.released)
+		}
+		if got := rec.Header().Get("X-AI-Quota-Remaining"); got != "0" {
+			t.Errorf("X-AI-Quota-Remaining = %q, want 0", got)
+		}
+	})
+
+	t.Run("quota used up", func(t *testing.T) {
+		app, fake, _, usage := newProfileApplication(t)
+		usage.usage.QuestionCalls = 1
+
+		rec := getSpeciesProfile(app, "blujay")
+		if rec.Code != http.StatusTooManyRequests {
+			t.Errorf("status = %d, want 429: %s", rec.Code, rec.Body)
+		}
+		if calls := fake.Calls(); len(calls) != 0 {
+			t.Errorf("calls = %q, want none", calls)
+		}
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		app, fake, profiles, usage := newProfileApplication(t)
+		stale := &store.SpeciesProfile{SpeciesCode: "blujay", Language: "en", Description: "A loud blue corvid.", GeneratedAt: time.Now().AddDate(0, 0, -31)}
+		profiles.profiles["blujay/en"] = stale
+
+		rec := getSpeciesProfile(app, "blujay")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+		}
+		var resp SpeciesProfileResponse
+		decodeResponse(t, rec, &resp)
+		if resp.Description != "A loud blue corvid." {
+			t.Errorf("response = %+v, want the stale profile while it is refreshed", resp)
+		}
+
+		app.wg.Wait()
+		if calls := fake.Calls(); !slices.Equal(calls, []string{"Describe(Blue Jay, en)"}) {
+			t.Errorf("calls = %q, want the refresh", calls)
+		}
+		if profiles.profiles["blujay/en"] == stale {
+			t.Error("the stale profile was not replaced")
+		}
+		if usage.usage.QuestionCalls != 1 || usage.usage.Tokens() == 0 {
+			t.Errorf("usage = %+v, want the refresh charged as one question", usage.usage)
+		}
+
+		// A caller with no quota left gets the stale profile, unrefreshed.
+		app, fake, profiles, usage = newProfileApplication(t)
+		usage.usage.QuestionCalls = 1
+		profiles.profiles["blujay/en"] = stale
+		if rec := getSpeciesProfile(app, "blujay"); rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+		}
+		app.wg.Wait()
+		if calls := fake.Calls(); len(calls) != 0 {
+			t.Errorf("calls = %q, want no refresh", calls)
+		}
+	})
+}
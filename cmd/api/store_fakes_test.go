@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sixync/birdlens-be/internal/store"
+)
+
+// The store interfaces below are those of store.Storage. Fakes embed them
+// and implement only what a test reaches; anything else panics.
+
+type usersStore interface {
+	Create(context.Context, *store.User) error
+	GetById(ctx context.Context, userId int64) (*store.User, error)
+	Update(ctx context.Context, user *store.User) error
+	Delete(ctx context.Context, userId int64) error
+	GetByEmail(ctx context.Context, email string) (*store.User, error)
+	GetByUsername(ctx context.Context, username string) (*store.User, error)
+	UsernameExists(ctx context.Context, username string) (bool, error)
+	EmailExists(ctx context.Context, username string) (bool, error)
+	GetByFirebaseUID(ctx context.Context, firebaseUID string) (*store.User, error)
+	AddEmailVerificationToken(ctx context.Context, userId int64, token string, expiresAt time.Time) error
+	GetEmailVerificationToken(ctx context.Context, userId int64) (token string, expiresAt time.Time, err error)
+	VerifyUserEmail(ctx context.Context, userId int64) error
+	GetSubscriptionByName(ctx context.Context, name string) (*store.Subscription, error)
+	AddResetPasswordToken(ctx context.Context, email string, token string, expiresAt time.Time) error
+	GetUserByResetPasswordToken(ctx context.Context, token string) (*store.User, error)
+	GrantSubscriptionForOrder(ctx context.Context, userID int64, subscriptionID int64) error
+	GetUserLifeList(ctx context.Context, userID, viewerID int64, filter store.LifeListFilter) ([]*store.LifeListEntry, error)
+	GetUserLifeListCountries(ctx context.Context, userID, viewerID int64, filter store.LifeListFilter) ([]store.CountrySpeciesCount, error)
+	GetSeenSpeciesCodes(ctx context.Context, userID int64, codes []string) (map[string]bool, error)
+	GetAllUserEmails(ctx context.Context) ([]string, error)
+}
+
+type speciesStore interface {
+	GetRangeByScientificName(ctx context.Context, scientificName string) ([]store.RangeData, error)
+	GetRangeSpeciesAtPoint(ctx context.Context, lat, lng float64, limit, offset int) (*store.PaginatedList[*store.RangeSpecies], error)
+	GetRangeSpeciesInBBox(ctx context.Context, bbox store.BoundingBox, limit, offset int) (*store.PaginatedList[*store.RangeSpecies], error)
+	GetRangeTile(ctx context.Context, scientificName string, z, x, y int) ([]byte, error)
+	GetByCode(ctx context.Context, code string) (*store.Species, error)
+	GetByCodes(ctx context.Context, codes []string) (map[string]*store.Species, error)
+	Exists(ctx context.Context, code string) (bool, error)
+	FindCodeByName(ctx context.Context, scientificName, commonName string) (string, error)
+	List(ctx context.Context, filter store.SpeciesFilter, limit, offset int) (*store.PaginatedList[*store.Species], error)
+	Search(ctx context.Context, q, locale string, limit int) ([]*store.SpeciesSearchResult, error)
+	UpsertTaxonomy(ctx context.Context, species []*store.Species) error
+	UpsertLocalizedNames(ctx context.Context, locale string, names map[string]string) (int, error)
+	SetSensitive(ctx context.Context, codes []string) (int, error)
+	SetSpeciesSensitive(ctx context.Context, code string, sensitive bool) error
+	GetSensitiveCodes(ctx context.Context) (map[string]bool, error)
+}
+
+type identificationsStore interface {
+	Create(ctx context.Context, identification *store.Identification) error
+	SetImageURL(ctx context.Context, id int64, imageURL string) error
+	GetByID(ctx context.Context, userID, id int64) (*store.Identification, error)
+	GetByUserID(ctx context.Context, userID int64, limit, offset int) (*store.PaginatedList[*store.Identification], error)
+	SaveFeedback(ctx context.Context, identification *store.Identification) error
+	GetAccuracy(ctx context.Context, model string) (*store.IdentificationAccuracy, error)
+	GetAccuracyBySpecies(ctx context.Context, model string, limit, offset int) (*store.PaginatedList[*store.SpeciesIdentificationAccuracy], error)
+	GetLabelled(ctx context.Context, limit, offset int) (*store.PaginatedList[*store.LabelledIdentification], error)
+}
+
+type speciesProfilesStore interface {
+	Get(ctx context.Context, speciesCode, language string) (*store.SpeciesProfile, error)
+	Upsert(ctx context.Context, profile *store.SpeciesProfile) error
+}
+
+type bookmarksStore interface {
+	Create(ctx context.Context, bookmark *store.Bookmark) error
+	Get(ctx context.Context, userID int64, hotspotLocationID string) (*store.Bookmark, error)
+	UpdateNote(ctx context.Context, userID int64, hotspotLocationID string, note *string) (*store.Bookmark, error)
+	Delete(ctx context.Context, userID int64, hotspotLocationID string) error
+	GetByUserID(ctx context.Context, userID int64, limit, offset int) (*store.PaginatedList[*store.Bookmark], error)
+	GetTrendingBookmarks(ctx context.Context, window time.Duration, limit, offset int) (*store.PaginatedList[*store.TrendingHotspot], error)
+	CountByHotspot(ctx context.Context, hotspotLocationID string) (int, error)
+	Exists(ctx context.Context, userID int64, hotspotLocationID string) (bool, error)
+}
+
+type aiUsageStore interface {
+	Reserve(ctx context.Context, userID int64, day time.Time, feature string, limits store.AILimits) (*store.AIUsage, bool, error)
+	Release(ctx context.Context, userID int64, day time.Time, feature string) error
+	AddTokens(ctx context.Context, userID int64, day time.Time, inputTokens, outputTokens int) error
+	Get(ctx context.Context, userID int64, day time.Time) (*store.AIUsage, error)
+	GetDailyTotals(ctx context.Context, from, to time.Time) ([]*store.AIUsage, error)
+	GetUserTotals(ctx context.Context, from, to time.Time, limit, offset int) (*store.PaginatedList[*store.UserAIUsage], error)
+}
+
+type subscriptionsStore interface {
+	GetUserSubscriptionByEmail(ctx context.Context, email string) (*store.Subscription, error)
+	GetAll(ctx context.Context) ([]*store.Subscription, error)
+	Create(ctx context.Context, subscription *store.Subscription) error
+}
+
+type fakeUsers struct {
+	usersStore
+	users map[string]*store.User // by Firebase UID
+	seen  map[string]bool
+}
+
+func (f *fakeUsers) GetByFirebaseUID(ctx context.Context, firebaseUID string) (*store.User, error) {
+	if user, ok := f.users[firebaseUID]; ok {
+		return user, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeUsers) GetSeenSpeciesCodes(ctx context.Context, userID int64, codes []string) (map[string]bool, error) {
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if f.seen[code] {
+			seen[code] = true
+		}
+	}
+	return seen, nil
+}
+
+type fakeSpecies struct {
+	speciesStore
+	species   map[string]*store.Species // by species code
+	sensitive map[string]bool
+}
+
+func (f *fakeSpecies) GetByCode(ctx context.Context, code string) (*store.Species, error) {
+	if sp, ok := f.species[code]; ok {
+		return sp, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeSpecies) FindCodeByName(ctx context.Context, scientificName, commonName string) (string, error) {
+	for code, sp := range f.species {
+		if (scientificName != "" && strings.EqualFold(sp.ScientificName, scientificName)) || strings.EqualFold(sp.CommonName, commonName) {
+			return code, nil
+		}
+	}
+	return "", sql.ErrNoRows
+}
+
+func (f *fakeSpecies) GetByCodes(ctx context.Context, codes []string) (map[string]*store.Species, error) {
+	species := map[string]*store.Species{}
+	for _, code := range codes {
+		if sp, ok := f.species[code]; ok {
+			species[code] = sp
+		}
+	}
+	return species, nil
+}
+
+func (f *fakeSpecies) GetSensitiveCodes(ctx context.Context) (map[string]bool, error) {
+	return f.sensitive, nil
+}
+
+type fakeIdentifications struct {
+	identificationsStore
+	mu      sync.Mutex
+	created []*store.Identification
+}
+
+func (f *fakeIdentifications) Create(ctx context.Context, identification *store.Identification) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.created = append(f.created, identification)
+	identification.ID = int64(len(f.created))
+	identification.CreatedAt = time.Now()
+	return nil
+}
+
+type fakeSpeciesProfiles struct {
+	speciesProfilesStore
+	mu       sync.Mutex
+	profiles map[string]*store.SpeciesProfile // by species code and language
+}
+
+func (f *fakeSpeciesProfiles) Get(ctx context.Context, speciesCode, language string) (*store.SpeciesProfile, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if profile, ok := f.profiles[speciesCode+"/"+language]; ok {
+		return profile, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeSpeciesProfiles) Upsert(ctx context.Context, profile *store.SpeciesProfile) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	profile.GeneratedAt = time.Now()
+	f.profiles[profile.SpeciesCode+"/"+profile.Language] = profile
+	return nil
+}
+
+type fakeBookmarks struct {
+	bookmarksStore
+	mu        sync.Mutex
+	bookmarks map[string]*store.Bookmark // by user id and hotspot
+	// staleExists makes Exists miss every bookmark, as it does for a
+	// request racing another one that bookmarks the same hotspot.
+	staleExists bool
+}
+
+func bookmarkKey(userID int64, hotspotLocationID string) string {
+	return fmt.Sprintf("%d/%s", userID, hotspotLocationID)
+}
+
+func (f *fakeBookmarks) Exists(ctx context.Context, userID int64, hotspotLocationID string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.bookmarks[bookmarkKey(userID, hotspotLocationID)]
+	return ok && !f.staleExists, nil
+}
+
+func (f *fakeBookmarks) Create(ctx context.Context, bookmark *store.Bookmark) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := bookmarkKey(bookmark.UserID, bookmark.HotspotLocationId)
+	if _, ok := f.bookmarks[key]; ok {
+		return store.ErrBookmarkExists
+	}
+	bookmark.ID = int64(len(f.bookmarks) + 1)
+	bookmark.CreatedAt = time.Now()
+	f.bookmarks[key] = bookmark
+	return nil
+}
+
+// fakeAIUsage keeps one user's usage of a single day.
+type fakeAIUsage struct {
+	aiUsageStore
+	mu       sync.Mutex
+	usage    store.AIUsage
+	released int
+}
+
+func (f *fakeAIUsage) Reserve(ctx context.Context, userID int64, day time.Time, feature string, limits store.AILimits) (*store.AIUsage, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.usage.Calls(feature) >= limits.Calls(feature) || f.usage.Tokens() >= int64(limits.DailyTokens) {
+		usage := f.usage
+		return &usage, false, nil
+	}
+	if feature == store.AIFeatureIdentify {
+		f.usage.IdentifyCalls++
+	} else {
+		f.usage.QuestionCalls++
+	}
+	usage := f.usage
+	return &usage, true, nil
+}
+
+func (f *fakeAIUsage) Release(ctx context.Context, userID int64, day time.Time, feature string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if feature == store.AIFeatureIdentify {
+		f.usage.IdentifyCalls--
+	} else {
+		f.usage.QuestionCalls--
+	}
+	f.released++
+	return nil
+}
+
+func (f *fakeAIUsage) AddTokens(ctx context.Context, userID int64, day time.Time, inputTokens, outputTokens int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.usage.InputTokens += int64(inputTokens)
+	f.usage.OutputTokens += int64(outputTokens)
+	return nil
+}
+
+// fakeSubscriptions has no subscribers, so everyone is on the free tier.
+type fakeSubscriptions struct {
+	subscriptionsStore
+}
+
+func (f *fakeSubscriptions) GetUserSubscriptionByEmail(ctx context.Context, email string) (*store.Subscription, error) {
+	return nil, sql.ErrNoRows
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/sixync/birdlens-be/internal/request"
+	"github.com/sixync/birdlens-be/internal/response"
+	"github.com/sixync/birdlens-be/internal/store"
+	"github.com/sixync/birdlens-be/internal/validator"
+)
+
+var (
+	TourOperatorKey       key = "tour_operator"
+	TourOperatorMemberKey key = "tour_operator_member_role"
+)
+
+type CreateTourOperatorRequest struct {
+	Name        string  `json:"name" validate:"required,max=255"`
+	Description *string `json:"description"`
+	AvatarUrl   *string `json:"avatar_url"`
+	OwnerEmail  string  `json:"owner_email" validate:"required,email"`
+}
+
+// createTourOperatorHandler is admin-only. It creates the operator, makes the
+// given user its owner and grants them the tour_operator role.
+func (app *application) createTourOperatorHandler(w http.ResponseWriter, r *http.Request) {
+	var req CreateTourOperatorRequest
+	if err := request.DecodeJSON(w, r, &req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := validator.Validate(req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	owner, err := app.store.Users.GetByEmail(ctx, req.OwnerEmail)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.badRequest(w, r, errors.New("no user found with owner_email"))
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	operator := &store.TourOperator{
+		Name:        req.Name,
+		Description: req.Description,
+		AvatarUrl:   req.AvatarUrl,
+	}
+	err = app.store.TourOperators.Create(ctx, operator, owner.Id)
+	switch {
+	case errors.Is(err, store.ErrMemberUserNotFound):
+		app.badRequest(w, r, errors.New("no user found with owner_email"))
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, operator, false, "tour operator created successfully")
+}
+
+func (app *application) getTourOperatorHandler(w http.ResponseWriter, r *http.Request) {
+	operator := getTourOperatorFromCtx(r)
+	if operator == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, operator, false, "tour operator retrieved successfully")
+}
+
+type UpdateTourOperatorRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	AvatarUrl   *string `json:"avatar_url"`
+}
+
+func (app *application) updateTourOperatorHandler(w http.ResponseWriter, r *http.Request) {
+	operator := getTourOperatorFromCtx(r)
+	if operator == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	var req UpdateTourOperatorRequest
+	if err := request.DecodeJSON(w, r, &req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if req.Name != nil {
+		if len(*req.Name) == 0 || len(*req.Name) > 255 {
+			app.badRequest(w, r, errors.New("name must be between 1 and 255 characters"))
+			return
+		}
+		operator.Name = *req.Name
+	}
+	if req.Description != nil {
+		operator.Description = req.Description
+	}
+	if req.AvatarUrl != nil {
+		operator.AvatarUrl = req.AvatarUrl
+	}
+
+	if err := app.store.TourOperators.Update(r.Context(), operator); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, operator, false, "tour operator updated successfully")
+}
+
+func (app *application) getMyTourOperatorsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	operators, err := app.store.TourOperators.GetByUserID(r.Context(), user.Id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, operators, false, "tour operators retrieved successfully")
+}
+
+func (app *application) getTourOperatorToursHandler(w http.ResponseWriter, r *http.Request) {
+	operator := getTourOperatorFromCtx(r)
+	if operator == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	limit, offset := getPaginateFromCtx(r)
+	tours, err := app.store.Tours.GetByOperatorID(r.Context(), operator.ID, limit, offset)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, tours, false, "get tours successfully")
+}
+
+func (app *application) getTourOperatorBookingsHandler(w http.ResponseWriter, r *http.Request) {
+	operator := getTourOperatorFromCtx(r)
+	if operator == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	limit, offset := getPaginateFromCtx(r)
+	bookings, err := app.store.TourOperators.GetBookings(r.Context(), operator.ID, limit, offset)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, bookings, false, "bookings retrieved successfully")
+}
+
+func (app *application) getTourOperatorRevenueHandler(w http.ResponseWriter, r *http.Request) {
+	operator := getTourOperatorFromCtx(r)
+	if operator == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	revenue, err := app.store.TourOperators.GetRevenue(r.Context(), operator.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, revenue, false, "revenue retrieved successfully")
+}
+
+func (app *application) getTourOperatorStaffHandler(w http.ResponseWriter, r *http.Request) {
+	operator := getTourOperatorFromCtx(r)
+	if operator == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	members, err := app.store.TourOperators.GetMembers(r.Context(), operator.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, members, false, "staff retrieved successfully")
+}
+
+type AddTourOperatorStaffRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+func (app *application) addTourOperatorStaffHandler(w http.ResponseWriter, r *http.Request) {
+	operator := getTourOperatorFromCtx(r)
+	if operator == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	var req AddTourOperatorStaffRequest
+	if err := request.DecodeJSON(w, r, &req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := validator.Validate(req); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	staff, err := app.store.Users.GetByEmail(ctx, req.Email)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.badRequest(w, r, errors.New("no user found with this email"))
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	existingRole, err := app.store.TourOperators.GetMemberRole(ctx, operator.ID, staff.Id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		app.serverError(w, r, err)
+		return
+	}
+	if existingRole == store.OperatorMemberOwner {
+		app.badRequest(w, r, errors.New("user is already the owner of this tour operator"))
+		return
+	}
+
+	err = app.store.TourOperators.AddMember(ctx, operator.ID, staff.Id, store.OperatorMemberStaff)
+	switch {
+	case errors.Is(err, store.ErrOperatorNotFound):
+		app.notFound(w, r)
+		return
+	case errors.Is(err, store.ErrMemberUserNotFound):
+		app.badRequest(w, r, errors.New("no user found with this email"))
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, nil, false, "staff member added successfully")
+}
+
+func (app *application) removeTourOperatorStaffHandler(w http.ResponseWriter, r *http.Request) {
+	operator := getTourOperatorFromCtx(r)
+	if operator == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	userId, err := strconv.ParseInt(r.PathValue("user_id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, errors.New("invalid user_id"))
+		return
+	}
+
+	err = app.store.TourOperators.RemoveMember(r.Context(), operator.ID, userId)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFound(w, r)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, nil, false, "staff member removed successfully")
+}
+
+// operatorMemberRole returns the user's role within the operator. Admins are
+// treated as owners of every operator. An empty role means no access.
+func (app *application) operatorMemberRole(ctx context.Context, user *store.User, operatorID int64) (string, error) {
+	isAdmin, err := app.userHasAnyRole(ctx, user.Id, store.ADMIN)
+	if err != nil {
+		return "", err
+	}
+	if isAdmin {
+		return store.OperatorMemberOwner, nil
+	}
+
+	role, err := app.store.TourOperators.GetMemberRole(ctx, operatorID, user.Id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return role, err
+}
+
+func (app *application) getTourOperatorMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		operatorIdStr := r.PathValue("operator_id")
+		if operatorIdStr == "" {
+			app.badRequest(w, r, errors.New("operator_id is required"))
+			return
+		}
+
+		operatorId, err := strconv.ParseInt(operatorIdStr, 10, 64)
+		if err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+
+		operator, err := app.store.TourOperators.GetByID(r.Context(), operatorId)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			app.notFound(w, r)
+			return
+		case err != nil:
+			app.serverError(w, r, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), TourOperatorKey, operator)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// operatorMemberMiddleware allows owners, staff and admins of the operator in context.
+// It must run *after* authMiddleware and getTourOperatorMiddleware.
+func (app *application) operatorMemberMiddleware(next http.Handler) http.Handler {
+	return app.requireOperatorRole(next, false)
+}
+
+// operatorOwnerMiddleware allows only the owner of the operator in context, or an admin.
+// It must run *after* authMiddleware and getTourOperatorMiddleware.
+func (app *application) operatorOwnerMiddleware(next http.Handler) http.Handler {
+	return app.requireOperatorRole(next, true)
+}
+
+func (app *application) requireOperatorRole(next http.Handler, ownerOnly bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := app.getUserFromFirebaseClaimsCtx(r)
+		if user == nil {
+			app.unauthorized(w, r)
+			return
+		}
+
+		operator := getTourOperatorFromCtx(r)
+		if operator == nil {
+			app.notFound(w, r)
+			return
+		}
+
+		role, err := app.operatorMemberRole(r.Context(), user, operator.ID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if role == "" || (ownerOnly && role != store.OperatorMemberOwner) {
+			app.errorMessage(w, r, http.StatusForbidden, "You do not have permission to manage this tour operator.", nil)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), TourOperatorMemberKey, role)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getTourOperatorFromCtx(r *http.Request) *store.TourOperator {
+	operator, _ := r.Context().Value(TourOperatorKey).(*store.TourOperator)
+	return operator
+}
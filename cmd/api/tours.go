@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/sixync/birdlens-be/internal/request"
 	"github.com/sixync/birdlens-be/internal/response"
@@ -89,6 +91,11 @@ type TourCreateRequest struct {
 	TourCapacity    int     `json:"tour_capacity"`
 	DurationDays    int     `json:"duration_days" validate:"required"`
 	LocationId      int64   `json:"location_id" validate:"required"`
+	// OperatorID is required for tour operators and optional for admins.
+	OperatorID *int64 `json:"operator_id"`
+	// StartDate and EndDate default to the event's dates when omitted.
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
 }
 
 func (app *application) createTourHandler(
@@ -108,6 +115,52 @@ func (app *application) createTourHandler(
 
 	ctx := r.Context()
 
+	user := app.getUserFromFirebaseClaimsCtx(r)
+	if user == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	isAdmin, err := app.userHasAnyRole(ctx, user.Id, store.ADMIN)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if req.OperatorID == nil && !isAdmin {
+		app.badRequest(w, r, errors.New("operator_id is required"))
+		return
+	}
+
+	if req.OperatorID != nil {
+		role, err := app.operatorMemberRole(ctx, user, *req.OperatorID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		if role == "" {
+			app.errorMessage(w, r, http.StatusForbidden, "You do not have permission to create tours for this tour operator.", nil)
+			return
+		}
+	}
+
+	event, err := app.store.Events.GetByID(ctx, req.EventID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.badRequest(w, r, errors.New("event not found"))
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	if req.StartDate == "" {
+		req.StartDate = event.StartDate.Format(time.RFC3339)
+	}
+	if req.EndDate == "" {
+		req.EndDate = event.EndDate.Format(time.RFC3339)
+	}
+
 	tour := &store.Tour{
 		EventId:     req.EventID,
 		Name:        req.TourName,
@@ -115,7 +168,10 @@ func (app *application) createTourHandler(
 		Price:       req.Price,
 		Capacity:    req.TourCapacity,
 		Duration:    req.DurationDays,
+		StartDate:   req.StartDate,
+		EndDate:     req.EndDate,
 		LocationId:  req.LocationId,
+		OperatorId:  req.OperatorID,
 	}
 
 	log.Println(tour)
@@ -132,7 +188,7 @@ func (app *application) createTourHandler(
 	// 	tour.ThumbnailUrl = &thumbnailUrl
 	// }
 
-	err := app.store.Tours.Create(ctx, tour)
+	err = app.store.Tours.Create(ctx, tour)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
@@ -342,6 +398,52 @@ func (app *application) addTourThumbnailHandler(
 	response.JSON(w, http.StatusOK, url, false, "tour thumbnail added successfully")
 }
 
+func (app *application) deleteTourHandler(w http.ResponseWriter, r *http.Request) {
+	tour, err := app.getTourFromContext(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	err = app.store.Tours.Delete(r.Context(), tour.ID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFound(w, r)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, nil, false, "tour deleted successfully")
+}
+
+func (app *application) removeTourImageHandler(w http.ResponseWriter, r *http.Request) {
+	tour, err := app.getTourFromContext(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	imageUrl := r.URL.Query().Get("image_url")
+	if imageUrl == "" {
+		app.badRequest(w, r, errors.New("image_url is required"))
+		return
+	}
+
+	err = app.store.Tours.RemoveTourImageUrl(r.Context(), tour.ID, imageUrl)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFound(w, r)
+		return
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, nil, false, "tour image removed successfully")
+}
+
 // get tour middleware
 func (app *application) getTourMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -368,11 +470,44 @@ func (app *application) getTourMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// Mutating routes are only open to admins and members of the owning operator.
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			user := app.getUserFromFirebaseClaimsCtx(r)
+			if user == nil {
+				app.unauthorized(w, r)
+				return
+			}
+
+			allowed, err := app.canManageTour(r.Context(), user, tour)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+			if !allowed {
+				app.errorMessage(w, r, http.StatusForbidden, "You do not have permission to manage this tour.", nil)
+				return
+			}
+		}
+
 		ctx := context.WithValue(r.Context(), TourKey, tour)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// canManageTour reports whether the user is an admin or belongs to the tour's operator.
+// Tours without an operator can only be managed by admins.
+func (app *application) canManageTour(ctx context.Context, user *store.User, tour *store.Tour) (bool, error) {
+	if tour.OperatorId == nil {
+		return app.userHasAnyRole(ctx, user.Id, store.ADMIN)
+	}
+
+	role, err := app.operatorMemberRole(ctx, user, *tour.OperatorId)
+	if err != nil {
+		return false, err
+	}
+	return role != "", nil
+}
+
 func (app *application) getTourFromContext(r *http.Request) (*store.Tour, error) {
 	tour, ok := r.Context().Value(TourKey).(*store.Tour)
 	if !ok {
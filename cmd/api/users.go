@@ -7,7 +7,10 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/sixync/birdlens-be/internal/jwt"
 	"github.com/sixync/birdlens-be/internal/request"
@@ -37,7 +40,28 @@ func (app *application) getUserFollowersHandler(w http.ResponseWriter, r *http.R
 	}
 }
 
-// New handler for the life list
+type LifeListResponse struct {
+	Species []*store.LifeListEntry `json:"species"`
+	Stats   LifeListStats          `json:"stats"`
+}
+
+type LifeListStats struct {
+	TotalSpecies int                         `json:"total_species"`
+	NewThisYear  int                         `json:"new_this_year"`
+	ByFamily     []FamilySpeciesCount        `json:"by_family"`
+	ByCountry    []store.CountrySpeciesCount `json:"by_country"`
+}
+
+type FamilySpeciesCount struct {
+	FamilyCommonName     string `json:"family_common_name"`
+	FamilyScientificName string `json:"family_scientific_name"`
+	SpeciesCount         int    `json:"species_count"`
+}
+
+// getUserLifeListHandler returns the user's life list with statistics,
+// leaving out their private observations unless they are the viewer. The
+// optional year, month, country and location query parameters scope both the
+// list and the statistics, e.g. ?year=2024 for a year list.
 func (app *application) getUserLifeListHandler(w http.ResponseWriter, r *http.Request) {
 	user, err := getUserFromCtx(r)
 	if err != nil {
@@ -45,14 +69,115 @@ func (app *application) getUserLifeListHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	viewer := app.getUserFromFirebaseClaimsCtx(r)
+	if viewer == nil {
+		app.unauthorized(w, r)
+		return
+	}
+
+	filter, err := parseLifeListFilter(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
 	ctx := r.Context()
-	lifeList, err := app.store.Users.GetUserLifeList(ctx, user.Id)
+	lifeList, err := app.store.Users.GetUserLifeList(ctx, user.Id, viewer.Id, filter)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	byCountry, err := app.store.Users.GetUserLifeListCountries(ctx, user.Id, viewer.Id, filter)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	response.JSON(w, http.StatusOK, lifeList, false, "life list retrieved successfully")
+	codes := make([]string, len(lifeList))
+	for i, entry := range lifeList {
+		codes[i] = entry.SpeciesCode
+	}
+
+	taxa, err := app.store.Species.GetByCodes(ctx, codes)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	stats := LifeListStats{
+		TotalSpecies: len(lifeList),
+		ByFamily:     []FamilySpeciesCount{},
+		ByCountry:    byCountry,
+	}
+	currentYear := time.Now().Year()
+	familyIndex := map[string]int{}
+
+	for _, entry := range lifeList {
+		if entry.FirstSeenAt.Year() == currentYear {
+			stats.NewThisYear++
+		}
+
+		species, ok := taxa[entry.SpeciesCode]
+		if !ok {
+			continue
+		}
+		entry.CommonName = &species.CommonName
+		entry.ScientificName = &species.ScientificName
+		entry.FamilyCommonName = species.FamilyCommonName
+		entry.FamilyScientificName = species.FamilyScientificName
+
+		if species.FamilyScientificName == nil {
+			continue
+		}
+		i, ok := familyIndex[*species.FamilyScientificName]
+		if !ok {
+			i = len(stats.ByFamily)
+			familyIndex[*species.FamilyScientificName] = i
+			family := FamilySpeciesCount{FamilyScientificName: *species.FamilyScientificName}
+			if species.FamilyCommonName != nil {
+				family.FamilyCommonName = *species.FamilyCommonName
+			}
+			stats.ByFamily = append(stats.ByFamily, family)
+		}
+		stats.ByFamily[i].SpeciesCount++
+	}
+
+	sort.SliceStable(stats.ByFamily, func(i, j int) bool {
+		return stats.ByFamily[i].SpeciesCount > stats.ByFamily[j].SpeciesCount
+	})
+
+	app.locationJSON(w, r, http.StatusOK, LifeListResponse{Species: lifeList, Stats: stats}, "life list retrieved successfully")
+}
+
+func parseLifeListFilter(r *http.Request) (store.LifeListFilter, error) {
+	query := r.URL.Query()
+	filter := store.LifeListFilter{
+		CountryCode: strings.ToUpper(strings.TrimSpace(query.Get("country"))),
+		Location:    strings.TrimSpace(query.Get("location")),
+	}
+
+	if yearStr := query.Get("year"); yearStr != "" {
+		year, err := strconv.Atoi(yearStr)
+		if err != nil || year < 1900 || year > 9999 {
+			return filter, errors.New("invalid year")
+		}
+		filter.Year = year
+	}
+
+	if monthStr := query.Get("month"); monthStr != "" {
+		month, err := strconv.Atoi(monthStr)
+		if err != nil || month < 1 || month > 12 {
+			return filter, errors.New("month must be between 1 and 12")
+		}
+		filter.Month = month
+	}
+
+	if filter.CountryCode != "" && len(filter.CountryCode) != 2 {
+		return filter, errors.New("country must be a two-letter ISO code")
+	}
+
+	return filter, nil
 }
 
 type CreateUserReq struct {
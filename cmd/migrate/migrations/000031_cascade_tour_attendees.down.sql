@@ -0,0 +1,3 @@
+ALTER TABLE tour_attendees
+DROP CONSTRAINT IF EXISTS tour_attendees_tour_id_fkey,
+ADD CONSTRAINT tour_attendees_tour_id_fkey FOREIGN KEY (tour_id) REFERENCES tours(id);
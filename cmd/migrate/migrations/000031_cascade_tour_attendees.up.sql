@@ -0,0 +1,4 @@
+-- Lets admins delete a tour without first clearing its attendee records by hand.
+ALTER TABLE tour_attendees
+DROP CONSTRAINT IF EXISTS tour_attendees_tour_id_fkey,
+ADD CONSTRAINT tour_attendees_tour_id_fkey FOREIGN KEY (tour_id) REFERENCES tours(id) ON DELETE CASCADE;
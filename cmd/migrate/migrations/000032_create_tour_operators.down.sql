@@ -0,0 +1,10 @@
+DELETE FROM user_roles WHERE role_id IN (SELECT id FROM roles WHERE name = 'tour_operator');
+DELETE FROM roles WHERE name = 'tour_operator';
+
+DROP INDEX IF EXISTS idx_tours_operator_id;
+ALTER TABLE tours
+DROP CONSTRAINT IF EXISTS fk_tours_operator,
+DROP COLUMN IF EXISTS operator_id;
+
+DROP TABLE IF EXISTS tour_operator_members;
+DROP TABLE IF EXISTS tour_operators;
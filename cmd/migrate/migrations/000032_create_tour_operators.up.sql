@@ -0,0 +1,30 @@
+-- Tour operators are organizations (stores) that own and run tours.
+CREATE TABLE IF NOT EXISTS tour_operators (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    avatar_url TEXT,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP(0) WITH TIME ZONE
+);
+
+-- Members of an operator. Exactly one 'owner' manages the 'staff' list.
+CREATE TABLE IF NOT EXISTS tour_operator_members (
+    operator_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    member_role VARCHAR(20) NOT NULL DEFAULT 'staff',
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (operator_id, user_id),
+    CONSTRAINT fk_operator_members_operator FOREIGN KEY (operator_id) REFERENCES tour_operators(id) ON DELETE CASCADE,
+    CONSTRAINT fk_operator_members_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_tour_operator_members_user_id ON tour_operator_members(user_id);
+
+ALTER TABLE tours
+ADD COLUMN operator_id BIGINT,
+ADD CONSTRAINT fk_tours_operator FOREIGN KEY (operator_id) REFERENCES tour_operators(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tours_operator_id ON tours(operator_id);
+
+INSERT INTO roles (name) VALUES ('tour_operator') ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS calendar_feed_tokens;
+DROP INDEX IF EXISTS idx_events_start_end;
+DROP TABLE IF EXISTS event_rsvps;
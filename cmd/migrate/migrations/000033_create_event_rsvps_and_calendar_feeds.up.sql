@@ -0,0 +1,26 @@
+-- RSVPs let users mark an event as 'going' or 'interested'.
+CREATE TABLE IF NOT EXISTS event_rsvps (
+    event_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP(0) WITH TIME ZONE,
+    PRIMARY KEY (event_id, user_id),
+    CONSTRAINT chk_event_rsvps_status CHECK (status IN ('going', 'interested')),
+    CONSTRAINT fk_event_rsvps_event FOREIGN KEY (event_id) REFERENCES events(id) ON DELETE CASCADE,
+    CONSTRAINT fk_event_rsvps_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_rsvps_user_id ON event_rsvps(user_id);
+
+-- Calendar range queries filter on the event's start and end dates.
+CREATE INDEX IF NOT EXISTS idx_events_start_end ON events(start_date, end_date);
+
+-- Secret tokens for the per-user subscribed calendar feed. Calendar apps
+-- cannot send our bearer token, so the feed URL itself is the credential.
+CREATE TABLE IF NOT EXISTS calendar_feed_tokens (
+    user_id BIGINT PRIMARY KEY,
+    token TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_calendar_feed_tokens_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,35 @@
+-- Observations are first-class sighting records. A post may optionally be
+-- linked to one; checklist_id groups observations from the same outing.
+CREATE TABLE IF NOT EXISTS observations (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    post_id BIGINT,
+    checklist_id BIGINT,
+    species_code VARCHAR(20) NOT NULL,
+    count INT, -- NULL means the species was present but not counted ('X' in eBird)
+    latitude DECIMAL(10,7),
+    longitude DECIMAL(11,8),
+    location_name VARCHAR(255),
+    observed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    breeding_code VARCHAR(4),
+    behavior_code VARCHAR(30),
+    notes TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT chk_observations_count CHECK (count IS NULL OR count > 0),
+    CONSTRAINT fk_observations_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_observations_post FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_observations_user_species ON observations(user_id, species_code);
+CREATE INDEX IF NOT EXISTS idx_observations_user_observed_at ON observations(user_id, observed_at DESC);
+CREATE INDEX IF NOT EXISTS idx_observations_checklist_id ON observations(checklist_id);
+CREATE INDEX IF NOT EXISTS idx_observations_post_id ON observations(post_id);
+
+-- Backfill from existing sighting posts so life lists keep their history.
+INSERT INTO observations (user_id, post_id, species_code, latitude, longitude, location_name, observed_at)
+SELECT user_id, id, tagged_species_code, latitude, longitude, location_name, COALESCE(sighting_date, created_at)
+FROM posts
+WHERE type = 'sighting'
+  AND tagged_species_code IS NOT NULL
+  AND user_id IS NOT NULL;
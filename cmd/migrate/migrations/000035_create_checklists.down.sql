@@ -0,0 +1,2 @@
+ALTER TABLE observations DROP CONSTRAINT IF EXISTS fk_observations_checklist;
+DROP TABLE IF EXISTS checklists;
@@ -0,0 +1,35 @@
+-- A checklist is one birding outing. Its species counts are observations
+-- that point back at it through observations.checklist_id.
+CREATE TABLE IF NOT EXISTS checklists (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    location_name VARCHAR(255) NOT NULL,
+    latitude DECIMAL(10,7),
+    longitude DECIMAL(11,8),
+    state_code VARCHAR(10),
+    country_code VARCHAR(2),
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    duration_minutes INT,
+    distance_km DECIMAL(8,3),
+    protocol VARCHAR(20) NOT NULL,
+    number_of_observers INT NOT NULL DEFAULT 1,
+    is_complete BOOLEAN NOT NULL DEFAULT FALSE,
+    comments TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT chk_checklists_protocol CHECK (protocol IN ('stationary', 'traveling', 'incidental')),
+    CONSTRAINT chk_checklists_duration CHECK (duration_minutes IS NULL OR duration_minutes > 0),
+    CONSTRAINT chk_checklists_distance CHECK (distance_km IS NULL OR distance_km >= 0),
+    CONSTRAINT chk_checklists_observers CHECK (number_of_observers > 0),
+    CONSTRAINT fk_checklists_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_checklists_user_started_at ON checklists(user_id, started_at DESC);
+
+-- Any checklist_id written before this table existed cannot be resolved.
+UPDATE observations SET checklist_id = NULL
+WHERE checklist_id IS NOT NULL
+  AND checklist_id NOT IN (SELECT id FROM checklists);
+
+ALTER TABLE observations
+    ADD CONSTRAINT fk_observations_checklist FOREIGN KEY (checklist_id) REFERENCES checklists(id) ON DELETE CASCADE;
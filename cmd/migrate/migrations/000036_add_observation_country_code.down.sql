@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_observations_user_country;
+ALTER TABLE observations DROP COLUMN IF EXISTS country_code;
@@ -0,0 +1,11 @@
+-- Country is needed for per-country life lists. Checklist observations take
+-- it from their checklist; standalone observations may set it directly.
+ALTER TABLE observations ADD COLUMN IF NOT EXISTS country_code VARCHAR(2);
+
+UPDATE observations o
+SET country_code = c.country_code
+FROM checklists c
+WHERE o.checklist_id = c.id
+  AND c.country_code IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_observations_user_country ON observations(user_id, country_code);
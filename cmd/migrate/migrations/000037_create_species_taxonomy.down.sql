@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS species_names;
+DROP TABLE IF EXISTS species_taxonomy;
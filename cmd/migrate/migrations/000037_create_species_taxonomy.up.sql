@@ -0,0 +1,35 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Local copy of the eBird/Clements taxonomy, loaded by cmd/taxonomy.
+CREATE TABLE IF NOT EXISTS species_taxonomy (
+    species_code VARCHAR(20) PRIMARY KEY,
+    common_name VARCHAR(255) NOT NULL,
+    scientific_name VARCHAR(255) NOT NULL,
+    category VARCHAR(20) NOT NULL,
+    taxon_order DOUBLE PRECISION NOT NULL,
+    order_name VARCHAR(100),
+    family_code VARCHAR(20),
+    family_common_name VARCHAR(255),
+    family_scientific_name VARCHAR(255),
+    report_as VARCHAR(20),
+    extinct BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_species_taxonomy_taxon_order ON species_taxonomy(taxon_order);
+CREATE INDEX IF NOT EXISTS idx_species_taxonomy_family ON species_taxonomy(family_scientific_name);
+CREATE INDEX IF NOT EXISTS idx_species_taxonomy_scientific_name ON species_taxonomy(LOWER(scientific_name));
+
+-- Every searchable name for a species: 'en' common names, 'sci' scientific
+-- names and localized common names such as 'vi'. search_name is lowercased
+-- with diacritics removed so "cò" and "co" match alike.
+CREATE TABLE IF NOT EXISTS species_names (
+    species_code VARCHAR(20) NOT NULL,
+    locale VARCHAR(10) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    search_name VARCHAR(255) NOT NULL,
+    PRIMARY KEY (species_code, locale),
+    CONSTRAINT fk_species_names_species FOREIGN KEY (species_code) REFERENCES species_taxonomy(species_code) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_species_names_search_trgm ON species_names USING GIN (search_name gin_trgm_ops);
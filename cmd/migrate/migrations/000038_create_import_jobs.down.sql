@@ -0,0 +1,4 @@
+ALTER TABLE observations DROP COLUMN IF EXISTS is_private;
+DROP INDEX IF EXISTS idx_checklists_user_external_id;
+ALTER TABLE checklists DROP COLUMN IF EXISTS external_id;
+DROP TABLE IF EXISTS import_jobs;
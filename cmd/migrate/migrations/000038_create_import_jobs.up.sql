@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    source VARCHAR(20) NOT NULL,
+    filename VARCHAR(255),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    total_rows INT NOT NULL DEFAULT 0,
+    processed_rows INT NOT NULL DEFAULT 0,
+    checklists_created INT NOT NULL DEFAULT 0,
+    observations_created INT NOT NULL DEFAULT 0,
+    duplicate_rows INT NOT NULL DEFAULT 0,
+    error_rows INT NOT NULL DEFAULT 0,
+    errors JSONB NOT NULL DEFAULT '[]',
+    failure_reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT chk_import_jobs_status CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    CONSTRAINT fk_import_jobs_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_jobs_user_id ON import_jobs(user_id, created_at DESC);
+
+-- external_id is the eBird submission ID (S123456789) of imported checklists,
+-- used to skip checklists that were already imported.
+ALTER TABLE checklists ADD COLUMN IF NOT EXISTS external_id VARCHAR(50);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_checklists_user_external_id ON checklists(user_id, external_id) WHERE external_id IS NOT NULL;
+
+-- Private observations are only visible to their owner, never in public
+-- aggregates. Imported history is private by default.
+ALTER TABLE observations ADD COLUMN IF NOT EXISTS is_private BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Existing observations inherit the privacy of their post, so sightings
+-- shared privately before this column existed stay out of public aggregates.
+UPDATE observations o SET is_private = TRUE
+FROM posts p
+WHERE o.post_id = p.id AND COALESCE(p.privacy_level, '') NOT IN ('', 'public');
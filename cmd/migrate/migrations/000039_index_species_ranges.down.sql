@@ -0,0 +1,4 @@
+-- The table holds externally loaded data, so only the indexes are dropped.
+DROP INDEX IF EXISTS idx_species_ranges_lower_sci_name;
+DROP INDEX IF EXISTS idx_species_ranges_sci_name;
+DROP INDEX IF EXISTS idx_species_ranges_geom;
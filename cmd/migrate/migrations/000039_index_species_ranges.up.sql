@@ -0,0 +1,16 @@
+CREATE EXTENSION IF NOT EXISTS postgis;
+
+-- species_ranges is bulk-loaded from range shapefiles (e.g. with shp2pgsql).
+-- Create it here with the columns the API relies on so loaders can append
+-- to it, and so the indexes below exist whichever happens first.
+CREATE TABLE IF NOT EXISTS public.species_ranges (
+    id SERIAL PRIMARY KEY,
+    sci_name VARCHAR(255) NOT NULL,
+    geom geometry(MultiPolygon, 4326) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_species_ranges_geom ON public.species_ranges USING GIST (geom);
+CREATE INDEX IF NOT EXISTS idx_species_ranges_sci_name ON public.species_ranges (sci_name);
+CREATE INDEX IF NOT EXISTS idx_species_ranges_lower_sci_name ON public.species_ranges (LOWER(sci_name));
+
+ANALYZE public.species_ranges;
@@ -0,0 +1,7 @@
+ALTER TABLE species_taxonomy DROP COLUMN IF EXISTS is_sensitive;
+
+DROP INDEX IF EXISTS idx_observations_location;
+DROP INDEX IF EXISTS idx_posts_location;
+
+ALTER TABLE observations DROP COLUMN IF EXISTS location;
+ALTER TABLE posts DROP COLUMN IF EXISTS location;
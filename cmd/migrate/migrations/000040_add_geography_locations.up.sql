@@ -0,0 +1,23 @@
+CREATE EXTENSION IF NOT EXISTS postgis;
+
+-- Geography points kept in sync with the latitude/longitude columns. Posts
+-- created without a location store 0,0, which is treated as no location.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS location geography(Point, 4326)
+    GENERATED ALWAYS AS (
+        CASE WHEN latitude IS NOT NULL AND longitude IS NOT NULL AND NOT (latitude = 0 AND longitude = 0)
+             THEN ST_SetSRID(ST_MakePoint(longitude::float8, latitude::float8), 4326)::geography
+        END
+    ) STORED;
+
+ALTER TABLE observations ADD COLUMN IF NOT EXISTS location geography(Point, 4326)
+    GENERATED ALWAYS AS (
+        CASE WHEN latitude IS NOT NULL AND longitude IS NOT NULL
+             THEN ST_SetSRID(ST_MakePoint(longitude::float8, latitude::float8), 4326)::geography
+        END
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_posts_location ON posts USING GIST (location);
+CREATE INDEX IF NOT EXISTS idx_observations_location ON observations USING GIST (location);
+
+-- Sensitive species (e.g. targets of trapping) only expose coarse locations publicly.
+ALTER TABLE species_taxonomy ADD COLUMN IF NOT EXISTS is_sensitive BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS hotspot_visiting_times;
+DROP TABLE IF EXISTS ebird_historic_observations;
+DROP TABLE IF EXISTS ebird_historic_days;
+DROP TABLE IF EXISTS ebird_cached_hotspots;
@@ -0,0 +1,39 @@
+-- Hotspots whose eBird history is cached and kept fresh by the refresher.
+CREATE TABLE IF NOT EXISTS ebird_cached_hotspots (
+    loc_id VARCHAR(20) PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_refreshed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- One row per successfully fetched day. Past days do not change, so a day
+-- is never fetched again once it is here; failed days are simply absent.
+CREATE TABLE IF NOT EXISTS ebird_historic_days (
+    loc_id VARCHAR(20) NOT NULL,
+    obs_date DATE NOT NULL,
+    fetched_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (loc_id, obs_date),
+    CONSTRAINT fk_ebird_historic_days_hotspot FOREIGN KEY (loc_id) REFERENCES ebird_cached_hotspots(loc_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS ebird_historic_observations (
+    loc_id VARCHAR(20) NOT NULL,
+    obs_date DATE NOT NULL,
+    species_code VARCHAR(20) NOT NULL,
+    obs_time TIME, -- NULL when eBird has no time for the observation
+    how_many INT,
+    CONSTRAINT fk_ebird_historic_observations_day FOREIGN KEY (loc_id, obs_date) REFERENCES ebird_historic_days(loc_id, obs_date) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_ebird_historic_observations_loc_date ON ebird_historic_observations(loc_id, obs_date);
+
+-- Visiting-times analyses computed from the cache. species_code is '' for all species.
+CREATE TABLE IF NOT EXISTS hotspot_visiting_times (
+    loc_id VARCHAR(20) NOT NULL,
+    species_code VARCHAR(20) NOT NULL DEFAULT '',
+    analysis JSONB NOT NULL,
+    days_covered INT NOT NULL,
+    days_missing INT NOT NULL,
+    computed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (loc_id, species_code),
+    CONSTRAINT fk_hotspot_visiting_times_hotspot FOREIGN KEY (loc_id) REFERENCES ebird_cached_hotspots(loc_id) ON DELETE CASCADE
+);
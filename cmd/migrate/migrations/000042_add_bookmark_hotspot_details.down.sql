@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_user_bookmarks_hotspot;
+DROP INDEX IF EXISTS idx_user_bookmarks_created_at;
+
+ALTER TABLE user_bookmarks
+    DROP COLUMN IF EXISTS note,
+    DROP COLUMN IF EXISTS longitude,
+    DROP COLUMN IF EXISTS latitude,
+    DROP COLUMN IF EXISTS hotspot_name;
@@ -0,0 +1,11 @@
+-- Bookmarks keep the hotspot's name and position from when they were
+-- created, so that lists can be shown without asking eBird.
+ALTER TABLE user_bookmarks
+    ADD COLUMN IF NOT EXISTS hotspot_name TEXT,
+    ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS note TEXT;
+
+-- Trending ranks hotspots by their bookmarks of the last days.
+CREATE INDEX IF NOT EXISTS idx_user_bookmarks_created_at ON user_bookmarks (created_at);
+CREATE INDEX IF NOT EXISTS idx_user_bookmarks_hotspot ON user_bookmarks (hotspot_location_id);
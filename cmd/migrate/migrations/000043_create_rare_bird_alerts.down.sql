@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS rare_bird_alerts;
+DROP TABLE IF EXISTS alert_settings;
+DROP TABLE IF EXISTS species_watchlists;
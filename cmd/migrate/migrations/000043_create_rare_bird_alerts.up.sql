@@ -0,0 +1,51 @@
+-- Species a user wants to hear about wherever they watch.
+CREATE TABLE IF NOT EXISTS species_watchlists (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    species_code TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, species_code)
+);
+
+-- Where and how a user receives rare bird alerts. Quiet hours are local
+-- hours in timezone; start = end means none.
+CREATE TABLE IF NOT EXISTS alert_settings (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    notify_notable BOOLEAN NOT NULL DEFAULT TRUE,
+    use_bookmarks BOOLEAN NOT NULL DEFAULT TRUE,
+    home_latitude DOUBLE PRECISION,
+    home_longitude DOUBLE PRECISION,
+    home_radius_km INT NOT NULL DEFAULT 10,
+    email_digest BOOLEAN NOT NULL DEFAULT TRUE,
+    quiet_hours_start SMALLINT CHECK (quiet_hours_start BETWEEN 0 AND 23),
+    quiet_hours_end SMALLINT CHECK (quiet_hours_end BETWEEN 0 AND 23),
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    max_alerts_per_day INT NOT NULL DEFAULT 10,
+    last_digest_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Every alert raised for a user. dedupe_key identifies the sighting (a
+-- species at a place on a day, or a post) so that it is raised only once.
+CREATE TABLE IF NOT EXISTS rare_bird_alerts (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    dedupe_key TEXT NOT NULL,
+    source TEXT NOT NULL, -- 'ebird' or 'post'
+    reason TEXT NOT NULL, -- 'notable' or 'watchlist'
+    species_code TEXT NOT NULL,
+    common_name TEXT NOT NULL,
+    location_name TEXT NOT NULL DEFAULT '',
+    loc_id TEXT,
+    post_id BIGINT REFERENCES posts(id) ON DELETE CASCADE,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    how_many INT,
+    observed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    notified_at TIMESTAMP WITH TIME ZONE,
+    emailed_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (user_id, dedupe_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_rare_bird_alerts_user_created ON rare_bird_alerts (user_id, created_at DESC);
@@ -0,0 +1,22 @@
+-- Every AI identification request, what the model answered and, once the
+-- user tells us, whether it was right. chosen_species_code is the species
+-- reported as identified; it is NULL when the model listed several or
+-- none. Rows with an image and feedback form the labelled dataset.
+CREATE TABLE IF NOT EXISTS ai_identifications (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    input_type TEXT NOT NULL, -- 'image' or 'text'
+    prompt TEXT NOT NULL,
+    image_url TEXT,
+    candidates JSONB NOT NULL DEFAULT '[]',
+    chosen_species_code TEXT,
+    chosen_name TEXT,
+    model TEXT NOT NULL,
+    feedback TEXT CHECK (feedback IN ('correct', 'incorrect')),
+    actual_species_code TEXT,
+    feedback_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_identifications_user_created ON ai_identifications (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_ai_identifications_feedback ON ai_identifications (chosen_species_code) WHERE feedback IS NOT NULL;
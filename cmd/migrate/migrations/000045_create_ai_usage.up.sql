@@ -0,0 +1,13 @@
+-- AI calls and model tokens per user per UTC day, metered against the
+-- limits of the user's subscription.
+CREATE TABLE IF NOT EXISTS ai_usage (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    identify_calls INT NOT NULL DEFAULT 0,
+    question_calls INT NOT NULL DEFAULT 0,
+    input_tokens BIGINT NOT NULL DEFAULT 0,
+    output_tokens BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_usage_day ON ai_usage (day);
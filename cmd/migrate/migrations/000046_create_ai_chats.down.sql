@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS ai_chat_messages;
+DROP TABLE IF EXISTS ai_chats;
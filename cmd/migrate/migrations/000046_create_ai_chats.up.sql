@@ -0,0 +1,28 @@
+-- Conversations with the AI assistant about a bird from a prior
+-- identification. message_count is raised with every exchange, and only
+-- while it stays within the chat length limit, so that concurrent requests
+-- cannot grow a conversation past it.
+CREATE TABLE IF NOT EXISTS ai_chats (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    identification_id BIGINT REFERENCES ai_identifications(id) ON DELETE SET NULL,
+    species_code TEXT,
+    bird_name TEXT NOT NULL,
+    message_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_chats_user_updated ON ai_chats (user_id, updated_at DESC);
+
+CREATE TABLE IF NOT EXISTS ai_chat_messages (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    chat_id BIGINT NOT NULL REFERENCES ai_chats(id) ON DELETE CASCADE,
+    role TEXT NOT NULL, -- 'user' or 'model'
+    text TEXT NOT NULL,
+    input_tokens INT NOT NULL DEFAULT 0,
+    output_tokens INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_chat_messages_chat ON ai_chat_messages (chat_id, id);
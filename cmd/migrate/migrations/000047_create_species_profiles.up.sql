@@ -0,0 +1,12 @@
+-- AI-written species descriptions and a Wikipedia image, per species and
+-- language. Profiles older than the refresh interval are served while they
+-- are regenerated.
+CREATE TABLE IF NOT EXISTS species_profiles (
+    species_code TEXT NOT NULL,
+    language TEXT NOT NULL,
+    description TEXT NOT NULL,
+    image_url TEXT,
+    model TEXT NOT NULL,
+    generated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (species_code, language)
+);
@@ -0,0 +1,134 @@
+// Package ai identifies birds and answers questions about them on top of a
+// pluggable model provider. Providers register themselves by name and are
+// chosen by configuration, so handlers only depend on Identifier and
+// Assistant.
+package ai
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ErrUnsupported is matched by the error of a request the provider cannot
+// serve, e.g. text identification by an image-only classifier.
+var ErrUnsupported = errors.New("ai: not supported by this provider")
+
+// Languages are the languages Describe writes in, by ISO 639-1 code.
+var Languages = map[string]string{
+	"en": "English",
+	"vi": "Vietnamese",
+}
+
+// Message roles of a conversation.
+const (
+	RoleUser  = "user"
+	RoleModel = "model"
+)
+
+// Media is an uploaded file handed to the model.
+type Media struct {
+	Data     []byte
+	MIMEType string // e.g. "image/jpeg" or "audio/wav"
+}
+
+func (m Media) IsAudio() bool {
+	return strings.HasPrefix(m.MIMEType, "audio/")
+}
+
+// Candidate is a species the model considers. Confidence is between 0 and
+// 1; providers that cannot tell leave ScientificName and FieldMarks empty.
+type Candidate struct {
+	Name           string   `json:"name"`
+	ScientificName string   `json:"scientific_name,omitempty"`
+	Confidence     float64  `json:"confidence"`
+	FieldMarks     []string `json:"field_marks,omitempty"`
+}
+
+// Usage is what a request cost in model tokens. Providers that do not
+// count tokens report zero.
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+func (u Usage) Add(other Usage) Usage {
+	return Usage{InputTokens: u.InputTokens + other.InputTokens, OutputTokens: u.OutputTokens + other.OutputTokens}
+}
+
+// Identification is the outcome of an identification request. Candidates
+// are ordered by confidence, highest first, and empty when no bird was
+// recognised.
+type Identification struct {
+	Candidates []Candidate `json:"candidates"`
+	Model      string      `json:"model"`
+	Usage      Usage       `json:"usage"`
+}
+
+// NoBird reports whether no bird was recognised.
+func (i *Identification) NoBird() bool {
+	return len(i.Candidates) == 0
+}
+
+func (i *Identification) sortCandidates() {
+	sort.SliceStable(i.Candidates, func(a, b int) bool {
+		return i.Candidates[a].Confidence > i.Candidates[b].Confidence
+	})
+}
+
+// Reply is an answer of the assistant.
+type Reply struct {
+	Text  string `json:"text"`
+	Model string `json:"model"`
+	Usage Usage  `json:"usage"`
+}
+
+// Message is a turn of a conversation about a bird.
+type Message struct {
+	Role string `json:"role"`
+	Text string `json:"text"`
+}
+
+// Identifier recognises bird species.
+type Identifier interface {
+	// IdentifyMedia identifies the bird in photos and sound recordings of
+	// the same bird, all considered together. Providers that cannot take
+	// some of the media fail with ErrUnsupported.
+	IdentifyMedia(ctx context.Context, media []Media) (*Identification, error)
+	// IdentifyText extracts the bird named in free text, in any language.
+	IdentifyText(ctx context.Context, text string) (*Identification, error)
+}
+
+// Assistant talks about bird species.
+type Assistant interface {
+	// Describe introduces a species by its common English name, in the
+	// language with the given code; see Languages.
+	Describe(ctx context.Context, name, language string) (*Reply, error)
+	// Ask answers a question following the conversation in history.
+	Ask(ctx context.Context, history []Message, question string) (*Reply, error)
+}
+
+// StreamingAssistant is an Assistant that delivers answers as they are
+// generated. onText receives the text in order; returning an error from it
+// stops the answer.
+type StreamingAssistant interface {
+	Assistant
+	AskStream(ctx context.Context, history []Message, question string, onText func(string) error) (*Reply, error)
+}
+
+// AskStream answers through a's streaming when it supports it, and as one
+// piece otherwise. The returned Reply holds the whole answer either way.
+func AskStream(ctx context.Context, a Assistant, history []Message, question string, onText func(string) error) (*Reply, error) {
+	if s, ok := a.(StreamingAssistant); ok {
+		return s.AskStream(ctx, history, question, onText)
+	}
+	reply, err := a.Ask(ctx, history, question)
+	if err != nil {
+		return nil, err
+	}
+	if err := onText(reply.Text); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
@@ -0,0 +1,128 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	classifierTimeout       = 30 * time.Second
+	classifierMaxCandidates = 3
+)
+
+func init() {
+	Register("classifier", func(ctx context.Context, cfg Config) (Provider, error) {
+		return NewClassifier(cfg.Endpoint)
+	})
+}
+
+// Classifier is an image-only provider that posts photos to a self-hosted
+// classification service, e.g. an ONNX model behind HTTP. The service
+// receives the raw image with its Content-Type and answers with
+//
+//	{"model": "birds-v2", "predictions": [{"label": "House Sparrow", "score": 0.91}]}
+//
+// Labels must be common English names; scores become the confidence.
+type Classifier struct {
+	endpoint string
+	client   *http.Client
+}
+
+func NewClassifier(endpoint string) (*Classifier, error) {
+	if endpoint == "" {
+		return nil, errors.New("classifier endpoint is not set")
+	}
+	return &Classifier{endpoint: endpoint, client: &http.Client{Timeout: classifierTimeout}}, nil
+}
+
+func (c *Classifier) Close() error {
+	return nil
+}
+
+type classifierResponse struct {
+	Model       string `json:"model"`
+	Predictions []struct {
+		Label string  `json:"label"`
+		Score float64 `json:"score"`
+	} `json:"predictions"`
+}
+
+// IdentifyMedia classifies each photo and averages the scores of every
+// label over all of them, a label missing from a photo's predictions
+// counting as 0. Recordings are not supported.
+func (c *Classifier) IdentifyMedia(ctx context.Context, media []Media) (*Identification, error) {
+	if len(media) == 0 {
+		return nil, errors.New("no media to identify")
+	}
+	for _, m := range media {
+		if m.IsAudio() {
+			return nil, fmt.Errorf("%w: sound recordings", ErrUnsupported)
+		}
+	}
+
+	id := &Identification{}
+	scores := map[string]float64{}
+	var labels []string
+	for _, image := range media {
+		body, err := c.classify(ctx, image)
+		if err != nil {
+			return nil, err
+		}
+		if id.Model == "" {
+			id.Model = body.Model
+		}
+		for _, p := range body.Predictions {
+			if p.Label == "" {
+				continue
+			}
+			if _, ok := scores[p.Label]; !ok {
+				labels = append(labels, p.Label)
+			}
+			scores[p.Label] += min(max(p.Score, 0), 1)
+		}
+	}
+	if id.Model == "" {
+		id.Model = "classifier"
+	}
+
+	for _, label := range labels {
+		id.Candidates = append(id.Candidates, Candidate{Name: label, Confidence: scores[label] / float64(len(media))})
+	}
+	id.sortCandidates()
+	if len(id.Candidates) > classifierMaxCandidates {
+		id.Candidates = id.Candidates[:classifierMaxCandidates]
+	}
+	return id, nil
+}
+
+func (c *Classifier) classify(ctx context.Context, image Media) (*classifierResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(image.Data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", image.MIMEType)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("classifier request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("classifier returned status %d", resp.StatusCode)
+	}
+
+	var body classifierResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode classifier response: %w", err)
+	}
+	return &body, nil
+}
+
+func (c *Classifier) IdentifyText(ctx context.Context, text string) (*Identification, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,142 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const FakeModel = "fake"
+
+func init() {
+	Register("fake", func(ctx context.Context, cfg Config) (Provider, error) {
+		return NewFake(), nil
+	})
+}
+
+// Fake is a deterministic provider for tests and local development. Photos
+// and recordings are identified as MediaCandidates; text is identified by
+// looking it up, case-insensitively, in TextCandidates, and anything else
+// is "no bird".
+// A lone candidate gets confidence 0.95; in a list the first gets 0.6 and
+// each next one 0.1 less. Usage counts a token per four bytes of input and
+// output. Every call is recorded.
+type Fake struct {
+	MediaCandidates []string
+	TextCandidates  map[string][]string
+	// Err, when set, is returned by every call.
+	Err error
+
+	mu    sync.Mutex
+	calls []string
+}
+
+// NewFake returns a Fake that finds a House Sparrow in all media and knows
+// a few names.
+func NewFake() *Fake {
+	return &Fake{
+		MediaCandidates: []string{"House Sparrow"},
+		TextCandidates: map[string][]string{
+			"blue jay": {"Blue Jay"},
+			"họa mi":   {"Chinese Hwamei"},
+			"sparrow":  {"House Sparrow", "Eurasian Tree Sparrow", "Song Sparrow"},
+		},
+	}
+}
+
+func (f *Fake) Close() error {
+	return nil
+}
+
+// Calls returns the calls made so far, e.g. "IdentifyText(blue jay)".
+func (f *Fake) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+func (f *Fake) record(format string, args ...any) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, fmt.Sprintf(format, args...))
+	return f.Err
+}
+
+func (f *Fake) IdentifyMedia(ctx context.Context, media []Media) (*Identification, error) {
+	types := make([]string, len(media))
+	size := 0
+	for i, m := range media {
+		types[i] = m.MIMEType
+		size += len(m.Data)
+	}
+	if err := f.record("IdentifyMedia(%s, %d bytes)", strings.Join(types, " "), size); err != nil {
+		return nil, err
+	}
+	id := fakeIdentification(f.MediaCandidates)
+	id.Usage = fakeUsage(size, len(id.Candidates)*20)
+	return id, nil
+}
+
+func (f *Fake) IdentifyText(ctx context.Context, text string) (*Identification, error) {
+	if err := f.record("IdentifyText(%s)", text); err != nil {
+		return nil, err
+	}
+	id := fakeIdentification(f.TextCandidates[strings.ToLower(strings.TrimSpace(text))])
+	id.Usage = fakeUsage(len(text), len(id.Candidates)*20)
+	return id, nil
+}
+
+func (f *Fake) Describe(ctx context.Context, name, language string) (*Reply, error) {
+	if err := f.record("Describe(%s, %s)", name, language); err != nil {
+		return nil, err
+	}
+	text := fmt.Sprintf("The %s is a bird.", name)
+	if language != "" && language != "en" {
+		text = fmt.Sprintf("[%s] %s", language, text)
+	}
+	return &Reply{Text: text, Model: FakeModel, Usage: fakeUsage(len(name), len(text))}, nil
+}
+
+func (f *Fake) Ask(ctx context.Context, history []Message, question string) (*Reply, error) {
+	if err := f.record("Ask(%d messages, %s)", len(history), question); err != nil {
+		return nil, err
+	}
+	input := len(question)
+	for _, msg := range history {
+		input += len(msg.Text)
+	}
+	text := fmt.Sprintf("You asked %q after %d messages.", question, len(history))
+	return &Reply{Text: text, Model: FakeModel, Usage: fakeUsage(input, len(text))}, nil
+}
+
+// AskStream delivers the answer of Ask word by word.
+func (f *Fake) AskStream(ctx context.Context, history []Message, question string, onText func(string) error) (*Reply, error) {
+	reply, err := f.Ask(ctx, history, question)
+	if err != nil {
+		return nil, err
+	}
+	words := strings.SplitAfter(reply.Text, " ")
+	for _, word := range words {
+		if err := onText(word); err != nil {
+			return nil, err
+		}
+	}
+	return reply, nil
+}
+
+func fakeIdentification(names []string) *Identification {
+	id := &Identification{Model: FakeModel}
+	for i, name := range names {
+		confidence := 0.95
+		if len(names) > 1 {
+			confidence = max(0.6-0.1*float64(i), 0.05)
+		}
+		id.Candidates = append(id.Candidates, Candidate{Name: name, Confidence: confidence})
+	}
+	return id
+}
+
+func fakeUsage(inputBytes, outputBytes int) Usage {
+	return Usage{InputTokens: (inputBytes + 3) / 4, OutputTokens: (outputBytes + 3) / 4}
+}
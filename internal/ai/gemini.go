@@ -0,0 +1,260 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+const DefaultGeminiModel = "gemini-1.5-flash"
+
+const geminiPromptIdentifyFromImage = "Identify the bird in this image. List up to 3 likely species, most likely first, by their most common English name and scientific name. Give each a confidence between 0 and 1 and the field marks visible in the image that support or distinguish it. If you are very confident about one species, list only that one. If there is no bird in the image, return an empty candidates list."
+const geminiPromptIdentifyFromMedia = "Identify the bird in these photos and sound recordings. They are all of the same bird, so weigh them together. List up to 3 likely species, most likely first, by their most common English name and scientific name. Give each a confidence between 0 and 1 and the field marks seen or heard, such as plumage, shape, song or calls, that support or distinguish it. If you are very confident about one species, list only that one. If there is no bird in any of them, return an empty candidates list."
+const geminiPromptExtractNameFromText = "You are an expert ornithologist and polyglot. Your task is to extract the bird name from the user's text.\n- If the name is specific (e.g., 'Blue Jay', 'Họa mi'), return only that species by its common English name and scientific name (e.g., 'Chinese Hwamei', 'Garrulax canorus') with a high confidence.\n- If the name is ambiguous (e.g., 'sparrow', 'chim sẻ'), return up to 5 likely species, most likely first, each with a confidence between 0 and 1.\n- For each species, list the field marks that tell it apart from the others.\n- If you cannot identify a bird (e.g., the text is 'con mèo' or 'what is the weather?'), return an empty candidates list.\n\nUser text: \"%s\""
+const geminiPromptDescribe = "Tell me about the %s."
+const geminiPromptLanguage = " Answer in %s."
+
+const geminiEmptyResponse = "Sorry, I could not generate a response."
+
+func init() {
+	Register("gemini", func(ctx context.Context, cfg Config) (Provider, error) {
+		return NewGemini(ctx, cfg.APIKey, cfg.IdentifyModel, cfg.ChatModel)
+	})
+}
+
+// Gemini is a provider backed by the Gemini API. It holds one client for
+// the lifetime of the application.
+type Gemini struct {
+	client        *genai.Client
+	identifyModel string
+	chatModel     string
+}
+
+// NewGemini connects to Gemini. Empty model names use DefaultGeminiModel.
+func NewGemini(ctx context.Context, apiKey, identifyModel, chatModel string) (*Gemini, error) {
+	if apiKey == "" {
+		return nil, errors.New("gemini API key is not set")
+	}
+	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create genai client: %w", err)
+	}
+	if identifyModel == "" {
+		identifyModel = DefaultGeminiModel
+	}
+	if chatModel == "" {
+		chatModel = DefaultGeminiModel
+	}
+	return &Gemini{client: client, identifyModel: identifyModel, chatModel: chatModel}, nil
+}
+
+func (g *Gemini) Close() error {
+	return g.client.Close()
+}
+
+func (g *Gemini) IdentifyMedia(ctx context.Context, media []Media) (*Identification, error) {
+	if len(media) == 0 {
+		return nil, errors.New("no media to identify")
+	}
+	prompt := geminiPromptIdentifyFromMedia
+	if len(media) == 1 && !media[0].IsAudio() {
+		prompt = geminiPromptIdentifyFromImage
+	}
+	parts := make([]genai.Part, 0, len(media)+1)
+	for _, m := range media {
+		parts = append(parts, genai.Blob{MIMEType: geminiMIMEType(m.MIMEType), Data: m.Data})
+	}
+	return g.identify(ctx, append(parts, genai.Text(prompt))...)
+}
+
+// geminiMIMEType names a media type the way Gemini expects it; Gemini
+// takes MP3 as audio/mp3 and AAC in an MP4 (.m4a) container as audio/aac.
+func geminiMIMEType(mimeType string) string {
+	switch mimeType {
+	case "audio/mpeg":
+		return "audio/mp3"
+	case "audio/mp4":
+		return "audio/aac"
+	}
+	return mimeType
+}
+
+func (g *Gemini) IdentifyText(ctx context.Context, text string) (*Identification, error) {
+	return g.identify(ctx, genai.Text(fmt.Sprintf(geminiPromptExtractNameFromText, text)))
+}
+
+// geminiIdentificationSchema is the JSON the identification prompts answer
+// with.
+var geminiIdentificationSchema = &genai.Schema{
+	Type: genai.TypeObject,
+	Properties: map[string]*genai.Schema{
+		"candidates": {
+			Type: genai.TypeArray,
+			Items: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"common_name":     {Type: genai.TypeString, Description: "Most common English name"},
+					"scientific_name": {Type: genai.TypeString},
+					"confidence":      {Type: genai.TypeNumber, Description: "Between 0 and 1"},
+					"field_marks":     {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}},
+				},
+				Required: []string{"common_name", "scientific_name", "confidence"},
+			},
+		},
+	},
+	Required: []string{"candidates"},
+}
+
+type geminiIdentification struct {
+	Candidates []struct {
+		CommonName     string   `json:"common_name"`
+		ScientificName string   `json:"scientific_name"`
+		Confidence     float64  `json:"confidence"`
+		FieldMarks     []string `json:"field_marks"`
+	} `json:"candidates"`
+}
+
+func (g *Gemini) identify(ctx context.Context, parts ...genai.Part) (*Identification, error) {
+	model := g.client.GenerativeModel(g.identifyModel)
+	model.ResponseMIMEType = "application/json"
+	model.ResponseSchema = geminiIdentificationSchema
+
+	resp, err := model.GenerateContent(ctx, parts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate content from Gemini: %w", err)
+	}
+
+	var answer geminiIdentification
+	if err := json.Unmarshal([]byte(responseText(resp)), &answer); err != nil {
+		return nil, fmt.Errorf("failed to decode Gemini identification: %w", err)
+	}
+
+	id := &Identification{Model: g.identifyModel, Usage: responseUsage(resp)}
+	for _, c := range answer.Candidates {
+		name := strings.TrimSpace(c.CommonName)
+		if name == "" {
+			continue
+		}
+		id.Candidates = append(id.Candidates, Candidate{
+			Name:           name,
+			ScientificName: strings.TrimSpace(c.ScientificName),
+			Confidence:     min(max(c.Confidence, 0), 1),
+			FieldMarks:     c.FieldMarks,
+		})
+	}
+	id.sortCandidates()
+	return id, nil
+}
+
+func (g *Gemini) Describe(ctx context.Context, name, language string) (*Reply, error) {
+	prompt := fmt.Sprintf(geminiPromptDescribe, name)
+	if language != "" && language != "en" {
+		prompt += fmt.Sprintf(geminiPromptLanguage, Languages[language])
+	}
+	resp, err := g.client.GenerativeModel(g.chatModel).GenerateContent(ctx, genai.Text(prompt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat response for '%s': %w", name, err)
+	}
+	// Descriptions are kept, so an empty one is an error rather than an
+	// apology.
+	text := responseText(resp)
+	if text == "" {
+		return nil, fmt.Errorf("empty description for '%s'", name)
+	}
+	return &Reply{Text: text, Model: g.chatModel, Usage: responseUsage(resp)}, nil
+}
+
+func (g *Gemini) Ask(ctx context.Context, history []Message, question string) (*Reply, error) {
+	resp, err := g.startChat(history).SendMessage(ctx, genai.Text(question))
+	if err != nil {
+		return nil, fmt.Errorf("failed to send message to Gemini: %w", err)
+	}
+	return &Reply{Text: textOrApology(resp), Model: g.chatModel, Usage: responseUsage(resp)}, nil
+}
+
+func responseText(resp *genai.GenerateContentResponse) string {
+	var b strings.Builder
+	if resp != nil && len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
+		for _, part := range resp.Candidates[0].Content.Parts {
+			if txt, ok := part.(genai.Text); ok {
+				b.WriteString(string(txt))
+			}
+		}
+	}
+	return b.String()
+}
+
+func (g *Gemini) AskStream(ctx context.Context, history []Message, question string, onText func(string) error) (*Reply, error) {
+	iter := g.startChat(history).SendMessageStream(ctx, genai.Text(question))
+
+	var text strings.Builder
+	var usage Usage
+	for {
+		resp, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to stream message from Gemini: %w", err)
+		}
+		// Every chunk carries the usage so far.
+		if chunkUsage := responseUsage(resp); chunkUsage != (Usage{}) {
+			usage = chunkUsage
+		}
+		chunk := responseText(resp)
+		if chunk == "" {
+			continue
+		}
+		text.WriteString(chunk)
+		if err := onText(chunk); err != nil {
+			return nil, err
+		}
+	}
+
+	if text.Len() == 0 {
+		slog.Warn("Gemini response was empty or contained no text parts")
+		if err := onText(geminiEmptyResponse); err != nil {
+			return nil, err
+		}
+		text.WriteString(geminiEmptyResponse)
+	}
+	return &Reply{Text: text.String(), Model: g.chatModel, Usage: usage}, nil
+}
+
+func (g *Gemini) startChat(history []Message) *genai.ChatSession {
+	chat := g.client.GenerativeModel(g.chatModel).StartChat()
+	chat.History = make([]*genai.Content, 0, len(history))
+	for _, msg := range history {
+		chat.History = append(chat.History, &genai.Content{
+			Role:  msg.Role,
+			Parts: []genai.Part{genai.Text(msg.Text)},
+		})
+	}
+	return chat
+}
+
+func responseUsage(resp *genai.GenerateContentResponse) Usage {
+	if resp == nil || resp.UsageMetadata == nil {
+		return Usage{}
+	}
+	return Usage{
+		InputTokens:  int(resp.UsageMetadata.PromptTokenCount),
+		OutputTokens: int(resp.UsageMetadata.CandidatesTokenCount),
+	}
+}
+
+func textOrApology(resp *genai.GenerateContentResponse) string {
+	text := responseText(resp)
+	if text == "" {
+		slog.Warn("Gemini response was empty or contained no text parts")
+		return geminiEmptyResponse
+	}
+	return text
+}
@@ -0,0 +1,125 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Config is handed to every provider factory. Providers read the fields
+// they need and ignore the rest.
+type Config struct {
+	APIKey        string
+	IdentifyModel string
+	ChatModel     string
+	Endpoint      string // base URL of self-hosted providers
+}
+
+// Provider is an opened backend. It implements Identifier, Assistant or
+// both.
+type Provider interface {
+	Close() error
+}
+
+// Factory opens a provider.
+type Factory func(ctx context.Context, cfg Config) (Provider, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// Register makes a provider available under name. It is meant to be called
+// from init and panics when name is taken.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; ok {
+		panic("ai: provider " + name + " registered twice")
+	}
+	registry[name] = factory
+}
+
+// Providers returns the registered provider names, sorted.
+func Providers() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Service routes identification and conversation to the configured
+// providers.
+type Service struct {
+	Identifier
+	Assistant
+
+	providers []Provider
+}
+
+// Open opens the providers named for identification and for the assistant.
+// A provider named for both is opened once and serves both.
+func Open(ctx context.Context, identifier, assistant string, cfg Config) (*Service, error) {
+	s := &Service{}
+	opened := map[string]Provider{}
+	open := func(name string) (Provider, error) {
+		if p, ok := opened[name]; ok {
+			return p, nil
+		}
+		registryMu.RLock()
+		factory, ok := registry[name]
+		registryMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("ai: unknown provider %q (registered: %v)", name, Providers())
+		}
+		p, err := factory(ctx, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("ai: open provider %q: %w", name, err)
+		}
+		opened[name] = p
+		s.providers = append(s.providers, p)
+		return p, nil
+	}
+
+	p, err := open(identifier)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	id, ok := p.(Identifier)
+	if !ok {
+		s.Close()
+		return nil, fmt.Errorf("ai: provider %q cannot identify birds", identifier)
+	}
+	s.Identifier = id
+
+	p, err = open(assistant)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	as, ok := p.(Assistant)
+	if !ok {
+		s.Close()
+		return nil, fmt.Errorf("ai: provider %q cannot answer questions", assistant)
+	}
+	s.Assistant = as
+
+	return s, nil
+}
+
+// Close closes the opened providers.
+func (s *Service) Close() error {
+	var errs []error
+	for _, p := range s.providers {
+		errs = append(errs, p.Close())
+	}
+	s.providers = nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,145 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// closeOnly is a provider that can do nothing but be closed.
+type closeOnly struct {
+	closed bool
+}
+
+func (p *closeOnly) Close() error {
+	p.closed = true
+	return nil
+}
+
+func TestOpen(t *testing.T) {
+	s, err := Open(context.Background(), "fake", "fake", Config{})
+	if err != nil {
+		t.Fatalf("Open(fake, fake) error = %v", err)
+	}
+	defer s.Close()
+	if s.Identifier.(*Fake) != s.Assistant.(*Fake) {
+		t.Error("Open(fake, fake) opened the fake twice, want one provider serving both")
+	}
+	if len(s.providers) != 1 {
+		t.Errorf("providers = %d, want 1", len(s.providers))
+	}
+
+	s, err = Open(context.Background(), "classifier", "fake", Config{Endpoint: "http://classifier.invalid"})
+	if err != nil {
+		t.Fatalf("Open(classifier, fake) error = %v", err)
+	}
+	defer s.Close()
+	if _, ok := s.Identifier.(*Classifier); !ok {
+		t.Errorf("Identifier = %T, want *Classifier", s.Identifier)
+	}
+	if _, ok := s.Assistant.(*Fake); !ok {
+		t.Errorf("Assistant = %T, want *Fake", s.Assistant)
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	if _, err := Open(context.Background(), "nope", "fake", Config{}); err == nil || !strings.Contains(err.Error(), `unknown provider "nope"`) {
+		t.Errorf("Open(nope) error = %v, want an unknown provider error", err)
+	}
+	if _, err := Open(context.Background(), "classifier", "fake", Config{}); err == nil || !strings.Contains(err.Error(), "endpoint is not set") {
+		t.Errorf("Open(classifier) without an endpoint error = %v, want the factory's error", err)
+	}
+
+	p := &closeOnly{}
+	Register("test-close-only", func(ctx context.Context, cfg Config) (Provider, error) {
+		return p, nil
+	})
+	if _, err := Open(context.Background(), "test-close-only", "fake", Config{}); err == nil || !strings.Contains(err.Error(), "cannot identify birds") {
+		t.Errorf("Open(test-close-only) error = %v, want a cannot identify error", err)
+	}
+	if !p.closed {
+		t.Error("the provider that cannot identify was not closed")
+	}
+
+	p.closed = false
+	if _, err := Open(context.Background(), "fake", "test-close-only", Config{}); err == nil || !strings.Contains(err.Error(), "cannot answer questions") {
+		t.Errorf("Open(fake, test-close-only) error = %v, want a cannot answer error", err)
+	}
+	if !p.closed {
+		t.Error("the provider that cannot answer was not closed")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	for _, name := range []string{"classifier", "fake", "gemini"} {
+		if !slices.Contains(Providers(), name) {
+			t.Errorf("Providers() = %v, want it to include %q", Providers(), name)
+		}
+	}
+	if !slices.IsSorted(Providers()) {
+		t.Errorf("Providers() = %v, want them sorted", Providers())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering fake twice did not panic")
+		}
+	}()
+	Register("fake", func(ctx context.Context, cfg Config) (Provider, error) {
+		return NewFake(), nil
+	})
+}
+
+// askOnly hides the streaming of the assistant it wraps.
+type askOnly struct {
+	Assistant
+}
+
+func TestAskStream(t *testing.T) {
+	fake := NewFake()
+	history := []Message{{Role: "user", Text: "hi"}, {Role: "model", Text: "hello"}}
+
+	var pieces []string
+	reply, err := AskStream(context.Background(), fake, history, "what is a jay?", func(text string) error {
+		pieces = append(pieces, text)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("AskStream() error = %v", err)
+	}
+	if len(pieces) < 2 {
+		t.Errorf("AskStream() delivered %d pieces, want the answer word by word", len(pieces))
+	}
+	if got := strings.Join(pieces, ""); got != reply.Text {
+		t.Errorf("streamed %q, want the reply %q", got, reply.Text)
+	}
+	if reply.Model != FakeModel || reply.Usage.InputTokens == 0 {
+		t.Errorf("AskStream() reply = %+v, want the fake model and its usage", reply)
+	}
+
+	// A provider without streaming answers in one piece.
+	pieces = nil
+	reply, err = AskStream(context.Background(), askOnly{NewFake()}, history, "what is a jay?", func(text string) error {
+		pieces = append(pieces, text)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("AskStream() without streaming error = %v", err)
+	}
+	if len(pieces) != 1 || pieces[0] != reply.Text {
+		t.Errorf("AskStream() without streaming delivered %q, want the reply %q in one piece", pieces, reply.Text)
+	}
+
+	stop := errors.New("client went away")
+	_, err = AskStream(context.Background(), fake, nil, "what is a jay?", func(string) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Errorf("AskStream() error = %v, want the callback's error", err)
+	}
+
+	fake.Err = errors.New("model unavailable")
+	if _, err := AskStream(context.Background(), fake, nil, "what is a jay?", func(string) error { return nil }); !errors.Is(err, fake.Err) {
+		t.Errorf("AskStream() error = %v, want the provider's error", err)
+	}
+}
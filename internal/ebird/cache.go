@@ -0,0 +1,175 @@
+package ebird
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Cache lifetimes of CachingClient. Hotspots and their metadata rarely
+// change; recent sightings are kept briefly so the map stays fresh.
+const (
+	DefaultHotspotTTL      = 24 * time.Hour
+	DefaultObservationsTTL = 15 * time.Minute
+
+	cachePurgeInterval = 10 * time.Minute
+
+	// cacheFetchTimeout bounds a call to eBird shared by concurrent misses.
+	// The call does not end with the request that started it, so that the
+	// others sharing it still get its result.
+	cacheFetchTimeout = 30 * time.Second
+)
+
+// CachingClient is a Client that keeps the responses of the hotspot and
+// recent observation endpoints in memory. Concurrent misses for the same
+// request share one call to eBird. Errors are not cached. Historic
+// observations and the taxonomy are passed through, as their callers store
+// them themselves.
+type CachingClient struct {
+	client          Client
+	hotspotTTL      time.Duration
+	observationsTTL time.Duration
+
+	group      singleflight.Group
+	mu         sync.Mutex
+	entries    map[string]cacheEntry
+	lastPurged time.Time
+}
+
+type cacheEntry struct {
+	value     any
+	expiresAt time.Time
+}
+
+type CacheOption func(*CachingClient)
+
+// WithCacheTTL sets how long hotspots and hotspot metadata, and recent and
+// notable observations, are kept.
+func WithCacheTTL(hotspots, observations time.Duration) CacheOption {
+	return func(c *CachingClient) {
+		c.hotspotTTL = hotspots
+		c.observationsTTL = observations
+	}
+}
+
+func NewCachingClient(client Client, opts ...CacheOption) *CachingClient {
+	c := &CachingClient{
+		client:          client,
+		hotspotTTL:      DefaultHotspotTTL,
+		observationsTTL: DefaultObservationsTTL,
+		entries:         map[string]cacheEntry{},
+		lastPurged:      time.Now(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *CachingClient) RecentObservations(ctx context.Context, regionCode string, opts ObservationOptions) ([]Observation, error) {
+	key := fmt.Sprintf("recent/%s/%d/%d", regionCode, opts.Back, opts.MaxResults)
+	return cached(ctx, c, key, c.observationsTTL, func(ctx context.Context) ([]Observation, error) {
+		return c.client.RecentObservations(ctx, regionCode, opts)
+	})
+}
+
+func (c *CachingClient) NotableObservations(ctx context.Context, regionCode string, opts ObservationOptions) ([]Observation, error) {
+	key := fmt.Sprintf("notable/%s/%d/%d", regionCode, opts.Back, opts.MaxResults)
+	return cached(ctx, c, key, c.observationsTTL, func(ctx context.Context) ([]Observation, error) {
+		return c.client.NotableObservations(ctx, regionCode, opts)
+	})
+}
+
+func (c *CachingClient) NearbyObservations(ctx context.Context, lat, lng float64, distKm int, opts ObservationOptions) ([]Observation, error) {
+	key := fmt.Sprintf("nearby/%g/%g/%d/%d/%d", lat, lng, distKm, opts.Back, opts.MaxResults)
+	return cached(ctx, c, key, c.observationsTTL, func(ctx context.Context) ([]Observation, error) {
+		return c.client.NearbyObservations(ctx, lat, lng, distKm, opts)
+	})
+}
+
+func (c *CachingClient) NearbyNotableObservations(ctx context.Context, lat, lng float64, distKm int, opts ObservationOptions) ([]Observation, error) {
+	key := fmt.Sprintf("nearby-notable/%g/%g/%d/%d/%d", lat, lng, distKm, opts.Back, opts.MaxResults)
+	return cached(ctx, c, key, c.observationsTTL, func(ctx context.Context) ([]Observation, error) {
+		return c.client.NearbyNotableObservations(ctx, lat, lng, distKm, opts)
+	})
+}
+
+func (c *CachingClient) NearbyHotspots(ctx context.Context, lat, lng float64, distKm int) ([]Hotspot, error) {
+	key := fmt.Sprintf("hotspots/%g/%g/%d", lat, lng, distKm)
+	return cached(ctx, c, key, c.hotspotTTL, func(ctx context.Context) ([]Hotspot, error) {
+		return c.client.NearbyHotspots(ctx, lat, lng, distKm)
+	})
+}
+
+func (c *CachingClient) HotspotInfo(ctx context.Context, locID string) (*HotspotInfo, error) {
+	return cached(ctx, c, "info/"+locID, c.hotspotTTL, func(ctx context.Context) (*HotspotInfo, error) {
+		return c.client.HotspotInfo(ctx, locID)
+	})
+}
+
+func (c *CachingClient) HistoricObservations(ctx context.Context, regionCode string, date time.Time) ([]Observation, error) {
+	return c.client.HistoricObservations(ctx, regionCode, date)
+}
+
+func (c *CachingClient) Taxonomy(ctx context.Context, locale string) ([]Taxon, error) {
+	return c.client.Taxonomy(ctx, locale)
+}
+
+// cached returns the unexpired value under key, or calls fetch and keeps its
+// result for ttl. Callers share cached values and must not modify them.
+// fetch runs detached from ctx, which only stops the caller from waiting
+// for it.
+func cached[T any](ctx context.Context, c *CachingClient, key string, ttl time.Duration, fetch func(ctx context.Context) (T, error)) (T, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.value.(T), nil
+	}
+
+	ch := c.group.DoChan(key, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cacheFetchTimeout)
+		defer cancel()
+		value, err := fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.entries[key] = cacheEntry{value: value, expiresAt: time.Now().Add(ttl)}
+		c.purgeExpired()
+		return value, nil
+	})
+	var zero T
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		return res.Val.(T), nil
+	}
+}
+
+// purgeExpired drops expired entries at most every cachePurgeInterval, so
+// requests for many different places do not grow the cache forever. c.mu
+// must be held.
+func (c *CachingClient) purgeExpired() {
+	now := time.Now()
+	if now.Sub(c.lastPurged) < cachePurgeInterval {
+		return
+	}
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+	c.lastPurged = now
+}
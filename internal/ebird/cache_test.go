@@ -0,0 +1,73 @@
+package ebird
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCachingClientSharesFetch(t *testing.T) {
+	fake := NewFakeServer()
+	defer fake.Close()
+	fake.SetRecent("L123", []Observation{{SpeciesCode: "houspa", LocID: "L123"}})
+	c := NewCachingClient(fake.Client())
+
+	for range 2 {
+		observations, err := c.RecentObservations(context.Background(), "L123", ObservationOptions{})
+		if err != nil || len(observations) != 1 {
+			t.Fatalf("RecentObservations() = %v, %v, want the house sparrow", observations, err)
+		}
+	}
+	if got := fake.Requests(); got != 1 {
+		t.Errorf("requests = %d, want 1: the second call is cached", got)
+	}
+}
+
+func TestCachingClientFetchOutlivesCaller(t *testing.T) {
+	var requests atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		w.Write([]byte(`[{"locId": "L123", "locName": "Central Park"}]`))
+	}))
+	defer server.Close()
+	c := NewCachingClient(NewClient("key", WithBaseURL(server.URL)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.NearbyHotspots(ctx, 40.78, -73.97, 5)
+		first <- err
+	}()
+	<-started
+
+	second := make(chan error, 1)
+	go func() {
+		hotspots, err := c.NearbyHotspots(context.Background(), 40.78, -73.97, 5)
+		if err == nil && len(hotspots) != 1 {
+			err = errors.New("want one hotspot")
+		}
+		second <- err
+	}()
+
+	// The first caller going away ends its wait, not the shared fetch.
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("NearbyHotspots() of the cancelled caller error = %v, want context.Canceled", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	if err := <-second; err != nil {
+		t.Errorf("NearbyHotspots() of the waiting caller error = %v", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("requests = %d, want 1 shared by both callers", got)
+	}
+}
@@ -0,0 +1,407 @@
+package ebird
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	DefaultBaseURL = "https://api.ebird.org/v2"
+
+	// eBird does not publish its limits; these keep a year of historic
+	// fetches well under what it tolerates.
+	DefaultRateLimit  = 10 // requests per second
+	DefaultRateBurst  = 10
+	DefaultMaxRetries = 3
+	DefaultRetryDelay = 500 * time.Millisecond
+
+	maxRetryDelay = 10 * time.Second
+)
+
+// ErrNotFound is matched by the error of a request that eBird answered with 404.
+var ErrNotFound = errors.New("ebird: not found")
+
+// APIError is a non-2xx response from eBird.
+type APIError struct {
+	StatusCode int
+	Path       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("ebird: %s returned status %d", e.Path, e.StatusCode)
+}
+
+func (e *APIError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// Observation is an entry of the eBird observation endpoints. HowMany is nil
+// when the species was reported as present without a count ("X").
+type Observation struct {
+	SpeciesCode     string  `json:"speciesCode"`
+	CommonName      string  `json:"comName"`
+	ScientificName  string  `json:"sciName"`
+	LocID           string  `json:"locId"`
+	LocName         string  `json:"locName"`
+	ObsDt           string  `json:"obsDt"` // "2006-01-02 15:04", or "2006-01-02" without a time
+	HowMany         *int    `json:"howMany,omitempty"`
+	Lat             float64 `json:"lat"`
+	Lng             float64 `json:"lng"`
+	ObsValid        bool    `json:"obsValid"`
+	ObsReviewed     bool    `json:"obsReviewed"`
+	LocationPrivate bool    `json:"locationPrivate"`
+	SubID           string  `json:"subId,omitempty"`
+}
+
+// Hotspot is an entry of the hotspots-near-a-point endpoint.
+type Hotspot struct {
+	LocID             string  `json:"locId"`
+	LocName           string  `json:"locName"`
+	CountryCode       string  `json:"countryCode"`
+	Subnational1Code  string  `json:"subnational1Code"`
+	Subnational2Code  string  `json:"subnational2Code,omitempty"`
+	Lat               float64 `json:"lat"`
+	Lng               float64 `json:"lng"`
+	LatestObsDt       string  `json:"latestObsDt,omitempty"`
+	NumSpeciesAllTime int     `json:"numSpeciesAllTime,omitempty"`
+}
+
+// HotspotInfo is the metadata eBird keeps about a hotspot.
+type HotspotInfo struct {
+	LocID            string  `json:"locId"`
+	Name             string  `json:"name"`
+	Lat              float64 `json:"latitude"`
+	Lng              float64 `json:"longitude"`
+	CountryCode      string  `json:"countryCode"`
+	CountryName      string  `json:"countryName"`
+	Subnational1Code string  `json:"subnational1Code"`
+	Subnational1Name string  `json:"subnational1Name"`
+	Subnational2Code string  `json:"subnational2Code,omitempty"`
+	Subnational2Name string  `json:"subnational2Name,omitempty"`
+	HierarchicalName string  `json:"hierarchicalName"`
+	IsHotspot        bool    `json:"isHotspot"`
+}
+
+// ObservationOptions narrows the recent and notable observation endpoints.
+// Zero values use eBird's defaults.
+type ObservationOptions struct {
+	Back       int // days back, 1-30; eBird defaults to 14
+	MaxResults int
+}
+
+// Client is the part of the eBird API 2.0 the app uses. APIClient implements
+// it; tests can use FakeServer or their own implementation.
+type Client interface {
+	// RecentObservations returns the latest observation of each species in a
+	// region (a country, subnational or location code such as L123456).
+	RecentObservations(ctx context.Context, regionCode string, opts ObservationOptions) ([]Observation, error)
+	// NotableObservations returns recent rare or unusual observations in a region.
+	NotableObservations(ctx context.Context, regionCode string, opts ObservationOptions) ([]Observation, error)
+	// NearbyObservations returns the latest observation of each species
+	// within distKm (at most 50) of a point.
+	NearbyObservations(ctx context.Context, lat, lng float64, distKm int, opts ObservationOptions) ([]Observation, error)
+	// NearbyNotableObservations returns recent rare or unusual observations
+	// within distKm (at most 50) of a point.
+	NearbyNotableObservations(ctx context.Context, lat, lng float64, distKm int, opts ObservationOptions) ([]Observation, error)
+	// NearbyHotspots returns the hotspots within distKm (at most 500) of a point.
+	NearbyHotspots(ctx context.Context, lat, lng float64, distKm int) ([]Hotspot, error)
+	// HotspotInfo returns a hotspot's metadata, or an error matching ErrNotFound.
+	HotspotInfo(ctx context.Context, locID string) (*HotspotInfo, error)
+	// HistoricObservations returns a region's observations on one day. A day
+	// without data is returned as an empty slice.
+	HistoricObservations(ctx context.Context, regionCode string, date time.Time) ([]Observation, error)
+	// Taxonomy returns the eBird taxonomy, with common names in locale when set.
+	Taxonomy(ctx context.Context, locale string) ([]Taxon, error)
+}
+
+// APIClient calls the eBird API with token-bucket rate limiting and retries
+// with exponential backoff on network errors, 429 and 5xx responses.
+type APIClient struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+	limiter    *rate.Limiter
+	maxRetries int
+	retryDelay time.Duration
+}
+
+type Option func(*APIClient)
+
+// WithBaseURL points the client at another server, such as a FakeServer.
+func WithBaseURL(baseURL string) Option {
+	return func(c *APIClient) { c.baseURL = strings.TrimSuffix(baseURL, "/") }
+}
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *APIClient) { c.httpClient = httpClient }
+}
+
+// WithRateLimit allows perSecond requests on average with bursts of burst.
+func WithRateLimit(perSecond float64, burst int) Option {
+	return func(c *APIClient) { c.limiter = rate.NewLimiter(rate.Limit(perSecond), burst) }
+}
+
+// WithRetries sets how many times a failed request is retried and the delay
+// before the first retry, which doubles on each further attempt.
+func WithRetries(maxRetries int, delay time.Duration) Option {
+	return func(c *APIClient) {
+		c.maxRetries = maxRetries
+		c.retryDelay = delay
+	}
+}
+
+func NewClient(apiKey string, opts ...Option) *APIClient {
+	c := &APIClient{
+		apiKey:     apiKey,
+		baseURL:    DefaultBaseURL,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+		limiter:    rate.NewLimiter(DefaultRateLimit, DefaultRateBurst),
+		maxRetries: DefaultMaxRetries,
+		retryDelay: DefaultRetryDelay,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *APIClient) RecentObservations(ctx context.Context, regionCode string, opts ObservationOptions) ([]Observation, error) {
+	var observations []Observation
+	err := c.get(ctx, "/data/obs/"+url.PathEscape(regionCode)+"/recent", opts.values(), &observations)
+	return observations, err
+}
+
+func (c *APIClient) NotableObservations(ctx context.Context, regionCode string, opts ObservationOptions) ([]Observation, error) {
+	var observations []Observation
+	err := c.get(ctx, "/data/obs/"+url.PathEscape(regionCode)+"/recent/notable", opts.values(), &observations)
+	return observations, err
+}
+
+func (c *APIClient) NearbyObservations(ctx context.Context, lat, lng float64, distKm int, opts ObservationOptions) ([]Observation, error) {
+	var observations []Observation
+	err := c.get(ctx, "/data/obs/geo/recent", geoValues(lat, lng, distKm, opts), &observations)
+	return observations, err
+}
+
+func (c *APIClient) NearbyNotableObservations(ctx context.Context, lat, lng float64, distKm int, opts ObservationOptions) ([]Observation, error) {
+	var observations []Observation
+	err := c.get(ctx, "/data/obs/geo/recent/notable", geoValues(lat, lng, distKm, opts), &observations)
+	return observations, err
+}
+
+func (c *APIClient) NearbyHotspots(ctx context.Context, lat, lng float64, distKm int) ([]Hotspot, error) {
+	query := url.Values{}
+	query.Set("lat", strconv.FormatFloat(lat, 'f', -1, 64))
+	query.Set("lng", strconv.FormatFloat(lng, 'f', -1, 64))
+	query.Set("fmt", "json")
+	if distKm > 0 {
+		query.Set("dist", strconv.Itoa(distKm))
+	}
+
+	var hotspots []Hotspot
+	err := c.get(ctx, "/ref/hotspot/geo", query, &hotspots)
+	return hotspots, err
+}
+
+func (c *APIClient) HotspotInfo(ctx context.Context, locID string) (*HotspotInfo, error) {
+	var info HotspotInfo
+	if err := c.get(ctx, "/ref/hotspot/info/"+url.PathEscape(locID), nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *APIClient) HistoricObservations(ctx context.Context, regionCode string, date time.Time) ([]Observation, error) {
+	path := fmt.Sprintf("/data/obs/%s/historic/%d/%d/%d", url.PathEscape(regionCode), date.Year(), date.Month(), date.Day())
+
+	observations := []Observation{}
+	err := c.get(ctx, path, nil, &observations)
+	if errors.Is(err, ErrNotFound) {
+		return []Observation{}, nil
+	}
+	return observations, err
+}
+
+func (c *APIClient) Taxonomy(ctx context.Context, locale string) ([]Taxon, error) {
+	query := url.Values{}
+	query.Set("fmt", "json")
+	if locale != "" {
+		query.Set("locale", locale)
+	}
+
+	var entries []taxonomyEntry
+	if err := c.get(ctx, "/ref/taxonomy/ebird", query, &entries); err != nil {
+		return nil, err
+	}
+
+	taxa := make([]Taxon, 0, len(entries))
+	for _, entry := range entries {
+		taxa = append(taxa, entry.taxon())
+	}
+	return taxa, nil
+}
+
+// taxonomyEntry is a row of the JSON taxonomy endpoint.
+type taxonomyEntry struct {
+	ScientificName string  `json:"sciName"`
+	CommonName     string  `json:"comName"`
+	SpeciesCode    string  `json:"speciesCode"`
+	Category       string  `json:"category"`
+	TaxonOrder     float64 `json:"taxonOrder"`
+	Order          string  `json:"order,omitempty"`
+	FamilyCode     string  `json:"familyCode,omitempty"`
+	FamilyComName  string  `json:"familyComName,omitempty"`
+	FamilySciName  string  `json:"familySciName,omitempty"`
+	ReportAs       string  `json:"reportAs,omitempty"`
+	Extinct        bool    `json:"extinct,omitempty"`
+}
+
+func (e taxonomyEntry) taxon() Taxon {
+	return Taxon{
+		SpeciesCode:          e.SpeciesCode,
+		CommonName:           e.CommonName,
+		ScientificName:       e.ScientificName,
+		Category:             strings.ToLower(e.Category),
+		TaxonOrder:           e.TaxonOrder,
+		Order:                e.Order,
+		FamilyCode:           e.FamilyCode,
+		FamilyCommonName:     e.FamilyComName,
+		FamilyScientificName: e.FamilySciName,
+		ReportAs:             e.ReportAs,
+		Extinct:              e.Extinct,
+	}
+}
+
+func (o ObservationOptions) values() url.Values {
+	query := url.Values{}
+	if o.Back > 0 {
+		query.Set("back", strconv.Itoa(o.Back))
+	}
+	if o.MaxResults > 0 {
+		query.Set("maxResults", strconv.Itoa(o.MaxResults))
+	}
+	return query
+}
+
+func geoValues(lat, lng float64, distKm int, opts ObservationOptions) url.Values {
+	query := opts.values()
+	query.Set("lat", strconv.FormatFloat(lat, 'f', -1, 64))
+	query.Set("lng", strconv.FormatFloat(lng, 'f', -1, 64))
+	if distKm > 0 {
+		query.Set("dist", strconv.Itoa(distKm))
+	}
+	return query
+}
+
+// get fetches path and decodes the JSON response into dst, retrying
+// transient failures. Every attempt waits for the rate limiter.
+func (c *APIClient) get(ctx context.Context, path string, query url.Values, dst any) error {
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, c.backoff(attempt, lastErr)); err != nil {
+				return err
+			}
+		}
+		if err := c.limiter.Wait(ctx); err != nil {
+			return err
+		}
+
+		retry, err := c.do(ctx, endpoint, path, dst)
+		if err == nil {
+			return nil
+		}
+		if !retry || ctx.Err() != nil {
+			return err
+		}
+		lastErr = err
+	}
+	return lastErr
+}
+
+// do makes one request and reports whether a failure is worth retrying.
+func (c *APIClient) do(ctx context.Context, endpoint, path string, dst any) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("X-eBirdApiToken", c.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("ebird: %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &retryableError{APIError: &APIError{StatusCode: resp.StatusCode, Path: path}}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			apiErr.retryAfter = time.Duration(seconds) * time.Second
+		}
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+		if !retryable {
+			return false, apiErr.APIError
+		}
+		return true, apiErr
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+		return false, fmt.Errorf("ebird: decoding %s: %w", path, err)
+	}
+	return false, nil
+}
+
+// retryableError carries the server's Retry-After hint alongside the APIError.
+type retryableError struct {
+	*APIError
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Unwrap() error { return e.APIError }
+
+// backoff doubles the retry delay on each attempt, with up to 50% jitter so
+// parallel workers do not retry in lockstep, and honours Retry-After.
+func (c *APIClient) backoff(attempt int, lastErr error) time.Duration {
+	if c.retryDelay <= 0 {
+		return 0
+	}
+	delay := min(c.retryDelay<<(attempt-1), maxRetryDelay)
+	delay += time.Duration(rand.Int64N(int64(delay)/2 + 1))
+
+	var retryErr *retryableError
+	if errors.As(lastErr, &retryErr) && retryErr.retryAfter > delay {
+		delay = min(retryErr.retryAfter, maxRetryDelay)
+	}
+	return delay
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
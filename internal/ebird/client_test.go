@@ -0,0 +1,154 @@
+package ebird
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientRetriesTransientFailures(t *testing.T) {
+	fake := NewFakeServer()
+	defer fake.Close()
+	fake.SetRecent("L123", []Observation{{SpeciesCode: "houspa", LocID: "L123"}})
+	fake.FailNext(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+
+	observations, err := fake.Client().RecentObservations(context.Background(), "L123", ObservationOptions{})
+	if err != nil {
+		t.Fatalf("RecentObservations() error = %v", err)
+	}
+	if len(observations) != 1 || observations[0].SpeciesCode != "houspa" {
+		t.Errorf("RecentObservations() = %+v, want the house sparrow", observations)
+	}
+	if got := fake.Requests(); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+}
+
+func TestClientGivesUpAfterMaxRetries(t *testing.T) {
+	fake := NewFakeServer()
+	defer fake.Close()
+	fake.FailNext(http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusInternalServerError)
+
+	_, err := fake.Client(WithRetries(2, 0)).RecentObservations(context.Background(), "L123", ObservationOptions{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("RecentObservations() error = %v, want the last attempt's 503", err)
+	}
+	if got := fake.Requests(); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+}
+
+func TestClientDoesNotRetryClientErrors(t *testing.T) {
+	fake := NewFakeServer()
+	defer fake.Close()
+	fake.FailNext(http.StatusBadRequest)
+
+	_, err := fake.Client().RecentObservations(context.Background(), "L123", ObservationOptions{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("RecentObservations() error = %v, want a 400 APIError", err)
+	}
+	if got := fake.Requests(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+
+	_, err = NewClient("", WithBaseURL(fake.URL())).RecentObservations(context.Background(), "L123", ObservationOptions{})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("RecentObservations() without a key error = %v, want a 401 APIError", err)
+	}
+}
+
+func TestClientNotFound(t *testing.T) {
+	fake := NewFakeServer()
+	defer fake.Close()
+	client := fake.Client()
+
+	_, err := client.HotspotInfo(context.Background(), "L999")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("HotspotInfo() error = %v, want ErrNotFound", err)
+	}
+
+	observations, err := client.HistoricObservations(context.Background(), "L123", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil || observations == nil || len(observations) != 0 {
+		t.Errorf("HistoricObservations() = %v, %v, want an empty slice for a day without data", observations, err)
+	}
+	if got := fake.Requests(); got != 2 {
+		t.Errorf("requests = %d, want 2: a 404 is not retried", got)
+	}
+}
+
+func TestClientHonoursRetryAfter(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	client := NewClient("key", WithBaseURL(server.URL), WithRetries(1, time.Millisecond))
+	start := time.Now()
+	if _, err := client.RecentObservations(context.Background(), "L123", ObservationOptions{}); err != nil {
+		t.Fatalf("RecentObservations() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want at least the 1s Retry-After", elapsed)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	client := NewClient("key", WithRetries(3, 100*time.Millisecond))
+
+	for attempt, base := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond} {
+		for range 20 {
+			if d := client.backoff(attempt, nil); d < base || d > base*3/2 {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", attempt, d, base, base*3/2)
+			}
+		}
+	}
+
+	if d := client.backoff(20, nil); d > maxRetryDelay*3/2 {
+		t.Errorf("backoff(20) = %v, want at most %v", d, maxRetryDelay*3/2)
+	}
+
+	hinted := &retryableError{APIError: &APIError{StatusCode: http.StatusTooManyRequests}, retryAfter: 5 * time.Second}
+	if d := client.backoff(1, hinted); d != 5*time.Second {
+		t.Errorf("backoff() with Retry-After 5s = %v, want 5s", d)
+	}
+	hinted.retryAfter = time.Hour
+	if d := client.backoff(1, hinted); d != maxRetryDelay {
+		t.Errorf("backoff() with Retry-After 1h = %v, want the %v cap", d, maxRetryDelay)
+	}
+}
+
+func TestClientCancellation(t *testing.T) {
+	fake := NewFakeServer()
+	defer fake.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fake.Client().RecentObservations(ctx, "L123", ObservationOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("RecentObservations() with a cancelled context error = %v, want context.Canceled", err)
+	}
+
+	// A long backoff is cut short by the deadline.
+	fake.FailNext(http.StatusServiceUnavailable)
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := fake.Client(WithRetries(3, time.Hour)).RecentObservations(ctx, "L123", ObservationOptions{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RecentObservations() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("RecentObservations() returned after %v, want it to stop at the deadline", elapsed)
+	}
+}
@@ -0,0 +1,241 @@
+package ebird
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeServer is an in-memory eBird API served by httptest, so code using a
+// Client can run offline. Populate it with the Set and Add methods, then call
+// Client for an APIClient pointed at it. Requests without an API token get 401.
+type FakeServer struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	recent   map[string][]Observation
+	notable  map[string][]Observation
+	historic map[string][]Observation // keyed by region code and "/2006-1-2"
+	hotspots []Hotspot
+	infos    map[string]HotspotInfo
+	taxonomy map[string][]Taxon // keyed by locale, "" for the default
+	failures []int
+	requests int
+}
+
+func NewFakeServer() *FakeServer {
+	f := &FakeServer{
+		recent:   map[string][]Observation{},
+		notable:  map[string][]Observation{},
+		historic: map[string][]Observation{},
+		infos:    map[string]HotspotInfo{},
+		taxonomy: map[string][]Taxon{},
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	return f
+}
+
+func (f *FakeServer) URL() string {
+	return f.server.URL
+}
+
+func (f *FakeServer) Close() {
+	f.server.Close()
+}
+
+// Client returns a client for the fake with rate limiting disabled and
+// immediate retries; opts override those defaults.
+func (f *FakeServer) Client(opts ...Option) *APIClient {
+	defaults := []Option{
+		WithBaseURL(f.server.URL),
+		WithHTTPClient(f.server.Client()),
+		WithRateLimit(math.MaxFloat64, 1),
+		WithRetries(DefaultMaxRetries, 0),
+	}
+	return NewClient("fake-api-key", append(defaults, opts...)...)
+}
+
+// SetRecent and SetNotable set a region's observations. The nearby
+// endpoints search the observations of every region by their coordinates.
+func (f *FakeServer) SetRecent(regionCode string, observations []Observation) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.recent[regionCode] = observations
+}
+
+func (f *FakeServer) SetNotable(regionCode string, observations []Observation) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.notable[regionCode] = observations
+}
+
+// SetHistoric sets a region's observations on one day. Days that are not
+// set answer 404, as eBird does for days without data.
+func (f *FakeServer) SetHistoric(regionCode string, date time.Time, observations []Observation) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.historic[historicKey(regionCode, date.Year(), int(date.Month()), date.Day())] = observations
+}
+
+// AddHotspot makes a hotspot findable by NearbyHotspots and HotspotInfo.
+func (f *FakeServer) AddHotspot(hotspot Hotspot, info HotspotInfo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hotspots = append(f.hotspots, hotspot)
+	if info.LocID == "" {
+		info.LocID = hotspot.LocID
+	}
+	f.infos[hotspot.LocID] = info
+}
+
+func (f *FakeServer) SetTaxonomy(locale string, taxa []Taxon) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.taxonomy[locale] = taxa
+}
+
+// FailNext answers the next requests with the given statuses, in order,
+// before serving normally again; use it to exercise retries.
+func (f *FakeServer) FailNext(statuses ...int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = append(f.failures, statuses...)
+}
+
+// Requests returns how many requests the fake has received.
+func (f *FakeServer) Requests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests
+}
+
+func (f *FakeServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests++
+	if r.Header.Get("X-eBirdApiToken") == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if len(f.failures) > 0 {
+		status := f.failures[0]
+		f.failures = f.failures[1:]
+		w.WriteHeader(status)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	query := r.URL.Query()
+
+	switch {
+	case len(parts) >= 4 && parts[0] == "data" && parts[1] == "obs" && parts[2] == "geo" && parts[3] == "recent":
+		lat, _ := strconv.ParseFloat(query.Get("lat"), 64)
+		lng, _ := strconv.ParseFloat(query.Get("lng"), 64)
+		dist, err := strconv.Atoi(query.Get("dist"))
+		if err != nil {
+			dist = 25
+		}
+		source := f.recent
+		if len(parts) == 5 && parts[4] == "notable" {
+			source = f.notable
+		}
+		nearby := []Observation{}
+		for _, observations := range source {
+			for _, obs := range observations {
+				if haversineKm(lat, lng, obs.Lat, obs.Lng) <= float64(dist) {
+					nearby = append(nearby, obs)
+				}
+			}
+		}
+		writeFakeJSON(w, nearby)
+	case len(parts) == 4 && parts[0] == "data" && parts[1] == "obs" && parts[3] == "recent":
+		writeFakeJSON(w, nonNil(f.recent[parts[2]]))
+	case len(parts) == 5 && parts[0] == "data" && parts[1] == "obs" && parts[3] == "recent" && parts[4] == "notable":
+		writeFakeJSON(w, nonNil(f.notable[parts[2]]))
+	case len(parts) == 7 && parts[0] == "data" && parts[1] == "obs" && parts[3] == "historic":
+		year, _ := strconv.Atoi(parts[4])
+		month, _ := strconv.Atoi(parts[5])
+		day, _ := strconv.Atoi(parts[6])
+		observations, ok := f.historic[historicKey(parts[2], year, month, day)]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeFakeJSON(w, nonNil(observations))
+	case len(parts) == 3 && parts[0] == "ref" && parts[1] == "hotspot" && parts[2] == "geo":
+		lat, _ := strconv.ParseFloat(query.Get("lat"), 64)
+		lng, _ := strconv.ParseFloat(query.Get("lng"), 64)
+		dist, err := strconv.Atoi(query.Get("dist"))
+		if err != nil {
+			dist = 25
+		}
+		nearby := []Hotspot{}
+		for _, hotspot := range f.hotspots {
+			if haversineKm(lat, lng, hotspot.Lat, hotspot.Lng) <= float64(dist) {
+				nearby = append(nearby, hotspot)
+			}
+		}
+		writeFakeJSON(w, nearby)
+	case len(parts) == 4 && parts[0] == "ref" && parts[1] == "hotspot" && parts[2] == "info":
+		info, ok := f.infos[parts[3]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeFakeJSON(w, info)
+	case len(parts) == 3 && parts[0] == "ref" && parts[1] == "taxonomy" && parts[2] == "ebird":
+		taxa := f.taxonomy[query.Get("locale")]
+		entries := make([]taxonomyEntry, 0, len(taxa))
+		for _, taxon := range taxa {
+			entries = append(entries, taxonomyEntry{
+				ScientificName: taxon.ScientificName,
+				CommonName:     taxon.CommonName,
+				SpeciesCode:    taxon.SpeciesCode,
+				Category:       taxon.Category,
+				TaxonOrder:     taxon.TaxonOrder,
+				Order:          taxon.Order,
+				FamilyCode:     taxon.FamilyCode,
+				FamilyComName:  taxon.FamilyCommonName,
+				FamilySciName:  taxon.FamilyScientificName,
+				ReportAs:       taxon.ReportAs,
+				Extinct:        taxon.Extinct,
+			})
+		}
+		writeFakeJSON(w, entries)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func historicKey(regionCode string, year, month, day int) string {
+	return regionCode + "/" + strconv.Itoa(year) + "-" + strconv.Itoa(month) + "-" + strconv.Itoa(day)
+}
+
+func nonNil(observations []Observation) []Observation {
+	if observations == nil {
+		return []Observation{}
+	}
+	return observations
+}
+
+func writeFakeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// haversineKm is the great-circle distance between two points in kilometres.
+func haversineKm(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadiusKm = 6371
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
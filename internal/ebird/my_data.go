@@ -0,0 +1,186 @@
+package ebird
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MyDataRow is one species line of the "Download My Data" CSV that eBird
+// emails to users (MyEBirdData.csv). Optional numeric fields are nil when blank.
+type MyDataRow struct {
+	Line               int
+	SubmissionID       string
+	CommonName         string
+	ScientificName     string
+	Count              *int // nil means "X" (present, not counted)
+	StateProvince      string
+	County             string
+	LocationID         string
+	Location           string
+	Latitude           *float64
+	Longitude          *float64
+	Date               string // YYYY-MM-DD
+	Time               string // e.g. "07:15 AM", blank for incidental records
+	Protocol           string
+	DurationMinutes    *int
+	AllObsReported     bool
+	DistanceKm         *float64
+	NumberOfObservers  *int
+	BreedingCode       string
+	ObservationDetails string
+	ChecklistComments  string
+}
+
+// RowError describes a line of an import file that could not be used.
+type RowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+var myDataRequiredColumns = []string{"submission_id", "common_name", "scientific_name", "count", "date"}
+
+// ReadMyData parses a My eBird Data CSV. Lines that cannot be parsed are
+// returned as RowErrors rather than failing the whole file; an error is only
+// returned when the file itself is unreadable or is missing required columns.
+func ReadMyData(r io.Reader) ([]MyDataRow, []RowError, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimPrefix(name, "\ufeff")
+		index[myDataColumnKey(name)] = i
+	}
+	for _, required := range myDataRequiredColumns {
+		if _, ok := index[required]; !ok {
+			return nil, nil, fmt.Errorf("not a My eBird Data file: missing column %q", required)
+		}
+	}
+
+	var rows []MyDataRow
+	var rowErrors []RowError
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rowErrors = append(rowErrors, RowError{Line: parseErr.StartLine, Message: parseErr.Err.Error()})
+				continue
+			}
+			return nil, nil, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		row, err := parseMyDataRow(record, index)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Line: line, Message: err.Error()})
+			continue
+		}
+		row.Line = line
+		rows = append(rows, row)
+	}
+
+	return rows, rowErrors, nil
+}
+
+// myDataColumnKey turns a header such as "Duration (Min)" into "duration_min".
+func myDataColumnKey(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.NewReplacer("(", "", ")", "", "/", "_", " ", "_").Replace(name)
+	return name
+}
+
+func parseMyDataRow(record []string, index map[string]int) (MyDataRow, error) {
+	get := func(column string) string {
+		i, ok := index[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	row := MyDataRow{
+		SubmissionID:       get("submission_id"),
+		CommonName:         get("common_name"),
+		ScientificName:     get("scientific_name"),
+		StateProvince:      get("state_province"),
+		County:             get("county"),
+		LocationID:         get("location_id"),
+		Location:           get("location"),
+		Date:               get("date"),
+		Time:               get("time"),
+		Protocol:           get("protocol"),
+		AllObsReported:     get("all_obs_reported") == "1",
+		BreedingCode:       get("breeding_code"),
+		ObservationDetails: get("observation_details"),
+		ChecklistComments:  get("checklist_comments"),
+	}
+
+	if row.SubmissionID == "" {
+		return row, errors.New("missing submission ID")
+	}
+	if row.ScientificName == "" && row.CommonName == "" {
+		return row, errors.New("missing species name")
+	}
+	if row.Date == "" {
+		return row, errors.New("missing date")
+	}
+
+	var err error
+	if count := get("count"); count != "" && !strings.EqualFold(count, "x") {
+		if row.Count, err = parseOptionalInt(count); err != nil || *row.Count <= 0 {
+			return row, fmt.Errorf("invalid count %q", count)
+		}
+	}
+	if row.Latitude, err = parseOptionalFloat(get("latitude")); err != nil {
+		return row, fmt.Errorf("invalid latitude: %w", err)
+	}
+	if row.Longitude, err = parseOptionalFloat(get("longitude")); err != nil {
+		return row, fmt.Errorf("invalid longitude: %w", err)
+	}
+	if row.DurationMinutes, err = parseOptionalInt(get("duration_min")); err != nil {
+		return row, fmt.Errorf("invalid duration: %w", err)
+	}
+	if row.DistanceKm, err = parseOptionalFloat(get("distance_traveled_km")); err != nil {
+		return row, fmt.Errorf("invalid distance: %w", err)
+	}
+	if row.NumberOfObservers, err = parseOptionalInt(get("number_of_observers")); err != nil {
+		return row, fmt.Errorf("invalid number of observers: %w", err)
+	}
+
+	return row, nil
+}
+
+func parseOptionalInt(s string) (*int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+func parseOptionalFloat(s string) (*float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
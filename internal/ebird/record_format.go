@@ -0,0 +1,118 @@
+// Package ebird holds helpers for exchanging data with eBird.
+package ebird
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+const kmPerMile = 1.609344
+
+// RecordFormatRow is one species line of the eBird Record Format, the
+// headerless 19-column CSV accepted by eBird's "Import Data" tool. Every row
+// repeats its checklist's effort fields; eBird groups rows into checklists by
+// location, date, start time and protocol.
+type RecordFormatRow struct {
+	CommonName        string
+	Genus             string
+	Species           string
+	Count             *int // nil is written as "X" (present, not counted)
+	SpeciesComments   string
+	LocationName      string
+	Latitude          *float64
+	Longitude         *float64
+	ObservedAt        time.Time // written in its own location; callers convert to local time
+	StateCode         string
+	CountryCode       string
+	Protocol          string // stationary, traveling or incidental
+	NumberOfObservers int
+	DurationMinutes   *int
+	AllReported       bool
+	DistanceKm        *float64
+	ChecklistComments string
+}
+
+// WriteRecordFormat writes rows in eBird Record Format.
+func WriteRecordFormat(w io.Writer, rows []RecordFormatRow) error {
+	cw := csv.NewWriter(w)
+	for _, row := range rows {
+		if err := cw.Write(row.fields()); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (r RecordFormatRow) fields() []string {
+	count := "X"
+	if r.Count != nil {
+		count = strconv.Itoa(*r.Count)
+	}
+
+	allReported := "N"
+	if r.AllReported {
+		allReported = "Y"
+	}
+
+	protocol := r.Protocol
+	switch r.Protocol {
+	case "stationary":
+		protocol = "Stationary"
+	case "traveling":
+		protocol = "Traveling"
+	case "incidental":
+		protocol = "Incidental"
+	}
+
+	observers := ""
+	if r.NumberOfObservers > 0 {
+		observers = strconv.Itoa(r.NumberOfObservers)
+	}
+
+	return []string{
+		r.CommonName,
+		r.Genus,
+		r.Species,
+		count,
+		r.SpeciesComments,
+		r.LocationName,
+		formatFloat(r.Latitude, 6),
+		formatFloat(r.Longitude, 6),
+		r.ObservedAt.Format("01/02/2006"),
+		r.ObservedAt.Format("15:04"),
+		r.StateCode,
+		r.CountryCode,
+		protocol,
+		observers,
+		formatInt(r.DurationMinutes),
+		allReported,
+		formatFloat(kmToMiles(r.DistanceKm), 3),
+		"", // effort area (acres) is not recorded
+		r.ChecklistComments,
+	}
+}
+
+func kmToMiles(km *float64) *float64 {
+	if km == nil {
+		return nil
+	}
+	miles := *km / kmPerMile
+	return &miles
+}
+
+func formatFloat(f *float64, precision int) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'f', precision, 64)
+}
+
+func formatInt(i *int) string {
+	if i == nil {
+		return ""
+	}
+	return strconv.Itoa(*i)
+}
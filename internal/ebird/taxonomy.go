@@ -0,0 +1,143 @@
+package ebird
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Taxon is one row of the eBird/Clements taxonomy.
+type Taxon struct {
+	SpeciesCode          string
+	CommonName           string
+	ScientificName       string
+	Category             string
+	TaxonOrder           float64
+	Order                string
+	FamilyCode           string
+	FamilyCommonName     string
+	FamilyScientificName string
+	ReportAs             string
+	Extinct              bool
+}
+
+// taxonomyColumns maps each Taxon field to the header names it appears under
+// in the eBird API CSV, the eBird taxonomy download and the Clements checklist.
+var taxonomyColumns = map[string][]string{
+	"code":        {"species_code"},
+	"common":      {"common_name", "primary_com_name", "english_name"},
+	"scientific":  {"scientific_name", "sci_name"},
+	"category":    {"category"},
+	"order":       {"taxon_order"},
+	"order_name":  {"order", "order1"},
+	"family":      {"family"},
+	"family_com":  {"family_com_name"},
+	"family_sci":  {"family_sci_name"},
+	"family_code": {"family_code"},
+	"report_as":   {"report_as"},
+	"extinct":     {"extinct"},
+}
+
+// ReadTaxonomyCSV parses a taxonomy CSV. Columns are matched by header name,
+// so the eBird API export (?fmt=csv, including localized exports), the
+// yearly eBird taxonomy file and the Clements checklist are all accepted.
+// Only the species code and common name columns are required; rows without a
+// species code, such as Clements subspecies rows, are skipped.
+func ReadTaxonomyCSV(r io.Reader) ([]Taxon, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	index := make(map[string]int, len(taxonomyColumns))
+	for i, name := range header {
+		name = strings.TrimPrefix(name, "\ufeff")
+		name = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), " ", "_"))
+		for field, aliases := range taxonomyColumns {
+			if _, ok := index[field]; ok {
+				continue
+			}
+			for _, alias := range aliases {
+				if name == alias {
+					index[field] = i
+				}
+			}
+		}
+		// Clements names its sort column after the release, e.g. "sort v2024".
+		if _, ok := index["order"]; !ok && strings.HasPrefix(name, "sort_v") {
+			index["order"] = i
+		}
+	}
+
+	if _, ok := index["code"]; !ok {
+		return nil, errors.New("taxonomy CSV has no species code column")
+	}
+	if _, ok := index["common"]; !ok {
+		return nil, errors.New("taxonomy CSV has no common name column")
+	}
+
+	var taxa []Taxon
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		get := func(field string) string {
+			i, ok := index[field]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		taxon := Taxon{
+			SpeciesCode:          get("code"),
+			CommonName:           get("common"),
+			ScientificName:       get("scientific"),
+			Category:             strings.ToLower(get("category")),
+			Order:                get("order_name"),
+			FamilyCode:           get("family_code"),
+			FamilyCommonName:     get("family_com"),
+			FamilyScientificName: get("family_sci"),
+			ReportAs:             get("report_as"),
+		}
+		if taxon.SpeciesCode == "" {
+			continue
+		}
+
+		if order := get("order"); order != "" {
+			taxon.TaxonOrder, err = strconv.ParseFloat(order, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid taxon order %q", line, order)
+			}
+		}
+
+		switch strings.ToLower(get("extinct")) {
+		case "1", "y", "yes", "true":
+			taxon.Extinct = true
+		}
+
+		// Older files combine the family as "Anatidae (Ducks, Geese, and Waterfowl)".
+		if family := get("family"); family != "" && taxon.FamilyScientificName == "" {
+			sci, com, found := strings.Cut(family, " (")
+			taxon.FamilyScientificName = strings.TrimSpace(sci)
+			if found && taxon.FamilyCommonName == "" {
+				taxon.FamilyCommonName = strings.TrimSuffix(com, ")")
+			}
+		}
+
+		taxa = append(taxa, taxon)
+	}
+
+	return taxa, nil
+}
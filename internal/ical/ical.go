@@ -0,0 +1,129 @@
+// Package ical writes minimal RFC 5545 (iCalendar) documents for calendar
+// exports and subscribed feeds.
+package ical
+
+import (
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102T150405Z"
+	maxLineOctets  = 75
+)
+
+// Event is a single VEVENT. AllDay events only use the date part of Start and
+// End, and End is inclusive (the exclusive DTEND is computed when writing).
+type Event struct {
+	UID          string
+	Summary      string
+	Description  string
+	Location     string
+	URL          string
+	Start        time.Time
+	End          time.Time
+	AllDay       bool
+	Created      time.Time
+	LastModified *time.Time
+}
+
+// Calendar is a VCALENDAR holding a list of events.
+type Calendar struct {
+	ProdID string
+	Name   string
+	Events []Event
+}
+
+func NewCalendar(name string) *Calendar {
+	return &Calendar{
+		ProdID: "-//Birdlens//Birdlens Calendar//EN",
+		Name:   name,
+	}
+}
+
+func (c *Calendar) Add(e Event) {
+	c.Events = append(c.Events, e)
+}
+
+// WriteTo writes the calendar with CRLF line endings and folded long lines.
+func (c *Calendar) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	writeLine(&b, "BEGIN:VCALENDAR")
+	writeLine(&b, "VERSION:2.0")
+	writeLine(&b, "PRODID:"+c.ProdID)
+	writeLine(&b, "CALSCALE:GREGORIAN")
+	writeLine(&b, "METHOD:PUBLISH")
+	if c.Name != "" {
+		writeLine(&b, "X-WR-CALNAME:"+escapeText(c.Name))
+	}
+
+	stamp := time.Now().UTC().Format(dateTimeFormat)
+	for _, e := range c.Events {
+		writeLine(&b, "BEGIN:VEVENT")
+		writeLine(&b, "UID:"+e.UID)
+		writeLine(&b, "DTSTAMP:"+stamp)
+		if e.AllDay {
+			writeLine(&b, "DTSTART;VALUE=DATE:"+e.Start.Format(dateFormat))
+			writeLine(&b, "DTEND;VALUE=DATE:"+e.End.AddDate(0, 0, 1).Format(dateFormat))
+		} else {
+			writeLine(&b, "DTSTART:"+e.Start.UTC().Format(dateTimeFormat))
+			writeLine(&b, "DTEND:"+e.End.UTC().Format(dateTimeFormat))
+		}
+		writeLine(&b, "SUMMARY:"+escapeText(e.Summary))
+		if e.Description != "" {
+			writeLine(&b, "DESCRIPTION:"+escapeText(e.Description))
+		}
+		if e.Location != "" {
+			writeLine(&b, "LOCATION:"+escapeText(e.Location))
+		}
+		if e.URL != "" {
+			writeLine(&b, "URL:"+e.URL)
+		}
+		if !e.Created.IsZero() {
+			writeLine(&b, "CREATED:"+e.Created.UTC().Format(dateTimeFormat))
+		}
+		if e.LastModified != nil {
+			writeLine(&b, "LAST-MODIFIED:"+e.LastModified.UTC().Format(dateTimeFormat))
+		}
+		writeLine(&b, "END:VEVENT")
+	}
+
+	writeLine(&b, "END:VCALENDAR")
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func escapeText(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, ";", `\;`)
+	s = strings.ReplaceAll(s, ",", `\,`)
+	s = strings.ReplaceAll(s, "\r\n", `\n`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return s
+}
+
+// writeLine folds lines longer than 75 octets without splitting UTF-8 sequences.
+// Continuation lines start with a space, which counts towards the limit.
+func writeLine(b *strings.Builder, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
@@ -0,0 +1,180 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	ChatRoleUser  = "user"
+	ChatRoleModel = "model"
+)
+
+// ErrChatFull is returned by AddMessages when the messages would take a
+// chat past its maximum length.
+var ErrChatFull = errors.New("chat is full")
+
+// Chat is a conversation with the AI assistant about one bird, taken from
+// the identification it was started from.
+type Chat struct {
+	ID               int64     `json:"id" db:"id"`
+	UserID           int64     `json:"-" db:"user_id"`
+	IdentificationID *int64    `json:"identification_id" db:"identification_id"`
+	SpeciesCode      *string   `json:"species_code" db:"species_code"`
+	BirdName         string    `json:"bird_name" db:"bird_name"`
+	MessageCount     int       `json:"message_count" db:"message_count"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ChatMessage is a turn of a chat. Tokens are those of the model call that
+// produced a model message.
+type ChatMessage struct {
+	ID           int64     `json:"id" db:"id"`
+	ChatID       int64     `json:"-" db:"chat_id"`
+	Role         string    `json:"role" db:"role"`
+	Text         string    `json:"text" db:"text"`
+	InputTokens  int       `json:"input_tokens" db:"input_tokens"`
+	OutputTokens int       `json:"output_tokens" db:"output_tokens"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+type ChatStore struct {
+	db *sqlx.DB
+}
+
+const chatColumns = `id, user_id, identification_id, species_code, bird_name, message_count, created_at, updated_at`
+
+const chatMessageColumns = `id, chat_id, role, text, input_tokens, output_tokens, created_at`
+
+// Create starts a chat with its opening messages in one transaction.
+func (s *ChatStore) Create(ctx context.Context, chat *Chat, messages []*ChatMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+    INSERT INTO ai_chats (user_id, identification_id, species_code, bird_name, message_count)
+    VALUES ($1, $2, $3, $4, $5)
+    RETURNING id, message_count, created_at, updated_at`
+	err = tx.QueryRowContext(ctx, query, chat.UserID, chat.IdentificationID, chat.SpeciesCode, chat.BirdName, len(messages)).
+		Scan(&chat.ID, &chat.MessageCount, &chat.CreatedAt, &chat.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := insertChatMessages(ctx, tx, chat, messages); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AddMessages appends messages to a chat in one transaction. It returns
+// ErrChatFull, adding nothing, when the chat would then hold more than
+// maxMessages; the count is checked and raised in one statement, so that
+// concurrent requests cannot overfill a chat.
+func (s *ChatStore) AddMessages(ctx context.Context, chat *Chat, messages []*ChatMessage, maxMessages int) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+    UPDATE ai_chats SET message_count = message_count + $2, updated_at = NOW()
+    WHERE id = $1 AND message_count + $2 <= $3
+    RETURNING message_count, updated_at`
+	err = tx.QueryRowContext(ctx, query, chat.ID, len(messages), maxMessages).Scan(&chat.MessageCount, &chat.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrChatFull
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := insertChatMessages(ctx, tx, chat, messages); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertChatMessages(ctx context.Context, tx *sqlx.Tx, chat *Chat, messages []*ChatMessage) error {
+	query := `
+    INSERT INTO ai_chat_messages (chat_id, role, text, input_tokens, output_tokens)
+    VALUES ($1, $2, $3, $4, $5)
+    RETURNING id, created_at`
+	for _, msg := range messages {
+		msg.ChatID = chat.ID
+		err := tx.QueryRowContext(ctx, query, msg.ChatID, msg.Role, msg.Text, msg.InputTokens, msg.OutputTokens).
+			Scan(&msg.ID, &msg.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetByID returns sql.ErrNoRows when the chat does not exist or belongs to
+// another user.
+func (s *ChatStore) GetByID(ctx context.Context, userID, id int64) (*Chat, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var chat Chat
+	err := s.db.GetContext(ctx, &chat, `SELECT `+chatColumns+` FROM ai_chats WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+	return &chat, nil
+}
+
+// GetByUserID returns a user's chats, the most recently active first.
+func (s *ChatStore) GetByUserID(ctx context.Context, userID int64, limit, offset int) (*PaginatedList[*Chat], error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var totalCount int
+	if err := s.db.GetContext(ctx, &totalCount, `SELECT COUNT(*) FROM ai_chats WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+
+	chats := []*Chat{}
+	query := `
+    SELECT ` + chatColumns + ` FROM ai_chats
+    WHERE user_id = $1
+    ORDER BY updated_at DESC, id DESC
+    LIMIT $2 OFFSET $3`
+	if err := s.db.SelectContext(ctx, &chats, query, userID, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return NewPaginatedList(chats, totalCount, limit, offset)
+}
+
+// GetMessages returns a chat's messages in order.
+func (s *ChatStore) GetMessages(ctx context.Context, chatID int64) ([]*ChatMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	messages := []*ChatMessage{}
+	query := `SELECT ` + chatMessageColumns + ` FROM ai_chat_messages WHERE chat_id = $1 ORDER BY id`
+	if err := s.db.SelectContext(ctx, &messages, query, chatID); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
@@ -0,0 +1,299 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// Input types. Requests with photos and a recording are "image".
+	IdentificationInputImage = "image"
+	IdentificationInputAudio = "audio"
+	IdentificationInputText  = "text"
+
+	IdentificationFeedbackCorrect   = "correct"
+	IdentificationFeedbackIncorrect = "incorrect"
+)
+
+// IdentificationCandidate is a species the model considered, matched
+// against the local taxonomy. SpeciesCode is empty when the model named a
+// species the taxonomy does not know.
+type IdentificationCandidate struct {
+	SpeciesCode    string   `json:"species_code,omitempty"`
+	CommonName     string   `json:"common_name"`
+	ScientificName string   `json:"scientific_name,omitempty"`
+	Confidence     float64  `json:"confidence"`
+	FieldMarks     []string `json:"field_marks,omitempty"`
+}
+
+// IdentificationCandidates is stored as a JSONB array.
+type IdentificationCandidates []IdentificationCandidate
+
+func (c *IdentificationCandidates) Scan(value any) error {
+	switch v := value.(type) {
+	case nil:
+		*c = IdentificationCandidates{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	default:
+		return errors.New("unsupported type for IdentificationCandidates")
+	}
+}
+
+// Identification is a stored AI identification request. ChosenSpeciesCode
+// and ChosenName are set when the model settled on one species. Feedback is
+// the user's verdict; ActualSpeciesCode is the species it really was, which
+// for correct identifications is the chosen one.
+type Identification struct {
+	ID                int64                    `json:"id" db:"id"`
+	UserID            int64                    `json:"-" db:"user_id"`
+	InputType         string                   `json:"input_type" db:"input_type"`
+	Prompt            string                   `json:"prompt" db:"prompt"`
+	ImageURL          *string                  `json:"image_url" db:"image_url"`
+	Candidates        IdentificationCandidates `json:"candidates" db:"candidates"`
+	ChosenSpeciesCode *string                  `json:"chosen_species_code" db:"chosen_species_code"`
+	ChosenName        *string                  `json:"chosen_name" db:"chosen_name"`
+	Model             string                   `json:"model" db:"model"`
+	Feedback          *string                  `json:"feedback" db:"feedback"`
+	ActualSpeciesCode *string                  `json:"actual_species_code" db:"actual_species_code"`
+	FeedbackAt        *time.Time               `json:"feedback_at" db:"feedback_at"`
+	CreatedAt         time.Time                `json:"created_at" db:"created_at"`
+}
+
+// IdentificationAccuracy summarises the feedback on identifications.
+// Accuracy is Correct over Rated and nil until something is rated.
+// InCandidates counts rated identifications whose actual species was among
+// the candidates, whether or not the model chose it.
+type IdentificationAccuracy struct {
+	Identifications int      `json:"identifications" db:"identifications"`
+	Rated           int      `json:"rated" db:"rated"`
+	Correct         int      `json:"correct" db:"correct"`
+	Incorrect       int      `json:"incorrect" db:"incorrect"`
+	InCandidates    int      `json:"in_candidates" db:"in_candidates"`
+	Accuracy        *float64 `json:"accuracy" db:"-"`
+}
+
+func (a *IdentificationAccuracy) computeAccuracy() {
+	if a.Rated > 0 {
+		accuracy := float64(a.Correct) / float64(a.Rated)
+		a.Accuracy = &accuracy
+	}
+}
+
+// SpeciesIdentificationAccuracy is the accuracy of identifications that
+// chose a species. MostConfusedWith is the actual species most often
+// reported when the choice was wrong.
+type SpeciesIdentificationAccuracy struct {
+	SpeciesCode      string  `json:"species_code" db:"species_code"`
+	CommonName       *string `json:"common_name" db:"common_name"`
+	MostConfusedWith *string `json:"most_confused_with" db:"most_confused_with"`
+	IdentificationAccuracy
+}
+
+// LabelledIdentification is a photo whose species a user has confirmed.
+type LabelledIdentification struct {
+	ID          int64     `json:"id" db:"id"`
+	ImageURL    string    `json:"image_url" db:"image_url"`
+	SpeciesCode string    `json:"species_code" db:"species_code"`
+	Model       string    `json:"model" db:"model"`
+	Correct     bool      `json:"correct" db:"correct"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+type IdentificationStore struct {
+	db *sqlx.DB
+}
+
+const identificationColumns = `id, user_id, input_type, prompt, image_url, candidates, chosen_species_code, chosen_name,
+    model, feedback, actual_species_code, feedback_at, created_at`
+
+// identificationAccuracySelect aggregates the rows of i. model filters by
+// model name when not empty.
+const identificationAccuracySelect = `
+        COUNT(*) AS identifications,
+        COUNT(i.feedback) AS rated,
+        COUNT(*) FILTER (WHERE i.feedback = 'correct') AS correct,
+        COUNT(*) FILTER (WHERE i.feedback = 'incorrect') AS incorrect,
+        COUNT(*) FILTER (WHERE i.actual_species_code IS NOT NULL
+            AND i.candidates @> jsonb_build_array(jsonb_build_object('species_code', i.actual_species_code))) AS in_candidates`
+
+func (s *IdentificationStore) Create(ctx context.Context, identification *Identification) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	if identification.Candidates == nil {
+		identification.Candidates = IdentificationCandidates{}
+	}
+	candidatesJSON, err := json.Marshal(identification.Candidates)
+	if err != nil {
+		return err
+	}
+
+	query := `
+    INSERT INTO ai_identifications (user_id, input_type, prompt, image_url, candidates, chosen_species_code, chosen_name, model)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    RETURNING id, created_at`
+	return s.db.QueryRowContext(ctx, query,
+		identification.UserID, identification.InputType, identification.Prompt, identification.ImageURL,
+		candidatesJSON, identification.ChosenSpeciesCode, identification.ChosenName, identification.Model,
+	).Scan(&identification.ID, &identification.CreatedAt)
+}
+
+func (s *IdentificationStore) SetImageURL(ctx context.Context, id int64, imageURL string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `UPDATE ai_identifications SET image_url = $2 WHERE id = $1`, id, imageURL)
+	return err
+}
+
+// GetByID returns sql.ErrNoRows when the identification does not exist or
+// belongs to another user.
+func (s *IdentificationStore) GetByID(ctx context.Context, userID, id int64) (*Identification, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var identification Identification
+	query := `SELECT ` + identificationColumns + ` FROM ai_identifications WHERE id = $1 AND user_id = $2`
+	err := s.db.GetContext(ctx, &identification, query, id, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+	return &identification, nil
+}
+
+func (s *IdentificationStore) GetByUserID(ctx context.Context, userID int64, limit, offset int) (*PaginatedList[*Identification], error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var totalCount int
+	if err := s.db.GetContext(ctx, &totalCount, `SELECT COUNT(*) FROM ai_identifications WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+
+	identifications := []*Identification{}
+	query := `
+    SELECT ` + identificationColumns + ` FROM ai_identifications
+    WHERE user_id = $1
+    ORDER BY created_at DESC, id DESC
+    LIMIT $2 OFFSET $3`
+	if err := s.db.SelectContext(ctx, &identifications, query, userID, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return NewPaginatedList(identifications, totalCount, limit, offset)
+}
+
+// SaveFeedback records the user's verdict on an identification, replacing
+// any earlier one. It returns sql.ErrNoRows when the identification does
+// not exist or belongs to another user.
+func (s *IdentificationStore) SaveFeedback(ctx context.Context, identification *Identification) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+    UPDATE ai_identifications
+    SET feedback = $3, actual_species_code = $4, feedback_at = NOW()
+    WHERE id = $1 AND user_id = $2
+    RETURNING feedback_at`
+	err := s.db.QueryRowContext(ctx, query,
+		identification.ID, identification.UserID, identification.Feedback, identification.ActualSpeciesCode,
+	).Scan(&identification.FeedbackAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// GetAccuracy summarises every identification, of model when it is not
+// empty.
+func (s *IdentificationStore) GetAccuracy(ctx context.Context, model string) (*IdentificationAccuracy, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var accuracy IdentificationAccuracy
+	query := `SELECT ` + identificationAccuracySelect + ` FROM ai_identifications i WHERE ($1 = '' OR i.model = $1)`
+	if err := s.db.GetContext(ctx, &accuracy, query, model); err != nil {
+		return nil, err
+	}
+	accuracy.computeAccuracy()
+	return &accuracy, nil
+}
+
+// GetAccuracyBySpecies returns the accuracy per chosen species, of model
+// when it is not empty, most rated first.
+func (s *IdentificationStore) GetAccuracyBySpecies(ctx context.Context, model string, limit, offset int) (*PaginatedList[*SpeciesIdentificationAccuracy], error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var totalCount int
+	countQuery := `
+    SELECT COUNT(DISTINCT chosen_species_code) FROM ai_identifications
+    WHERE chosen_species_code IS NOT NULL AND ($1 = '' OR model = $1)`
+	if err := s.db.GetContext(ctx, &totalCount, countQuery, model); err != nil {
+		return nil, err
+	}
+
+	species := []*SpeciesIdentificationAccuracy{}
+	query := `
+    SELECT
+        i.chosen_species_code AS species_code,
+        t.common_name,
+        (SELECT w.actual_species_code FROM ai_identifications w
+         WHERE w.chosen_species_code = i.chosen_species_code AND w.feedback = 'incorrect'
+           AND w.actual_species_code IS NOT NULL AND ($1 = '' OR w.model = $1)
+         GROUP BY w.actual_species_code
+         ORDER BY COUNT(*) DESC, w.actual_species_code
+         LIMIT 1) AS most_confused_with,` + identificationAccuracySelect + `
+    FROM ai_identifications i
+    LEFT JOIN species_taxonomy t ON t.species_code = i.chosen_species_code
+    WHERE i.chosen_species_code IS NOT NULL AND ($1 = '' OR i.model = $1)
+    GROUP BY i.chosen_species_code, t.common_name
+    ORDER BY rated DESC, identifications DESC, i.chosen_species_code
+    LIMIT $2 OFFSET $3`
+	if err := s.db.SelectContext(ctx, &species, query, model, limit, offset); err != nil {
+		return nil, err
+	}
+	for _, sp := range species {
+		sp.computeAccuracy()
+	}
+
+	return NewPaginatedList(species, totalCount, limit, offset)
+}
+
+// GetLabelled returns the photos whose actual species is known, oldest
+// first, so that exports can resume where they left off.
+func (s *IdentificationStore) GetLabelled(ctx context.Context, limit, offset int) (*PaginatedList[*LabelledIdentification], error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	const where = `WHERE image_url IS NOT NULL AND actual_species_code IS NOT NULL`
+
+	var totalCount int
+	if err := s.db.GetContext(ctx, &totalCount, `SELECT COUNT(*) FROM ai_identifications `+where); err != nil {
+		return nil, err
+	}
+
+	labelled := []*LabelledIdentification{}
+	query := `
+    SELECT id, image_url, actual_species_code AS species_code, model, feedback = 'correct' AS correct, created_at
+    FROM ai_identifications ` + where + `
+    ORDER BY id
+    LIMIT $1 OFFSET $2`
+	if err := s.db.SelectContext(ctx, &labelled, query, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return NewPaginatedList(labelled, totalCount, limit, offset)
+}
@@ -0,0 +1,207 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Metered AI features.
+const (
+	AIFeatureIdentify = "identify"
+	AIFeatureQuestion = "question"
+)
+
+// AILimits are the daily allowances of a subscription tier. Tokens are
+// checked before a call, so the call that crosses DailyTokens completes.
+type AILimits struct {
+	DailyIdentifications int `json:"daily_identifications"`
+	DailyQuestions       int `json:"daily_questions"`
+	DailyTokens          int `json:"daily_tokens"`
+}
+
+// Calls returns the daily call allowance of feature.
+func (l AILimits) Calls(feature string) int {
+	if feature == AIFeatureIdentify {
+		return l.DailyIdentifications
+	}
+	return l.DailyQuestions
+}
+
+// AIUsage is what a user consumed on a UTC day.
+type AIUsage struct {
+	Day           time.Time `json:"day" db:"day"`
+	IdentifyCalls int       `json:"identify_calls" db:"identify_calls"`
+	QuestionCalls int       `json:"question_calls" db:"question_calls"`
+	InputTokens   int64     `json:"input_tokens" db:"input_tokens"`
+	OutputTokens  int64     `json:"output_tokens" db:"output_tokens"`
+}
+
+func (u *AIUsage) Calls(feature string) int {
+	if feature == AIFeatureIdentify {
+		return u.IdentifyCalls
+	}
+	return u.QuestionCalls
+}
+
+func (u *AIUsage) Tokens() int64 {
+	return u.InputTokens + u.OutputTokens
+}
+
+// UserAIUsage is a user's usage over the days of a report.
+type UserAIUsage struct {
+	UserID     int64  `json:"user_id" db:"user_id"`
+	Username   string `json:"username" db:"username"`
+	Email      string `json:"email" db:"email"`
+	ActiveDays int    `json:"active_days" db:"active_days"`
+	AIUsage
+}
+
+type AIUsageStore struct {
+	db *sqlx.DB
+}
+
+func aiUsageCallColumn(feature string) (string, error) {
+	switch feature {
+	case AIFeatureIdentify:
+		return "identify_calls", nil
+	case AIFeatureQuestion:
+		return "question_calls", nil
+	default:
+		return "", fmt.Errorf("unknown AI feature %q", feature)
+	}
+}
+
+// Reserve counts a call of feature on day unless that would exceed the
+// feature's call limit or the user has used up the day's tokens. It returns
+// the day's usage, including the call when it was counted, and whether it
+// was.
+func (s *AIUsageStore) Reserve(ctx context.Context, userID int64, day time.Time, feature string, limits AILimits) (*AIUsage, bool, error) {
+	column, err := aiUsageCallColumn(feature)
+	if err != nil {
+		return nil, false, err
+	}
+	if limits.Calls(feature) <= 0 || limits.DailyTokens <= 0 {
+		usage, err := s.Get(ctx, userID, day)
+		return usage, false, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var usage AIUsage
+	query := `
+    INSERT INTO ai_usage (user_id, day, ` + column + `)
+    VALUES ($1, $2, 1)
+    ON CONFLICT (user_id, day) DO UPDATE SET ` + column + ` = ai_usage.` + column + ` + 1
+    WHERE ai_usage.` + column + ` < $3 AND ai_usage.input_tokens + ai_usage.output_tokens < $4
+    RETURNING day, identify_calls, question_calls, input_tokens, output_tokens`
+	err = s.db.GetContext(ctx, &usage, query, userID, day, limits.Calls(feature), limits.DailyTokens)
+	if errors.Is(err, sql.ErrNoRows) {
+		current, err := s.Get(ctx, userID, day)
+		return current, false, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return &usage, true, nil
+}
+
+// Release gives back a reserved call that did not reach the model.
+func (s *AIUsageStore) Release(ctx context.Context, userID int64, day time.Time, feature string) error {
+	column, err := aiUsageCallColumn(feature)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `UPDATE ai_usage SET ` + column + ` = GREATEST(` + column + ` - 1, 0) WHERE user_id = $1 AND day = $2`
+	_, err = s.db.ExecContext(ctx, query, userID, day)
+	return err
+}
+
+func (s *AIUsageStore) AddTokens(ctx context.Context, userID int64, day time.Time, inputTokens, outputTokens int) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+    INSERT INTO ai_usage (user_id, day, input_tokens, output_tokens)
+    VALUES ($1, $2, $3, $4)
+    ON CONFLICT (user_id, day) DO UPDATE SET
+        input_tokens = ai_usage.input_tokens + EXCLUDED.input_tokens,
+        output_tokens = ai_usage.output_tokens + EXCLUDED.output_tokens`
+	_, err := s.db.ExecContext(ctx, query, userID, day, inputTokens, outputTokens)
+	return err
+}
+
+// Get returns a user's usage on day, which is zero when nothing was used.
+func (s *AIUsageStore) Get(ctx context.Context, userID int64, day time.Time) (*AIUsage, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	usage := AIUsage{Day: day}
+	query := `
+    SELECT day, identify_calls, question_calls, input_tokens, output_tokens
+    FROM ai_usage WHERE user_id = $1 AND day = $2`
+	err := s.db.GetContext(ctx, &usage, query, userID, day)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// GetDailyTotals returns the usage of all users per day from from to to,
+// inclusive.
+func (s *AIUsageStore) GetDailyTotals(ctx context.Context, from, to time.Time) ([]*AIUsage, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	totals := []*AIUsage{}
+	query := `
+    SELECT day, SUM(identify_calls) AS identify_calls, SUM(question_calls) AS question_calls,
+        SUM(input_tokens) AS input_tokens, SUM(output_tokens) AS output_tokens
+    FROM ai_usage
+    WHERE day BETWEEN $1 AND $2
+    GROUP BY day
+    ORDER BY day`
+	if err := s.db.SelectContext(ctx, &totals, query, from, to); err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// GetUserTotals returns each user's usage from from to to, inclusive, the
+// heaviest token users first. Day is the last day the user was active.
+func (s *AIUsageStore) GetUserTotals(ctx context.Context, from, to time.Time, limit, offset int) (*PaginatedList[*UserAIUsage], error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var totalCount int
+	countQuery := `SELECT COUNT(DISTINCT user_id) FROM ai_usage WHERE day BETWEEN $1 AND $2`
+	if err := s.db.GetContext(ctx, &totalCount, countQuery, from, to); err != nil {
+		return nil, err
+	}
+
+	users := []*UserAIUsage{}
+	query := `
+    SELECT a.user_id, u.username, u.email, COUNT(*) AS active_days, MAX(a.day) AS day,
+        SUM(a.identify_calls) AS identify_calls, SUM(a.question_calls) AS question_calls,
+        SUM(a.input_tokens) AS input_tokens, SUM(a.output_tokens) AS output_tokens
+    FROM ai_usage a
+    JOIN users u ON u.id = a.user_id
+    WHERE a.day BETWEEN $1 AND $2
+    GROUP BY a.user_id, u.username, u.email
+    ORDER BY SUM(a.input_tokens + a.output_tokens) DESC, a.user_id
+    LIMIT $3 OFFSET $4`
+	if err := s.db.SelectContext(ctx, &users, query, from, to, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return NewPaginatedList(users, totalCount, limit, offset)
+}
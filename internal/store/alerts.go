@@ -0,0 +1,393 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	AlertSourceEbird = "ebird"
+	AlertSourcePost  = "post"
+
+	AlertReasonNotable   = "notable"
+	AlertReasonWatchlist = "watchlist"
+)
+
+// AlertSettings is where and how a user receives rare bird alerts. Alerts
+// cover the user's bookmarked hotspots when UseBookmarks is set and the home
+// radius when a home location is set. Quiet hours are local hours in
+// Timezone; alerts raised during them are delivered when they end.
+type AlertSettings struct {
+	UserID          int64      `json:"-" db:"user_id"`
+	Enabled         bool       `json:"enabled" db:"enabled"`
+	NotifyNotable   bool       `json:"notify_notable" db:"notify_notable"`
+	UseBookmarks    bool       `json:"use_bookmarks" db:"use_bookmarks"`
+	HomeLatitude    *float64   `json:"home_latitude" db:"home_latitude"`
+	HomeLongitude   *float64   `json:"home_longitude" db:"home_longitude"`
+	HomeRadiusKm    int        `json:"home_radius_km" db:"home_radius_km"`
+	EmailDigest     bool       `json:"email_digest" db:"email_digest"`
+	QuietHoursStart *int       `json:"quiet_hours_start" db:"quiet_hours_start"`
+	QuietHoursEnd   *int       `json:"quiet_hours_end" db:"quiet_hours_end"`
+	Timezone        string     `json:"timezone" db:"timezone"`
+	MaxAlertsPerDay int        `json:"max_alerts_per_day" db:"max_alerts_per_day"`
+	LastDigestAt    *time.Time `json:"last_digest_at" db:"last_digest_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// DefaultAlertSettings are the settings of a user who has not saved any;
+// alerts stay off until the user turns them on.
+func DefaultAlertSettings(userID int64) *AlertSettings {
+	return &AlertSettings{
+		UserID:          userID,
+		NotifyNotable:   true,
+		UseBookmarks:    true,
+		HomeRadiusKm:    10,
+		EmailDigest:     true,
+		Timezone:        "UTC",
+		MaxAlertsPerDay: 10,
+	}
+}
+
+// WatchlistEntry is a species on a user's watchlist, named from the local
+// taxonomy when the code is known.
+type WatchlistEntry struct {
+	SpeciesCode    string    `json:"species_code" db:"species_code"`
+	CommonName     *string   `json:"common_name" db:"common_name"`
+	ScientificName *string   `json:"scientific_name" db:"scientific_name"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// RareBirdAlert is a sighting raised for a user. DedupeKey identifies the
+// sighting, so that a bird reported on many checklists is raised only once.
+type RareBirdAlert struct {
+	ID           int64      `json:"id" db:"id"`
+	UserID       int64      `json:"-" db:"user_id"`
+	DedupeKey    string     `json:"-" db:"dedupe_key"`
+	Source       string     `json:"source" db:"source"`
+	Reason       string     `json:"reason" db:"reason"`
+	SpeciesCode  string     `json:"species_code" db:"species_code"`
+	CommonName   string     `json:"common_name" db:"common_name"`
+	LocationName string     `json:"location_name" db:"location_name"`
+	LocID        *string    `json:"loc_id,omitempty" db:"loc_id"`
+	PostID       *int64     `json:"post_id,omitempty" db:"post_id"`
+	Latitude     float64    `json:"latitude" db:"latitude"`
+	Longitude    float64    `json:"longitude" db:"longitude"`
+	HowMany      *int       `json:"how_many" db:"how_many"`
+	ObservedAt   time.Time  `json:"observed_at" db:"observed_at"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	NotifiedAt   *time.Time `json:"notified_at" db:"notified_at"`
+	EmailedAt    *time.Time `json:"emailed_at" db:"emailed_at"`
+}
+
+type AlertStore struct {
+	db *sqlx.DB
+}
+
+const alertSettingsColumns = `user_id, enabled, notify_notable, use_bookmarks, home_latitude, home_longitude, home_radius_km,
+    email_digest, quiet_hours_start, quiet_hours_end, timezone, max_alerts_per_day, last_digest_at, updated_at`
+
+const alertColumns = `id, user_id, dedupe_key, source, reason, species_code, common_name, location_name, loc_id, post_id,
+    latitude, longitude, how_many, observed_at, created_at, notified_at, emailed_at`
+
+// GetSettings returns a user's alert settings, or DefaultAlertSettings when
+// none are saved.
+func (s *AlertStore) GetSettings(ctx context.Context, userID int64) (*AlertSettings, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var settings AlertSettings
+	err := s.db.GetContext(ctx, &settings, `SELECT `+alertSettingsColumns+` FROM alert_settings WHERE user_id = $1`, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return DefaultAlertSettings(userID), nil
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// SaveSettings stores a user's alert settings. LastDigestAt is kept as is.
+func (s *AlertStore) SaveSettings(ctx context.Context, settings *AlertSettings) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+    INSERT INTO alert_settings (user_id, enabled, notify_notable, use_bookmarks, home_latitude, home_longitude, home_radius_km,
+        email_digest, quiet_hours_start, quiet_hours_end, timezone, max_alerts_per_day)
+    VALUES (:user_id, :enabled, :notify_notable, :use_bookmarks, :home_latitude, :home_longitude, :home_radius_km,
+        :email_digest, :quiet_hours_start, :quiet_hours_end, :timezone, :max_alerts_per_day)
+    ON CONFLICT (user_id) DO UPDATE SET
+        enabled = EXCLUDED.enabled,
+        notify_notable = EXCLUDED.notify_notable,
+        use_bookmarks = EXCLUDED.use_bookmarks,
+        home_latitude = EXCLUDED.home_latitude,
+        home_longitude = EXCLUDED.home_longitude,
+        home_radius_km = EXCLUDED.home_radius_km,
+        email_digest = EXCLUDED.email_digest,
+        quiet_hours_start = EXCLUDED.quiet_hours_start,
+        quiet_hours_end = EXCLUDED.quiet_hours_end,
+        timezone = EXCLUDED.timezone,
+        max_alerts_per_day = EXCLUDED.max_alerts_per_day,
+        updated_at = NOW()
+    RETURNING updated_at, last_digest_at`
+	rows, err := s.db.NamedQueryContext(ctx, query, settings)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		if err := rows.Scan(&settings.UpdatedAt, &settings.LastDigestAt); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ListEnabledSettings returns the settings of every user with alerts on.
+func (s *AlertStore) ListEnabledSettings(ctx context.Context) ([]*AlertSettings, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	settings := []*AlertSettings{}
+	if err := s.db.SelectContext(ctx, &settings, `SELECT `+alertSettingsColumns+` FROM alert_settings WHERE enabled ORDER BY user_id`); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (s *AlertStore) GetWatchlist(ctx context.Context, userID int64) ([]*WatchlistEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	entries := []*WatchlistEntry{}
+	query := `
+    SELECT w.species_code, t.common_name, t.scientific_name, w.created_at
+    FROM species_watchlists w
+    LEFT JOIN species_taxonomy t ON t.species_code = w.species_code
+    WHERE w.user_id = $1
+    ORDER BY t.taxon_order NULLS LAST, w.species_code`
+	if err := s.db.SelectContext(ctx, &entries, query, userID); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// AddToWatchlist adds a species to a user's watchlist. It is a no-op for
+// species already on it.
+func (s *AlertStore) AddToWatchlist(ctx context.Context, userID int64, speciesCode string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `INSERT INTO species_watchlists (user_id, species_code) VALUES ($1, $2) ON CONFLICT (user_id, species_code) DO NOTHING`
+	_, err := s.db.ExecContext(ctx, query, userID, speciesCode)
+	return err
+}
+
+// RemoveFromWatchlist returns sql.ErrNoRows when the species is not on the
+// user's watchlist.
+func (s *AlertStore) RemoveFromWatchlist(ctx context.Context, userID int64, speciesCode string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `DELETE FROM species_watchlists WHERE user_id = $1 AND species_code = $2`, userID, speciesCode)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CreateAlert raises an alert unless the user already has one with the same
+// DedupeKey. It reports whether the alert was created.
+func (s *AlertStore) CreateAlert(ctx context.Context, alert *RareBirdAlert) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+    INSERT INTO rare_bird_alerts (user_id, dedupe_key, source, reason, species_code, common_name, location_name,
+        loc_id, post_id, latitude, longitude, how_many, observed_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+    ON CONFLICT (user_id, dedupe_key) DO NOTHING
+    RETURNING id, created_at`
+	err := s.db.QueryRowContext(ctx, query,
+		alert.UserID, alert.DedupeKey, alert.Source, alert.Reason, alert.SpeciesCode, alert.CommonName, alert.LocationName,
+		alert.LocID, alert.PostID, alert.Latitude, alert.Longitude, alert.HowMany, alert.ObservedAt,
+	).Scan(&alert.ID, &alert.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// CreatePostAlerts raises alerts for the public posts created since then
+// that tag a species on a watchlist, within the watcher's home radius or
+// within bookmarkRadiusKm of one of their bookmarked hotspots. Posts of
+// sensitive species are placed on the sensitive grid without their location
+// name. It returns how many alerts were raised.
+func (s *AlertStore) CreatePostAlerts(ctx context.Context, since time.Time, bookmarkRadiusKm float64) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := fmt.Sprintf(`
+    INSERT INTO rare_bird_alerts (user_id, dedupe_key, source, reason, species_code, common_name, location_name,
+        post_id, latitude, longitude, observed_at)
+    SELECT s.user_id, 'post:' || p.id, '%[1]s', '%[2]s', p.tagged_species_code,
+           COALESCE(t.common_name, p.tagged_species_code),
+           CASE WHEN COALESCE(t.is_sensitive, FALSE) THEN '' ELSE COALESCE(p.location_name, '') END, p.id,
+           CASE WHEN COALESCE(t.is_sensitive, FALSE) THEN %[3]s ELSE p.latitude::float8 END,
+           CASE WHEN COALESCE(t.is_sensitive, FALSE) THEN %[4]s ELSE p.longitude::float8 END,
+           COALESCE(p.sighting_date, p.created_at)
+    FROM posts p
+    JOIN species_watchlists w ON w.species_code = p.tagged_species_code
+    JOIN alert_settings s ON s.user_id = w.user_id AND s.enabled
+    LEFT JOIN species_taxonomy t ON t.species_code = p.tagged_species_code
+    WHERE p.created_at >= $1
+      AND p.location IS NOT NULL
+      AND p.user_id <> s.user_id
+      AND COALESCE(p.privacy_level, '') IN ('', 'public')
+      AND (
+        (s.home_latitude IS NOT NULL AND s.home_longitude IS NOT NULL
+            AND ST_DWithin(p.location, ST_SetSRID(ST_MakePoint(s.home_longitude, s.home_latitude), 4326)::geography, s.home_radius_km * 1000))
+        OR (s.use_bookmarks AND EXISTS (
+            SELECT 1 FROM user_bookmarks b
+            WHERE b.user_id = s.user_id AND b.latitude IS NOT NULL AND b.longitude IS NOT NULL
+              AND ST_DWithin(p.location, ST_SetSRID(ST_MakePoint(b.longitude, b.latitude), 4326)::geography, $2)))
+      )
+    ON CONFLICT (user_id, dedupe_key) DO NOTHING`,
+		AlertSourcePost, AlertReasonWatchlist,
+		snapToGridSQL("p.latitude", SensitiveGridDegrees), snapToGridSQL("p.longitude", SensitiveGridDegrees))
+
+	result, err := s.db.ExecContext(ctx, query, since, bookmarkRadiusKm*1000)
+	if err != nil {
+		return 0, err
+	}
+	created, err := result.RowsAffected()
+	return int(created), err
+}
+
+// GetPendingAlerts returns a user's alerts raised since then that have not
+// been notified, oldest first.
+func (s *AlertStore) GetPendingAlerts(ctx context.Context, userID int64, since time.Time) ([]*RareBirdAlert, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	alerts := []*RareBirdAlert{}
+	query := `
+    SELECT ` + alertColumns + ` FROM rare_bird_alerts
+    WHERE user_id = $1 AND notified_at IS NULL AND created_at >= $2
+    ORDER BY created_at, id`
+	if err := s.db.SelectContext(ctx, &alerts, query, userID, since); err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// CountNotifiedSince counts a user's alerts notified since then.
+func (s *AlertStore) CountNotifiedSince(ctx context.Context, userID int64, since time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var count int
+	err := s.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM rare_bird_alerts WHERE user_id = $1 AND notified_at >= $2`, userID, since)
+	return count, err
+}
+
+func (s *AlertStore) MarkNotified(ctx context.Context, ids []int64, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query, args, err := sqlx.In(`UPDATE rare_bird_alerts SET notified_at = ? WHERE id IN (?)`, at, ids)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, s.db.Rebind(query), args...)
+	return err
+}
+
+// GetUndigestedAlerts returns a user's alerts raised since then that have
+// not been in an email digest, oldest first.
+func (s *AlertStore) GetUndigestedAlerts(ctx context.Context, userID int64, since time.Time) ([]*RareBirdAlert, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	alerts := []*RareBirdAlert{}
+	query := `
+    SELECT ` + alertColumns + ` FROM rare_bird_alerts
+    WHERE user_id = $1 AND emailed_at IS NULL AND created_at >= $2
+    ORDER BY created_at, id`
+	if err := s.db.SelectContext(ctx, &alerts, query, userID, since); err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// MarkDigested records that alerts were emailed to a user at a time, which
+// also becomes the user's last digest time.
+func (s *AlertStore) MarkDigested(ctx context.Context, userID int64, ids []int64, at time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if len(ids) > 0 {
+		query, args, err := sqlx.In(`UPDATE rare_bird_alerts SET emailed_at = ? WHERE user_id = ? AND id IN (?)`, at, userID, ids)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, tx.Rebind(query), args...); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE alert_settings SET last_digest_at = $1 WHERE user_id = $2`, at, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetAlerts returns a user's alerts, newest first.
+func (s *AlertStore) GetAlerts(ctx context.Context, userID int64, limit, offset int) (*PaginatedList[*RareBirdAlert], error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var totalCount int
+	if err := s.db.GetContext(ctx, &totalCount, `SELECT COUNT(*) FROM rare_bird_alerts WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+
+	alerts := []*RareBirdAlert{}
+	query := `
+    SELECT ` + alertColumns + ` FROM rare_bird_alerts
+    WHERE user_id = $1
+    ORDER BY created_at DESC, id DESC
+    LIMIT $2 OFFSET $3`
+	if err := s.db.SelectContext(ctx, &alerts, query, userID, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return NewPaginatedList(alerts, totalCount, limit, offset)
+}
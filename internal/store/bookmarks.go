@@ -2,91 +2,214 @@ package store
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"log"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
+// TrendingHalfLife is how long it takes a bookmark to count half as much
+// towards a hotspot's trending score.
+const TrendingHalfLife = 48 * time.Hour
+
 type Bookmark struct {
-	ID                int64      `db:"id"`
-	UserID            int64      `db:"user_id"`
-	HotspotLocationId string     `db:"hotspot_location_id"`
-	CreatedAt         time.Time  `db:"created_at"`
-	UpdatedAt         *time.Time `db:"updated_at"`
+	ID                int64      `json:"id" db:"id"`
+	UserID            int64      `json:"user_id" db:"user_id"`
+	HotspotLocationId string     `json:"hotspot_location_id" db:"hotspot_location_id"`
+	HotspotName       *string    `json:"hotspot_name" db:"hotspot_name"`
+	Latitude          *float64   `json:"latitude" db:"latitude"`
+	Longitude         *float64   `json:"longitude" db:"longitude"`
+	Note              *string    `json:"note" db:"note"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         *time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// TrendingHotspot is a hotspot ranked by how quickly it is being bookmarked.
+// Score sums the hotspot's recent bookmarks, each weighted by
+// 0.5^(age/TrendingHalfLife).
+type TrendingHotspot struct {
+	HotspotLocationId string   `json:"hotspot_location_id" db:"hotspot_location_id"`
+	HotspotName       *string  `json:"hotspot_name" db:"hotspot_name"`
+	Latitude          *float64 `json:"latitude" db:"latitude"`
+	Longitude         *float64 `json:"longitude" db:"longitude"`
+	RecentBookmarks   int      `json:"recent_bookmarks" db:"recent_bookmarks"`
+	TotalBookmarks    int      `json:"total_bookmarks" db:"total_bookmarks"`
+	Score             float64  `json:"score" db:"score"`
 }
 
 type BookmarksStore struct {
 	db *sqlx.DB
 }
 
+const bookmarkColumns = `id, user_id, hotspot_location_id, hotspot_name, latitude, longitude, note, created_at, updated_at`
+
+// ErrBookmarkExists is returned by Create when the user has already
+// bookmarked the hotspot.
+var ErrBookmarkExists = errors.New("hotspot already bookmarked")
+
 func (store *BookmarksStore) Create(ctx context.Context, bookmark *Bookmark) error {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
-	query := `INSERT INTO bookmarks (user_id, hotspot_location_id, created_at, updated_at)
-  VALUES (:user_id, :hotspot_location_id);
-  `
-	_, err := store.db.NamedExecContext(ctx, query, bookmark)
+
+	query := `
+    INSERT INTO user_bookmarks (user_id, hotspot_location_id, hotspot_name, latitude, longitude, note)
+    VALUES ($1, $2, $3, $4, $5, $6)
+    ON CONFLICT (user_id, hotspot_location_id) DO NOTHING
+    RETURNING id, created_at`
+	err := store.db.QueryRowContext(ctx, query,
+		bookmark.UserID, bookmark.HotspotLocationId, bookmark.HotspotName, bookmark.Latitude, bookmark.Longitude, bookmark.Note,
+	).Scan(&bookmark.ID, &bookmark.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrBookmarkExists
+	}
+	return err
+}
+
+// Get returns a user's bookmark of a hotspot, or sql.ErrNoRows.
+func (store *BookmarksStore) Get(ctx context.Context, userID int64, hotspotLocationID string) (*Bookmark, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var bookmark Bookmark
+	query := `SELECT ` + bookmarkColumns + ` FROM user_bookmarks WHERE user_id = $1 AND hotspot_location_id = $2`
+	err := store.db.GetContext(ctx, &bookmark, query, userID, hotspotLocationID)
 	if err != nil {
-		return err
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
 	}
+	return &bookmark, nil
+}
 
-	return nil
+// UpdateNote sets the note of a user's bookmark, or clears it when note is
+// nil. It returns sql.ErrNoRows when the user has not bookmarked the hotspot.
+func (store *BookmarksStore) UpdateNote(ctx context.Context, userID int64, hotspotLocationID string, note *string) (*Bookmark, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var bookmark Bookmark
+	query := `
+    UPDATE user_bookmarks SET note = $3, updated_at = NOW()
+    WHERE user_id = $1 AND hotspot_location_id = $2
+    RETURNING ` + bookmarkColumns
+	err := store.db.GetContext(ctx, &bookmark, query, userID, hotspotLocationID, note)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+	return &bookmark, nil
 }
 
-func (store *BookmarksStore) Delete(ctx context.Context, userID int64, hotspotLocationID int64) error {
+// Delete removes a user's bookmark of a hotspot. It returns sql.ErrNoRows
+// when there is none.
+func (store *BookmarksStore) Delete(ctx context.Context, userID int64, hotspotLocationID string) error {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
-	query := `DELETE FROM bookmarks WHERE user_id = :user_id AND hotspot_location_id = :hotspot_location_id`
-	_, err := store.db.NamedExecContext(ctx, query, map[string]any{
-		"user_id":             userID,
-		"hotspot_location_id": hotspotLocationID,
-	})
+
+	query := `DELETE FROM user_bookmarks WHERE user_id = $1 AND hotspot_location_id = $2`
+	result, err := store.db.ExecContext(ctx, query, userID, hotspotLocationID)
 	if err != nil {
 		return err
 	}
 
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
 	return nil
 }
 
-func (store *BookmarksStore) GetByUserID(ctx context.Context, userID int64) ([]*Bookmark, error) {
+// GetByUserID returns a user's bookmarks, newest first.
+func (store *BookmarksStore) GetByUserID(ctx context.Context, userID int64, limit, offset int) (*PaginatedList[*Bookmark], error) {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	var bookmarks []*Bookmark
-	query := `SELECT * FROM bookmarks WHERE user_id = :user_id`
+	var totalCount int
+	if err := store.db.GetContext(ctx, &totalCount, `SELECT COUNT(*) FROM user_bookmarks WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
 
-	err := store.db.SelectContext(ctx, &bookmarks, query, map[string]any{
-		"user_id": userID,
-	})
-	if err != nil {
+	bookmarks := []*Bookmark{}
+	query := `
+    SELECT ` + bookmarkColumns + ` FROM user_bookmarks
+    WHERE user_id = $1
+    ORDER BY created_at DESC, id DESC
+    LIMIT $2 OFFSET $3`
+	if err := store.db.SelectContext(ctx, &bookmarks, query, userID, limit, offset); err != nil {
 		return nil, err
 	}
-	return bookmarks, nil
+
+	return NewPaginatedList(bookmarks, totalCount, limit, offset)
 }
 
-func (store *BookmarksStore) GetTrendingBookmarks(ctx context.Context, limit, offset int) (*PaginatedList[*Bookmark], error) {
+// GetTrendingBookmarks ranks the hotspots bookmarked within window by their
+// decayed bookmark count, so a hotspot bookmarked ten times yesterday ranks
+// above one bookmarked ten times last week.
+func (store *BookmarksStore) GetTrendingBookmarks(ctx context.Context, window time.Duration, limit, offset int) (*PaginatedList[*TrendingHotspot], error) {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	var bookmarks []*Bookmark
-	query := `SELECT * FROM bookmarks ORDER BY created_at DESC LIMIT :limit OFFSET :offset`
-	err := store.db.SelectContext(ctx, &bookmarks, query, map[string]any{
-		"limit":  limit,
-		"offset": offset,
-	})
-	if err != nil {
+	since := time.Now().Add(-window)
+
+	var totalCount int
+	countQuery := `SELECT COUNT(DISTINCT hotspot_location_id) FROM user_bookmarks WHERE created_at >= $1`
+	if err := store.db.GetContext(ctx, &totalCount, countQuery, since); err != nil {
+		return nil, err
+	}
+
+	hotspots := []*TrendingHotspot{}
+	query := `
+    WITH recent AS (
+        SELECT hotspot_location_id,
+               COUNT(*) AS recent_bookmarks,
+               SUM(POWER(0.5, EXTRACT(EPOCH FROM (NOW() - created_at)) / $2)) AS score
+        FROM user_bookmarks
+        WHERE created_at >= $1
+        GROUP BY hotspot_location_id
+    )
+    SELECT r.hotspot_location_id, r.recent_bookmarks, r.score::float8 AS score,
+           latest.hotspot_name, latest.latitude, latest.longitude,
+           (SELECT COUNT(*) FROM user_bookmarks b WHERE b.hotspot_location_id = r.hotspot_location_id) AS total_bookmarks
+    FROM recent r
+    LEFT JOIN LATERAL (
+        SELECT b.hotspot_name, b.latitude, b.longitude
+        FROM user_bookmarks b
+        WHERE b.hotspot_location_id = r.hotspot_location_id AND b.hotspot_name IS NOT NULL
+        ORDER BY b.created_at DESC
+        LIMIT 1
+    ) latest ON TRUE
+    ORDER BY r.score DESC, r.hotspot_location_id
+    LIMIT $3 OFFSET $4`
+	if err := store.db.SelectContext(ctx, &hotspots, query, since, TrendingHalfLife.Seconds(), limit, offset); err != nil {
 		return nil, err
 	}
 
-	return &PaginatedList[*Bookmark]{Items: bookmarks}, nil
+	return NewPaginatedList(hotspots, totalCount, limit, offset)
+}
+
+// CountByHotspot returns how many users have bookmarked a hotspot.
+func (store *BookmarksStore) CountByHotspot(ctx context.Context, hotspotLocationID string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var count int
+	err := store.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM user_bookmarks WHERE hotspot_location_id = $1`, hotspotLocationID)
+	return count, err
 }
 
 func (store *BookmarksStore) Exists(ctx context.Context, userID int64, hotspotLocationID string) (bool, error) {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	query := `SELECT EXISTS(SELECT 1 FROM bookmarks WHERE user_id=$1 AND hotspot_location_id=$2);`
+	query := `SELECT EXISTS(SELECT 1 FROM user_bookmarks WHERE user_id=$1 AND hotspot_location_id=$2);`
 	var exists bool
 
 	err := store.db.GetContext(ctx, &exists, query, userID, hotspotLocationID)
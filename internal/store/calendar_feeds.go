@@ -0,0 +1,50 @@
+package store
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CalendarFeedStore keeps the secret token behind each user's subscribed calendar URL.
+type CalendarFeedStore struct {
+	db *sqlx.DB
+}
+
+// GetToken returns the user's feed token, or sql.ErrNoRows if none has been issued.
+func (s *CalendarFeedStore) GetToken(ctx context.Context, userID int64) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var token string
+	query := `SELECT token FROM calendar_feed_tokens WHERE user_id = $1`
+	err := s.db.GetContext(ctx, &token, query, userID)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// SetToken issues or replaces the user's feed token. Replacing it revokes the old URL.
+func (s *CalendarFeedStore) SetToken(ctx context.Context, userID int64, token string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `INSERT INTO calendar_feed_tokens (user_id, token) VALUES ($1, $2)
+              ON CONFLICT (user_id) DO UPDATE SET token = EXCLUDED.token, created_at = NOW()`
+	_, err := s.db.ExecContext(ctx, query, userID, token)
+	return err
+}
+
+func (s *CalendarFeedStore) GetUserIDByToken(ctx context.Context, token string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var userID int64
+	query := `SELECT user_id FROM calendar_feed_tokens WHERE token = $1`
+	err := s.db.GetContext(ctx, &userID, query, token)
+	if err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
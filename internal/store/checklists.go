@@ -0,0 +1,308 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	ChecklistProtocolStationary = "stationary"
+	ChecklistProtocolTraveling  = "traveling"
+	ChecklistProtocolIncidental = "incidental"
+)
+
+// Checklist is a single birding outing. Its species counts are stored as
+// observations that share the checklist's location and start time.
+type Checklist struct {
+	ID                int64          `json:"id" db:"id"`
+	UserID            int64          `json:"user_id" db:"user_id"`
+	ExternalID        *string        `json:"external_id,omitempty" db:"external_id"`
+	LocationName      string         `json:"location_name" db:"location_name"`
+	Latitude          *float64       `json:"latitude,omitempty" db:"latitude"`
+	Longitude         *float64       `json:"longitude,omitempty" db:"longitude"`
+	StateCode         *string        `json:"state_code,omitempty" db:"state_code"`
+	CountryCode       *string        `json:"country_code,omitempty" db:"country_code"`
+	StartedAt         time.Time      `json:"started_at" db:"started_at"`
+	DurationMinutes   *int           `json:"duration_minutes,omitempty" db:"duration_minutes"`
+	DistanceKm        *float64       `json:"distance_km,omitempty" db:"distance_km"`
+	Protocol          string         `json:"protocol" db:"protocol"`
+	NumberOfObservers int            `json:"number_of_observers" db:"number_of_observers"`
+	IsComplete        bool           `json:"is_complete" db:"is_complete"`
+	Comments          *string        `json:"comments,omitempty" db:"comments"`
+	SpeciesCount      int            `json:"species_count" db:"species_count"`
+	Observations      []*Observation `json:"observations,omitempty" db:"-"`
+	CreatedAt         time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt         *time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+type ChecklistStore struct {
+	db *sqlx.DB
+}
+
+const checklistColumns = `c.id, c.user_id, c.external_id, c.location_name, c.latitude, c.longitude, c.state_code, c.country_code,
+    c.started_at, c.duration_minutes, c.distance_km, c.protocol, c.number_of_observers, c.is_complete, c.comments,
+    (SELECT COUNT(DISTINCT o.species_code) FROM observations o WHERE o.checklist_id = c.id) AS species_count,
+    c.created_at, c.updated_at`
+
+// Create inserts the checklist and its observations in one transaction.
+func (s *ChecklistStore) Create(ctx context.Context, checklist *Checklist) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+    INSERT INTO checklists (user_id, external_id, location_name, latitude, longitude, state_code, country_code, started_at,
+        duration_minutes, distance_km, protocol, number_of_observers, is_complete, comments)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+    RETURNING id, created_at`
+	err = tx.QueryRowContext(ctx, query,
+		checklist.UserID,
+		checklist.ExternalID,
+		checklist.LocationName,
+		checklist.Latitude,
+		checklist.Longitude,
+		checklist.StateCode,
+		checklist.CountryCode,
+		checklist.StartedAt,
+		checklist.DurationMinutes,
+		checklist.DistanceKm,
+		checklist.Protocol,
+		checklist.NumberOfObservers,
+		checklist.IsComplete,
+		checklist.Comments,
+	).Scan(&checklist.ID, &checklist.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := insertChecklistObservations(ctx, tx, checklist); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	checklist.SpeciesCount = countSpecies(checklist.Observations)
+	return nil
+}
+
+// GetByID returns the checklist with its observations.
+func (s *ChecklistStore) GetByID(ctx context.Context, id int64) (*Checklist, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var checklist Checklist
+	query := `SELECT ` + checklistColumns + ` FROM checklists c WHERE c.id = $1`
+	err := s.db.GetContext(ctx, &checklist, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+
+	checklist.Observations = []*Observation{}
+	obsQuery := `SELECT ` + observationColumns + ` FROM observations WHERE checklist_id = $1 ORDER BY id`
+	if err := s.db.SelectContext(ctx, &checklist.Observations, obsQuery, id); err != nil {
+		return nil, err
+	}
+	return &checklist, nil
+}
+
+// ExistsByExternalID reports whether the user already has a checklist
+// imported with the given external (eBird submission) ID.
+func (s *ChecklistStore) ExistsByExternalID(ctx context.Context, userID int64, externalID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM checklists WHERE user_id = $1 AND external_id = $2)`
+	err := s.db.GetContext(ctx, &exists, query, userID, externalID)
+	return exists, err
+}
+
+// Update saves the checklist header and copies its location and start time
+// onto its observations. When replaceObservations is set, the checklist's
+// observations are replaced with checklist.Observations.
+func (s *ChecklistStore) Update(ctx context.Context, checklist *Checklist, replaceObservations bool) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+    UPDATE checklists
+    SET location_name = $1, latitude = $2, longitude = $3, state_code = $4, country_code = $5, started_at = $6,
+        duration_minutes = $7, distance_km = $8, protocol = $9, number_of_observers = $10, is_complete = $11,
+        comments = $12, updated_at = NOW()
+    WHERE id = $13
+    RETURNING updated_at`
+	err = tx.QueryRowContext(ctx, query,
+		checklist.LocationName,
+		checklist.Latitude,
+		checklist.Longitude,
+		checklist.StateCode,
+		checklist.CountryCode,
+		checklist.StartedAt,
+		checklist.DurationMinutes,
+		checklist.DistanceKm,
+		checklist.Protocol,
+		checklist.NumberOfObservers,
+		checklist.IsComplete,
+		checklist.Comments,
+		checklist.ID,
+	).Scan(&checklist.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sql.ErrNoRows
+		}
+		return err
+	}
+
+	if replaceObservations {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM observations WHERE checklist_id = $1`, checklist.ID); err != nil {
+			return err
+		}
+		if err := insertChecklistObservations(ctx, tx, checklist); err != nil {
+			return err
+		}
+	} else {
+		syncQuery := `
+        UPDATE observations
+        SET latitude = $1, longitude = $2, location_name = $3, country_code = $4, observed_at = $5, updated_at = NOW()
+        WHERE checklist_id = $6`
+		_, err := tx.ExecContext(ctx, syncQuery, checklist.Latitude, checklist.Longitude, checklist.LocationName, checklist.CountryCode, checklist.StartedAt, checklist.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if replaceObservations {
+		checklist.SpeciesCount = countSpecies(checklist.Observations)
+	}
+	return nil
+}
+
+// Delete removes the checklist; its observations are removed by the foreign key cascade.
+func (s *ChecklistStore) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `DELETE FROM checklists WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetByUserID lists a user's checklists, newest first, without their observations.
+func (s *ChecklistStore) GetByUserID(ctx context.Context, userID int64, limit, offset int) (*PaginatedList[*Checklist], error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var totalCount int
+	if err := s.db.GetContext(ctx, &totalCount, `SELECT COUNT(*) FROM checklists WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+
+	checklists := []*Checklist{}
+	query := `SELECT ` + checklistColumns + ` FROM checklists c
+              WHERE c.user_id = $1
+              ORDER BY c.started_at DESC, c.id DESC
+              LIMIT $2 OFFSET $3`
+	if err := s.db.SelectContext(ctx, &checklists, query, userID, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return NewPaginatedList(checklists, totalCount, limit, offset)
+}
+
+// GetWithObservationsByUserID returns every checklist started within [from, to]
+// with its observations attached, oldest first. It is used for exports.
+func (s *ChecklistStore) GetWithObservationsByUserID(ctx context.Context, userID int64, from, to time.Time) ([]*Checklist, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	checklists := []*Checklist{}
+	query := `SELECT ` + checklistColumns + ` FROM checklists c
+              WHERE c.user_id = $1 AND c.started_at BETWEEN $2 AND $3
+              ORDER BY c.started_at, c.id`
+	if err := s.db.SelectContext(ctx, &checklists, query, userID, from, to); err != nil {
+		return nil, err
+	}
+	if len(checklists) == 0 {
+		return checklists, nil
+	}
+
+	ids := make([]int64, len(checklists))
+	byID := make(map[int64]*Checklist, len(checklists))
+	for i, c := range checklists {
+		ids[i] = c.ID
+		byID[c.ID] = c
+		c.Observations = []*Observation{}
+	}
+
+	obsQuery, args, err := sqlx.In(`SELECT `+observationColumns+` FROM observations WHERE checklist_id IN (?) ORDER BY id`, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var observations []*Observation
+	if err := s.db.SelectContext(ctx, &observations, s.db.Rebind(obsQuery), args...); err != nil {
+		return nil, err
+	}
+	for _, obs := range observations {
+		if c, ok := byID[*obs.ChecklistID]; ok {
+			c.Observations = append(c.Observations, obs)
+		}
+	}
+	return checklists, nil
+}
+
+func insertChecklistObservations(ctx context.Context, tx *sqlx.Tx, checklist *Checklist) error {
+	for _, obs := range checklist.Observations {
+		obs.UserID = checklist.UserID
+		obs.ChecklistID = &checklist.ID
+		obs.Latitude = checklist.Latitude
+		obs.Longitude = checklist.Longitude
+		obs.LocationName = &checklist.LocationName
+		obs.CountryCode = checklist.CountryCode
+		obs.ObservedAt = checklist.StartedAt
+		if err := insertObservation(ctx, tx, obs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func countSpecies(observations []*Observation) int {
+	seen := make(map[string]bool, len(observations))
+	for _, obs := range observations {
+		seen[obs.SpeciesCode] = true
+	}
+	return len(seen)
+}
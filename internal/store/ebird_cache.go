@@ -0,0 +1,321 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// CachedHotspot is an eBird hotspot whose historic observations are cached.
+type CachedHotspot struct {
+	LocID           string     `json:"loc_id" db:"loc_id"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	LastRefreshedAt *time.Time `json:"last_refreshed_at" db:"last_refreshed_at"`
+}
+
+// HistoricObservation is one cached eBird historic observation.
+type HistoricObservation struct {
+	LocID       string    `db:"loc_id"`
+	ObsDate     time.Time `db:"obs_date"`
+	SpeciesCode string    `db:"species_code"`
+	ObsTime     *string   `db:"obs_time"` // "15:04", nil when eBird has no time
+	HowMany     *int      `db:"how_many"`
+}
+
+// HistoricActivity aggregates cached observations. Observations without a
+// time count towards ByMonth only.
+type HistoricActivity struct {
+	Observations int
+	ByMonth      map[time.Month]int
+	ByHour       map[int]int
+}
+
+// WeeklyPresence counts the days with observations in a week of the year,
+// and the days among them on which a species was reported.
+type WeeklyPresence struct {
+	Week         int `db:"week"` // 1-48, see GetWeeklyPresence
+	SurveyedDays int `db:"surveyed_days"`
+	DetectedDays int `db:"detected_days"`
+}
+
+// MonthlySpeciesPresence counts the days with observations in a month, and
+// the days among them on which a species was reported.
+type MonthlySpeciesPresence struct {
+	Month        time.Month `db:"month"`
+	SpeciesCode  string     `db:"species_code"`
+	DetectedDays int        `db:"detected_days"`
+	SurveyedDays int        `db:"surveyed_days"`
+}
+
+// HotspotVisitingTimes is a stored analysis. Analysis is the JSON document
+// served to clients; SpeciesCode is empty for the all-species analysis.
+type HotspotVisitingTimes struct {
+	LocID       string    `db:"loc_id"`
+	SpeciesCode string    `db:"species_code"`
+	Analysis    []byte    `db:"analysis"`
+	DaysCovered int       `db:"days_covered"`
+	DaysMissing int       `db:"days_missing"`
+	ComputedAt  time.Time `db:"computed_at"`
+}
+
+type EbirdCacheStore struct {
+	db *sqlx.DB
+}
+
+// TrackHotspot starts caching a hotspot. It is a no-op for tracked hotspots.
+func (s *EbirdCacheStore) TrackHotspot(ctx context.Context, locID string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `INSERT INTO ebird_cached_hotspots (loc_id) VALUES ($1) ON CONFLICT (loc_id) DO NOTHING`, locID)
+	return err
+}
+
+func (s *EbirdCacheStore) GetHotspot(ctx context.Context, locID string) (*CachedHotspot, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var hotspot CachedHotspot
+	err := s.db.GetContext(ctx, &hotspot, `SELECT loc_id, created_at, last_refreshed_at FROM ebird_cached_hotspots WHERE loc_id = $1`, locID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+	return &hotspot, nil
+}
+
+// ListHotspots returns the tracked hotspots, least recently refreshed first.
+func (s *EbirdCacheStore) ListHotspots(ctx context.Context) ([]*CachedHotspot, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	hotspots := []*CachedHotspot{}
+	query := `SELECT loc_id, created_at, last_refreshed_at FROM ebird_cached_hotspots ORDER BY last_refreshed_at NULLS FIRST`
+	if err := s.db.SelectContext(ctx, &hotspots, query); err != nil {
+		return nil, err
+	}
+	return hotspots, nil
+}
+
+func (s *EbirdCacheStore) MarkRefreshed(ctx context.Context, locID string, at time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `UPDATE ebird_cached_hotspots SET last_refreshed_at = $1 WHERE loc_id = $2`, at, locID)
+	return err
+}
+
+// GetCachedDates returns the days between from and to, inclusive, that are
+// already cached, keyed as "2006-01-02".
+func (s *EbirdCacheStore) GetCachedDates(ctx context.Context, locID string, from, to time.Time) (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var dates []string
+	query := `SELECT TO_CHAR(obs_date, 'YYYY-MM-DD') FROM ebird_historic_days WHERE loc_id = $1 AND obs_date BETWEEN $2::date AND $3::date`
+	if err := s.db.SelectContext(ctx, &dates, query, locID, from.Format("2006-01-02"), to.Format("2006-01-02")); err != nil {
+		return nil, err
+	}
+
+	cached := make(map[string]bool, len(dates))
+	for _, date := range dates {
+		cached[date] = true
+	}
+	return cached, nil
+}
+
+// SaveHistoricDay caches one day of a hotspot's observations, which may be
+// empty, replacing anything cached for that day.
+func (s *EbirdCacheStore) SaveHistoricDay(ctx context.Context, locID string, date time.Time, observations []*HistoricObservation) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	day := date.Format("2006-01-02")
+	query := `
+    INSERT INTO ebird_historic_days (loc_id, obs_date) VALUES ($1, $2::date)
+    ON CONFLICT (loc_id, obs_date) DO UPDATE SET fetched_at = NOW()`
+	if _, err := tx.ExecContext(ctx, query, locID, day); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM ebird_historic_observations WHERE loc_id = $1 AND obs_date = $2::date`, locID, day); err != nil {
+		return err
+	}
+
+	if len(observations) > 0 {
+		for _, obs := range observations {
+			obs.LocID = locID
+			obs.ObsDate = date
+		}
+		insert := `
+        INSERT INTO ebird_historic_observations (loc_id, obs_date, species_code, obs_time, how_many)
+        VALUES (:loc_id, :obs_date, :species_code, :obs_time, :how_many)`
+		if _, err := tx.NamedExecContext(ctx, insert, observations); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetHistoricActivity counts the cached observations of hotspots between
+// from and to, inclusive, by month and hour. An empty speciesCode counts all species.
+func (s *EbirdCacheStore) GetHistoricActivity(ctx context.Context, locIDs []string, speciesCode string, from, to time.Time) (*HistoricActivity, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var rows []struct {
+		Month int  `db:"month"`
+		Hour  *int `db:"hour"`
+		Count int  `db:"count"`
+	}
+	query := `
+    SELECT EXTRACT(MONTH FROM obs_date)::int AS month, EXTRACT(HOUR FROM obs_time)::int AS hour, COUNT(*) AS count
+    FROM ebird_historic_observations
+    WHERE loc_id = ANY($1) AND obs_date BETWEEN $2::date AND $3::date AND ($4 = '' OR species_code = $4)
+    GROUP BY 1, 2`
+	if err := s.db.SelectContext(ctx, &rows, query, pq.Array(locIDs), from.Format("2006-01-02"), to.Format("2006-01-02"), speciesCode); err != nil {
+		return nil, err
+	}
+
+	activity := &HistoricActivity{ByMonth: map[time.Month]int{}, ByHour: map[int]int{}}
+	for _, row := range rows {
+		activity.Observations += row.Count
+		activity.ByMonth[time.Month(row.Month)] += row.Count
+		if row.Hour != nil {
+			activity.ByHour[*row.Hour] += row.Count
+		}
+	}
+	return activity, nil
+}
+
+// GetWeeklyPresence counts, for each week of the year, the days with cached
+// observations at the hotspots and the days on which the species was among
+// them. A day at each hotspot counts once. Weeks follow eBird's bar charts:
+// each month has four, starting on days 1, 8, 15 and 22, for 48 in a year.
+func (s *EbirdCacheStore) GetWeeklyPresence(ctx context.Context, locIDs []string, speciesCode string, from, to time.Time) ([]*WeeklyPresence, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	presence := []*WeeklyPresence{}
+	query := `
+    WITH surveyed AS (
+        SELECT loc_id, obs_date, BOOL_OR(species_code = $4) AS detected
+        FROM ebird_historic_observations
+        WHERE loc_id = ANY($1) AND obs_date BETWEEN $2::date AND $3::date
+        GROUP BY loc_id, obs_date
+    )
+    SELECT ` + ebirdWeekSQL("obs_date") + ` AS week,
+           COUNT(*) AS surveyed_days,
+           COUNT(*) FILTER (WHERE detected) AS detected_days
+    FROM surveyed
+    GROUP BY 1
+    ORDER BY 1`
+	if err := s.db.SelectContext(ctx, &presence, query, pq.Array(locIDs), from.Format("2006-01-02"), to.Format("2006-01-02"), speciesCode); err != nil {
+		return nil, err
+	}
+	return presence, nil
+}
+
+// GetTopSpeciesByMonth returns, for each month, the perMonth species reported
+// on the most days with cached observations at the hotspots.
+func (s *EbirdCacheStore) GetTopSpeciesByMonth(ctx context.Context, locIDs []string, from, to time.Time, perMonth int) ([]*MonthlySpeciesPresence, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	presence := []*MonthlySpeciesPresence{}
+	query := `
+    WITH observations AS (
+        SELECT loc_id, obs_date, species_code, EXTRACT(MONTH FROM obs_date)::int AS month
+        FROM ebird_historic_observations
+        WHERE loc_id = ANY($1) AND obs_date BETWEEN $2::date AND $3::date
+    ),
+    surveyed AS (
+        SELECT month, COUNT(DISTINCT (loc_id, obs_date)) AS surveyed_days
+        FROM observations
+        GROUP BY month
+    ),
+    detected AS (
+        SELECT month, species_code, COUNT(DISTINCT (loc_id, obs_date)) AS detected_days
+        FROM observations
+        GROUP BY month, species_code
+    ),
+    ranked AS (
+        SELECT d.month, d.species_code, d.detected_days, s.surveyed_days,
+               ROW_NUMBER() OVER (PARTITION BY d.month ORDER BY d.detected_days DESC, d.species_code) AS rank
+        FROM detected d
+        JOIN surveyed s ON s.month = d.month
+    )
+    SELECT month, species_code, detected_days, surveyed_days
+    FROM ranked
+    WHERE rank <= $4
+    ORDER BY month, rank`
+	if err := s.db.SelectContext(ctx, &presence, query, pq.Array(locIDs), from.Format("2006-01-02"), to.Format("2006-01-02"), perMonth); err != nil {
+		return nil, err
+	}
+	return presence, nil
+}
+
+// ebirdWeekSQL returns SQL for the eBird bar-chart week, 1 to 48, of a date.
+func ebirdWeekSQL(column string) string {
+	return fmt.Sprintf("((EXTRACT(MONTH FROM %[1]s)::int - 1) * 4 + LEAST((EXTRACT(DAY FROM %[1]s)::int - 1) / 7, 3) + 1)", column)
+}
+
+func (s *EbirdCacheStore) GetVisitingTimes(ctx context.Context, locID, speciesCode string) (*HotspotVisitingTimes, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var vt HotspotVisitingTimes
+	query := `
+    SELECT loc_id, species_code, analysis, days_covered, days_missing, computed_at
+    FROM hotspot_visiting_times WHERE loc_id = $1 AND species_code = $2`
+	err := s.db.GetContext(ctx, &vt, query, locID, speciesCode)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+	return &vt, nil
+}
+
+// GetVisitingTimesSpecies returns the species codes, the empty one included, that have
+// a stored analysis for the hotspot.
+func (s *EbirdCacheStore) GetVisitingTimesSpecies(ctx context.Context, locID string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	codes := []string{}
+	if err := s.db.SelectContext(ctx, &codes, `SELECT species_code FROM hotspot_visiting_times WHERE loc_id = $1`, locID); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *EbirdCacheStore) SaveVisitingTimes(ctx context.Context, vt *HotspotVisitingTimes) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+    INSERT INTO hotspot_visiting_times (loc_id, species_code, analysis, days_covered, days_missing, computed_at)
+    VALUES ($1, $2, $3, $4, $5, $6)
+    ON CONFLICT (loc_id, species_code) DO UPDATE SET
+        analysis = EXCLUDED.analysis,
+        days_covered = EXCLUDED.days_covered,
+        days_missing = EXCLUDED.days_missing,
+        computed_at = EXCLUDED.computed_at`
+	_, err := s.db.ExecContext(ctx, query, vt.LocID, vt.SpeciesCode, vt.Analysis, vt.DaysCovered, vt.DaysMissing, vt.ComputedAt)
+	return err
+}
@@ -17,8 +17,30 @@ type Event struct {
 	EndDate       time.Time  `json:"end_date" db:"end_date"`
 	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt     *time.Time `json:"updated_at" db:"updated_at"`
+	// RSVP counts are aggregated from event_rsvps.
+	GoingCount      int `json:"going_count" db:"going_count"`
+	InterestedCount int `json:"interested_count" db:"interested_count"`
 }
 
+const (
+	RSVPGoing      = "going"
+	RSVPInterested = "interested"
+)
+
+// EventRSVP is a user's response to an event.
+type EventRSVP struct {
+	EventID   int64      `json:"event_id" db:"event_id"`
+	UserID    int64      `json:"user_id" db:"user_id"`
+	Status    string     `json:"status" db:"status"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// eventColumns selects an event and its RSVP counts. Queries using it must alias events as e.
+const eventColumns = `e.id, e.title, e.description, e.cover_photo_url, e.start_date, e.end_date, e.created_at, e.updated_at,
+    (SELECT COUNT(*) FROM event_rsvps r WHERE r.event_id = e.id AND r.status = 'going') AS going_count,
+    (SELECT COUNT(*) FROM event_rsvps r WHERE r.event_id = e.id AND r.status = 'interested') AS interested_count`
+
 type EventStore struct {
 	db *sqlx.DB
 }
@@ -31,8 +53,8 @@ func (s *EventStore) GetByID(ctx context.Context, id int64) (*Event, error) {
 	log.Println("GetByID event id", id)
 
 	query := `
-  SELECT * FROM events
-  WHERE id = $1
+  SELECT ` + eventColumns + ` FROM events e
+  WHERE e.id = $1
   `
 	err := s.db.GetContext(ctx, &event, query, id)
 	if err != nil {
@@ -50,9 +72,9 @@ func (s *EventStore) GetAll(ctx context.Context, limit, offset int) (*PaginatedL
 	var events []*Event
 
 	query := `
-    SELECT id, title, description, cover_photo_url, start_date, end_date, created_at, updated_at
-    FROM events
-    ORDER BY created_at DESC
+    SELECT ` + eventColumns + `
+    FROM events e
+    ORDER BY e.created_at DESC
     LIMIT $1 OFFSET $2
   `
 
@@ -74,22 +96,56 @@ func (s *EventStore) GetAll(ctx context.Context, limit, offset int) (*PaginatedL
 }
 
 func (s *EventStore) Create(ctx context.Context, event *Event) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
 	query := `
-    INSERT INTO events (title, description, start_date, end_date)
-    VALUES (:title, :description, :start_date, :end_date)
+    INSERT INTO events (title, description, cover_photo_url, start_date, end_date)
+    VALUES ($1, $2, $3, $4, $5)
+    RETURNING id, created_at
   `
-	result, err := s.db.NamedExecContext(ctx, query, event)
+	err := s.db.QueryRowContext(ctx, query,
+		event.Title,
+		event.Description,
+		event.CoverPhotoUrl,
+		event.StartDate,
+		event.EndDate,
+	).Scan(&event.ID, &event.CreatedAt)
 	if err != nil {
 		log.Println("Create event error", err)
 		return err
 	}
 
-	log.Println("the result is", result)
-
 	log.Println("Create event id", event.ID)
 	return nil
 }
 
+func (s *EventStore) Update(ctx context.Context, event *Event) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+    UPDATE events
+    SET title = $1, description = $2, cover_photo_url = $3, start_date = $4, end_date = $5, updated_at = NOW()
+    WHERE id = $6
+    RETURNING updated_at
+  `
+	err := s.db.QueryRowContext(ctx, query,
+		event.Title,
+		event.Description,
+		event.CoverPhotoUrl,
+		event.StartDate,
+		event.EndDate,
+		event.ID,
+	).Scan(&event.UpdatedAt)
+	if err != nil {
+		log.Println("Update event error", err)
+		return err
+	}
+
+	return nil
+}
+
 func (s *EventStore) Delete(ctx context.Context, id int64) error {
 	query := `DELETE FROM events WHERE id = $1`
 	_, err := s.db.ExecContext(ctx, query, id)
@@ -101,3 +157,86 @@ func (s *EventStore) Delete(ctx context.Context, id int64) error {
 	log.Println("Delete event id", id)
 	return nil
 }
+
+// GetInRange returns events overlapping [from, to], ordered by start date.
+func (s *EventStore) GetInRange(ctx context.Context, from, to time.Time, limit, offset int) (*PaginatedList[*Event], error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	events := []*Event{}
+	query := `
+    SELECT ` + eventColumns + `
+    FROM events e
+    WHERE e.start_date <= $2 AND e.end_date >= $1
+    ORDER BY e.start_date ASC
+    LIMIT $3 OFFSET $4
+  `
+	err := s.db.SelectContext(ctx, &events, query, from, to, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	var totalCount int
+	countQuery := `SELECT COUNT(*) FROM events WHERE start_date <= $2 AND end_date >= $1`
+	err = s.db.GetContext(ctx, &totalCount, countQuery, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPaginatedList(events, totalCount, limit, offset)
+}
+
+func (s *EventStore) SetRSVP(ctx context.Context, rsvp *EventRSVP) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+    INSERT INTO event_rsvps (event_id, user_id, status)
+    VALUES ($1, $2, $3)
+    ON CONFLICT (event_id, user_id) DO UPDATE SET status = EXCLUDED.status, updated_at = NOW()
+    RETURNING created_at, updated_at
+  `
+	return s.db.QueryRowContext(ctx, query, rsvp.EventID, rsvp.UserID, rsvp.Status).Scan(&rsvp.CreatedAt, &rsvp.UpdatedAt)
+}
+
+func (s *EventStore) GetRSVP(ctx context.Context, eventID, userID int64) (*EventRSVP, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var rsvp EventRSVP
+	query := `SELECT event_id, user_id, status, created_at, updated_at FROM event_rsvps WHERE event_id = $1 AND user_id = $2`
+	err := s.db.GetContext(ctx, &rsvp, query, eventID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &rsvp, nil
+}
+
+func (s *EventStore) DeleteRSVP(ctx context.Context, eventID, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `DELETE FROM event_rsvps WHERE event_id = $1 AND user_id = $2`
+	_, err := s.db.ExecContext(ctx, query, eventID, userID)
+	return err
+}
+
+// GetRSVPedByUserID returns every event the user is going to or interested in.
+func (s *EventStore) GetRSVPedByUserID(ctx context.Context, userID int64) ([]*Event, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	events := []*Event{}
+	query := `
+    SELECT ` + eventColumns + `
+    FROM events e
+    JOIN event_rsvps rsvp ON rsvp.event_id = e.id
+    WHERE rsvp.user_id = $1
+    ORDER BY e.start_date ASC
+  `
+	err := s.db.SelectContext(ctx, &events, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"fmt"
+	"math"
+)
+
+// SensitiveGridDegrees is the grid that locations of sensitive species are
+// snapped to when shown to anyone but the observer, about 22 km at the equator.
+const SensitiveGridDegrees = 0.2
+
+// sensitiveOffsetMeters is the farthest a snapped location can be from the
+// real one: half of a grid cell's diagonal, measured at the equator.
+var sensitiveOffsetMeters = SensitiveGridDegrees / 2 * math.Sqrt2 * 111320
+
+// snapToGridSQL returns SQL snapping a coordinate expression to the centre of
+// its cell on a grid of the given size in degrees. Snapping to a fixed grid,
+// rather than adding noise, means repeated queries cannot be averaged out.
+func snapToGridSQL(column string, cellDegrees float64) string {
+	return fmt.Sprintf("(FLOOR((%[1]s)::float8 / %[2]g) * %[2]g + %[3]g)", column, cellDegrees, cellDegrees/2)
+}
+
+// SensitiveLocation is implemented by records that reveal where a species was
+// seen. The API obfuscates them for sensitive species unless the viewer is the
+// record's owner or an admin.
+type SensitiveLocation interface {
+	LocationOwnerID() int64
+	// LocationSpeciesCode is empty when the record is not about a species.
+	LocationSpeciesCode() string
+	// ObfuscateLocation snaps the coordinates to the sensitive grid and
+	// drops the location name, which can give the place away by itself.
+	ObfuscateLocation()
+}
+
+// SnapToSensitiveGrid moves a coordinate to the centre of its
+// SensitiveGridDegrees cell. Snapping is idempotent and matches snapToGridSQL.
+func SnapToSensitiveGrid(v float64) float64 {
+	return math.Floor(v/SensitiveGridDegrees)*SensitiveGridDegrees + SensitiveGridDegrees/2
+}
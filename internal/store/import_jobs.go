@@ -0,0 +1,160 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	ImportSourceEbird = "ebird"
+
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+
+	// MaxImportErrors caps how many row errors are kept in a job's report.
+	MaxImportErrors = 500
+)
+
+type ImportRowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// ImportErrors is stored as a JSONB array.
+type ImportErrors []ImportRowError
+
+func (e *ImportErrors) Scan(value any) error {
+	switch v := value.(type) {
+	case nil:
+		*e = ImportErrors{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, e)
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	default:
+		return errors.New("unsupported type for ImportErrors")
+	}
+}
+
+// ImportJob tracks a background import of a user's data from another service.
+type ImportJob struct {
+	ID                  int64        `json:"id" db:"id"`
+	UserID              int64        `json:"user_id" db:"user_id"`
+	Source              string       `json:"source" db:"source"`
+	Filename            *string      `json:"filename,omitempty" db:"filename"`
+	Status              string       `json:"status" db:"status"`
+	TotalRows           int          `json:"total_rows" db:"total_rows"`
+	ProcessedRows       int          `json:"processed_rows" db:"processed_rows"`
+	ChecklistsCreated   int          `json:"checklists_created" db:"checklists_created"`
+	ObservationsCreated int          `json:"observations_created" db:"observations_created"`
+	DuplicateRows       int          `json:"duplicate_rows" db:"duplicate_rows"`
+	ErrorRows           int          `json:"error_rows" db:"error_rows"`
+	Errors              ImportErrors `json:"errors" db:"errors"`
+	FailureReason       *string      `json:"failure_reason,omitempty" db:"failure_reason"`
+	CreatedAt           time.Time    `json:"created_at" db:"created_at"`
+	StartedAt           *time.Time   `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt          *time.Time   `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// AddError records a failed row, keeping at most MaxImportErrors in the report.
+func (j *ImportJob) AddError(line int, message string) {
+	j.ErrorRows++
+	if len(j.Errors) < MaxImportErrors {
+		j.Errors = append(j.Errors, ImportRowError{Line: line, Message: message})
+	}
+}
+
+type ImportJobStore struct {
+	db *sqlx.DB
+}
+
+const importJobColumns = `id, user_id, source, filename, status, total_rows, processed_rows, checklists_created,
+    observations_created, duplicate_rows, error_rows, errors, failure_reason, created_at, started_at, finished_at`
+
+func (s *ImportJobStore) Create(ctx context.Context, job *ImportJob) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	job.Status = ImportStatusPending
+	job.Errors = ImportErrors{}
+	query := `INSERT INTO import_jobs (user_id, source, filename, status)
+              VALUES ($1, $2, $3, $4)
+              RETURNING id, created_at`
+	return s.db.QueryRowContext(ctx, query, job.UserID, job.Source, job.Filename, job.Status).Scan(&job.ID, &job.CreatedAt)
+}
+
+func (s *ImportJobStore) GetByID(ctx context.Context, id int64) (*ImportJob, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var job ImportJob
+	err := s.db.GetContext(ctx, &job, `SELECT `+importJobColumns+` FROM import_jobs WHERE id = $1`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (s *ImportJobStore) GetByUserID(ctx context.Context, userID int64, limit, offset int) (*PaginatedList[*ImportJob], error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var totalCount int
+	if err := s.db.GetContext(ctx, &totalCount, `SELECT COUNT(*) FROM import_jobs WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+
+	jobs := []*ImportJob{}
+	query := `SELECT ` + importJobColumns + ` FROM import_jobs
+              WHERE user_id = $1
+              ORDER BY created_at DESC
+              LIMIT $2 OFFSET $3`
+	if err := s.db.SelectContext(ctx, &jobs, query, userID, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return NewPaginatedList(jobs, totalCount, limit, offset)
+}
+
+// Save writes the job's status, counters and error report.
+func (s *ImportJobStore) Save(ctx context.Context, job *ImportJob) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	errorsJSON, err := json.Marshal(job.Errors)
+	if err != nil {
+		return err
+	}
+
+	query := `
+    UPDATE import_jobs
+    SET status = $1, total_rows = $2, processed_rows = $3, checklists_created = $4, observations_created = $5,
+        duplicate_rows = $6, error_rows = $7, errors = $8, failure_reason = $9, started_at = $10, finished_at = $11
+    WHERE id = $12`
+	_, err = s.db.ExecContext(ctx, query,
+		job.Status,
+		job.TotalRows,
+		job.ProcessedRows,
+		job.ChecklistsCreated,
+		job.ObservationsCreated,
+		job.DuplicateRows,
+		job.ErrorRows,
+		errorsJSON,
+		job.FailureReason,
+		job.StartedAt,
+		job.FinishedAt,
+		job.ID,
+	)
+	return err
+}
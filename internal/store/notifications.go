@@ -25,7 +25,7 @@ type NotificationStore struct {
 // Create inserts a new notification into the database.
 func (s *NotificationStore) Create(ctx context.Context, notification *Notification) error {
 	query := `INSERT INTO notifications (user_id, type, message) VALUES ($1, $2, $3) RETURNING id, created_at`
-	err := s.db.QueryRowContext(ctx, query, notification.UserID, notification.Type, notification.Message).Scan(notification.ID, notification.CreatedAt)
+	err := s.db.QueryRowContext(ctx, query, notification.UserID, notification.Type, notification.Message).Scan(&notification.ID, &notification.CreatedAt)
 	return err
 }
 
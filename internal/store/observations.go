@@ -0,0 +1,327 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Observation is a single species record: what was seen, how many, where and when.
+type Observation struct {
+	ID           int64      `json:"id" db:"id"`
+	UserID       int64      `json:"user_id" db:"user_id"`
+	PostID       *int64     `json:"post_id,omitempty" db:"post_id"`
+	ChecklistID  *int64     `json:"checklist_id,omitempty" db:"checklist_id"`
+	SpeciesCode  string     `json:"species_code" db:"species_code"`
+	Count        *int       `json:"count" db:"count"`
+	Latitude     *float64   `json:"latitude,omitempty" db:"latitude"`
+	Longitude    *float64   `json:"longitude,omitempty" db:"longitude"`
+	LocationName *string    `json:"location_name,omitempty" db:"location_name"`
+	CountryCode  *string    `json:"country_code,omitempty" db:"country_code"`
+	ObservedAt   time.Time  `json:"observed_at" db:"observed_at"`
+	BreedingCode *string    `json:"breeding_code,omitempty" db:"breeding_code"`
+	BehaviorCode *string    `json:"behavior_code,omitempty" db:"behavior_code"`
+	Notes        *string    `json:"notes,omitempty" db:"notes"`
+	IsPrivate    bool       `json:"is_private" db:"is_private"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    *time.Time `json:"updated_at" db:"updated_at"`
+	// LocationObfuscated is set when the coordinates were snapped to a grid
+	// because the species is sensitive.
+	LocationObfuscated bool `json:"location_obfuscated,omitempty" db:"-"`
+}
+
+func (o *Observation) LocationOwnerID() int64      { return o.UserID }
+func (o *Observation) LocationSpeciesCode() string { return o.SpeciesCode }
+
+func (o *Observation) ObfuscateLocation() {
+	if o.LocationName != nil {
+		o.LocationName = nil
+		o.LocationObfuscated = true
+	}
+	if o.Latitude == nil || o.Longitude == nil {
+		return
+	}
+	lat, lng := SnapToSensitiveGrid(*o.Latitude), SnapToSensitiveGrid(*o.Longitude)
+	o.Latitude, o.Longitude = &lat, &lng
+	o.LocationObfuscated = true
+}
+
+// ObservationFilter narrows GetByUserID. Zero values are ignored.
+type ObservationFilter struct {
+	SpeciesCode string
+	ChecklistID *int64
+	From        *time.Time
+	To          *time.Time
+}
+
+// BreedingCodes are the eBird breeding and atlas codes accepted on observations.
+var BreedingCodes = map[string]string{
+	"F":  "Flyover",
+	"H":  "In appropriate habitat",
+	"S":  "Singing bird",
+	"S7": "Singing bird present 7+ days",
+	"M":  "Multiple (7+) singing birds",
+	"P":  "Pair in suitable habitat",
+	"T":  "Territorial defense",
+	"C":  "Courtship, display, or copulation",
+	"N":  "Visiting probable nest site",
+	"A":  "Agitated behavior",
+	"B":  "Wren/woodpecker nest building",
+	"PE": "Physiological evidence",
+	"CN": "Carrying nesting material",
+	"NB": "Nest building",
+	"DD": "Distraction display",
+	"UN": "Used nest",
+	"ON": "Occupied nest",
+	"CF": "Carrying food",
+	"FY": "Feeding young",
+	"FS": "Carrying fecal sac",
+	"FL": "Recently fledged young",
+	"NE": "Nest with eggs",
+	"NY": "Nest with young",
+}
+
+// BehaviorCodes are the free-standing behaviors a user can attach to an observation.
+var BehaviorCodes = map[string]bool{
+	"foraging":      true,
+	"feeding":       true,
+	"singing":       true,
+	"calling":       true,
+	"perched":       true,
+	"flying":        true,
+	"flocking":      true,
+	"roosting":      true,
+	"bathing":       true,
+	"preening":      true,
+	"displaying":    true,
+	"nesting":       true,
+	"feeding_young": true,
+	"migrating":     true,
+}
+
+type ObservationStore struct {
+	db *sqlx.DB
+}
+
+const observationColumns = `id, user_id, post_id, checklist_id, species_code, count, latitude, longitude, location_name, country_code, observed_at, breeding_code, behavior_code, notes, is_private, created_at, updated_at`
+
+func (s *ObservationStore) Create(ctx context.Context, obs *Observation) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return insertObservation(ctx, s.db, obs)
+}
+
+// insertObservation is shared with the checklist store, which inserts a
+// checklist's observations inside its own transaction.
+func insertObservation(ctx context.Context, q sqlx.QueryerContext, obs *Observation) error {
+	query := `
+    INSERT INTO observations (user_id, post_id, checklist_id, species_code, count, latitude, longitude, location_name, country_code, observed_at, breeding_code, behavior_code, notes, is_private)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+    RETURNING id, created_at`
+	return q.QueryRowxContext(ctx, query,
+		obs.UserID,
+		obs.PostID,
+		obs.ChecklistID,
+		obs.SpeciesCode,
+		obs.Count,
+		obs.Latitude,
+		obs.Longitude,
+		obs.LocationName,
+		obs.CountryCode,
+		obs.ObservedAt,
+		obs.BreedingCode,
+		obs.BehaviorCode,
+		obs.Notes,
+		obs.IsPrivate,
+	).Scan(&obs.ID, &obs.CreatedAt)
+}
+
+func (s *ObservationStore) GetByID(ctx context.Context, id int64) (*Observation, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var obs Observation
+	query := `SELECT ` + observationColumns + ` FROM observations WHERE id = $1`
+	err := s.db.GetContext(ctx, &obs, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+	return &obs, nil
+}
+
+func (s *ObservationStore) Update(ctx context.Context, obs *Observation) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+    UPDATE observations
+    SET post_id = $1, checklist_id = $2, species_code = $3, count = $4, latitude = $5, longitude = $6,
+        location_name = $7, country_code = $8, observed_at = $9, breeding_code = $10, behavior_code = $11, notes = $12,
+        is_private = $13, updated_at = NOW()
+    WHERE id = $14
+    RETURNING updated_at`
+	err := s.db.QueryRowContext(ctx, query,
+		obs.PostID,
+		obs.ChecklistID,
+		obs.SpeciesCode,
+		obs.Count,
+		obs.Latitude,
+		obs.Longitude,
+		obs.LocationName,
+		obs.CountryCode,
+		obs.ObservedAt,
+		obs.BreedingCode,
+		obs.BehaviorCode,
+		obs.Notes,
+		obs.IsPrivate,
+		obs.ID,
+	).Scan(&obs.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+func (s *ObservationStore) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `DELETE FROM observations WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetByUserID lists a user's observations, newest first.
+func (s *ObservationStore) GetByUserID(ctx context.Context, userID int64, filter ObservationFilter, limit, offset int) (*PaginatedList[*Observation], error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	conditions := []string{"user_id = $1"}
+	args := []any{userID}
+
+	if filter.SpeciesCode != "" {
+		args = append(args, filter.SpeciesCode)
+		conditions = append(conditions, fmt.Sprintf("species_code = $%d", len(args)))
+	}
+	if filter.ChecklistID != nil {
+		args = append(args, *filter.ChecklistID)
+		conditions = append(conditions, fmt.Sprintf("checklist_id = $%d", len(args)))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("observed_at >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("observed_at <= $%d", len(args)))
+	}
+
+	where := strings.Join(conditions, " AND ")
+
+	var totalCount int
+	countQuery := `SELECT COUNT(*) FROM observations WHERE ` + where
+	if err := s.db.GetContext(ctx, &totalCount, countQuery, args...); err != nil {
+		return nil, err
+	}
+
+	observations := []*Observation{}
+	query := fmt.Sprintf(`SELECT %s FROM observations WHERE %s ORDER BY observed_at DESC, id DESC LIMIT $%d OFFSET $%d`,
+		observationColumns, where, len(args)+1, len(args)+2)
+	if err := s.db.SelectContext(ctx, &observations, query, append(args, limit, offset)...); err != nil {
+		return nil, err
+	}
+
+	return NewPaginatedList(observations, totalCount, limit, offset)
+}
+
+// HeatmapFilter selects the observations aggregated by GetHeatmap.
+// CellDegrees is the grid size; SpeciesCode, From and To are optional.
+type HeatmapFilter struct {
+	BBox        BoundingBox
+	CellDegrees float64
+	SpeciesCode string
+	From        *time.Time
+	To          *time.Time
+}
+
+// HeatmapCell is the number of observations in one grid cell, located at
+// the cell's centre.
+type HeatmapCell struct {
+	Latitude  float64 `json:"latitude" db:"latitude"`
+	Longitude float64 `json:"longitude" db:"longitude"`
+	Count     int     `json:"count" db:"count"`
+}
+
+// GetHeatmap counts public observations per grid cell inside the filter's
+// bounding box. Observations of sensitive species are first snapped to the
+// SensitiveGridDegrees grid, so a fine heatmap cannot reveal their location.
+func (s *ObservationStore) GetHeatmap(ctx context.Context, filter HeatmapFilter) ([]*HeatmapCell, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	bbox := filter.BBox
+	args := []any{bbox.MinLng, bbox.MinLat, bbox.MaxLng, bbox.MaxLat}
+	// Geography edges follow great circles rather than parallels, so the
+	// index lookup uses a slightly larger box and the exact bounds are then
+	// checked on the plain coordinates.
+	conditions := []string{
+		"NOT o.is_private",
+		"o.location && ST_Expand(ST_MakeEnvelope($1, $2, $3, $4, 4326), 1)::geography",
+		"o.longitude BETWEEN $1 AND $3",
+		"o.latitude BETWEEN $2 AND $4",
+	}
+	if filter.SpeciesCode != "" {
+		args = append(args, filter.SpeciesCode)
+		conditions = append(conditions, fmt.Sprintf("o.species_code = $%d", len(args)))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("o.observed_at >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("o.observed_at <= $%d", len(args)))
+	}
+
+	query := fmt.Sprintf(`
+        WITH points AS (
+            SELECT
+                CASE WHEN COALESCE(t.is_sensitive, FALSE) THEN %s ELSE o.latitude::float8 END AS latitude,
+                CASE WHEN COALESCE(t.is_sensitive, FALSE) THEN %s ELSE o.longitude::float8 END AS longitude
+            FROM observations o
+            LEFT JOIN species_taxonomy t ON t.species_code = o.species_code
+            WHERE %s
+        )
+        SELECT %s AS latitude, %s AS longitude, COUNT(*) AS count
+        FROM points
+        GROUP BY 1, 2
+        ORDER BY count DESC`,
+		snapToGridSQL("o.latitude", SensitiveGridDegrees), snapToGridSQL("o.longitude", SensitiveGridDegrees),
+		strings.Join(conditions, " AND "),
+		snapToGridSQL("latitude", filter.CellDegrees), snapToGridSQL("longitude", filter.CellDegrees))
+
+	cells := []*HeatmapCell{}
+	if err := s.db.SelectContext(ctx, &cells, query, args...); err != nil {
+		return nil, err
+	}
+	return cells, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"log"
 	"time"
 
@@ -26,6 +27,31 @@ type Post struct {
 	UserId            int64      `json:"user_id" db:"user_id"`
 	SightingDate      *time.Time `json:"sighting_date,omitempty" db:"sighting_date"`
 	TaggedSpeciesCode *string    `json:"tagged_species_code,omitempty" db:"tagged_species_code"`
+	// LocationObfuscated is set when the coordinates were snapped to a grid
+	// because the tagged species is sensitive.
+	LocationObfuscated bool `json:"location_obfuscated,omitempty" db:"location_obfuscated"`
+}
+
+func (p *Post) LocationOwnerID() int64 { return p.UserId }
+
+func (p *Post) LocationSpeciesCode() string {
+	if p.TaggedSpeciesCode == nil {
+		return ""
+	}
+	return *p.TaggedSpeciesCode
+}
+
+func (p *Post) ObfuscateLocation() {
+	if p.LocationName != "" {
+		p.LocationName = ""
+		p.LocationObfuscated = true
+	}
+	if p.Latitude == 0 && p.Longitude == 0 {
+		return
+	}
+	p.Latitude = SnapToSensitiveGrid(p.Latitude)
+	p.Longitude = SnapToSensitiveGrid(p.Longitude)
+	p.LocationObfuscated = true
 }
 
 type PostReaction struct {
@@ -68,13 +94,7 @@ func (s *PostStore) Create(ctx context.Context, post *Post) error {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	query := `
-    INSERT INTO posts (user_id, content, location_name, latitude, longitude, privacy_level, type, is_featured, sighting_date, tagged_species_code)
-    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-    RETURNING id, created_at`
-
-	err := s.db.QueryRowContext(ctx, query, post.UserId, post.Content, post.LocationName, post.Latitude, post.Longitude, post.PrivacyLevel, post.Type, post.IsFeatured, post.SightingDate, post.TaggedSpeciesCode).Scan(&post.Id, &post.CreatedAt)
-	if err != nil {
+	if err := insertPost(ctx, s.db, post); err != nil {
 		log.Println("Create post error", err)
 		return err
 	}
@@ -82,6 +102,44 @@ func (s *PostStore) Create(ctx context.Context, post *Post) error {
 	return nil
 }
 
+// CreateWithObservation creates a sighting post and the observation it
+// records in one transaction, so that neither is kept without the other.
+// obs is linked to the post and, when it has no ObservedAt, dated at the
+// post's creation.
+func (s *PostStore) CreateWithObservation(ctx context.Context, post *Post, obs *Observation) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertPost(ctx, tx, post); err != nil {
+		return err
+	}
+
+	obs.PostID = &post.Id
+	if obs.ObservedAt.IsZero() {
+		obs.ObservedAt = post.CreatedAt
+	}
+	if err := insertObservation(ctx, tx, obs); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertPost(ctx context.Context, q sqlx.QueryerContext, post *Post) error {
+	query := `
+    INSERT INTO posts (user_id, content, location_name, latitude, longitude, privacy_level, type, is_featured, sighting_date, tagged_species_code)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+    RETURNING id, created_at`
+
+	return q.QueryRowxContext(ctx, query, post.UserId, post.Content, post.LocationName, post.Latitude, post.Longitude, post.PrivacyLevel, post.Type, post.IsFeatured, post.SightingDate, post.TaggedSpeciesCode).Scan(&post.Id, &post.CreatedAt)
+}
+
 // Update modifies an existing post in the database
 func (s *PostStore) Update(ctx context.Context, post *Post) error {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -422,4 +480,79 @@ func (s *PostStore) GetFollowerPosts(ctx context.Context, userId int64, limit, o
 	}
 
 	return NewPaginatedList(posts, int(totalCount), limit, offset)
-}
\ No newline at end of file
+}
+// NearbyPost is a post with its distance from the queried point.
+type NearbyPost struct {
+	Post
+	DistanceKm float64 `json:"distance_km" db:"distance_km"`
+}
+
+// GetNearby returns the posts within radiusKm of lat/lng that viewerID may
+// see, i.e. public posts and the viewer's own, nearest first. Unless
+// revealSensitive is set, posts of sensitive species by others are placed at
+// the centre of their SensitiveGridDegrees cell, and their distance is
+// measured from there so the exact spot cannot be narrowed down by varying
+// the radius.
+func (s *PostStore) GetNearby(ctx context.Context, viewerID int64, lat, lng, radiusKm float64, revealSensitive bool, limit, offset int) (*PaginatedList[*NearbyPost], error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	// The index lookup is widened by the obfuscation offset so snapped posts
+	// whose real location lies just outside the radius are still considered.
+	// lib/pq sends parameters untyped, so those used in arithmetic are cast.
+	nearby := fmt.Sprintf(`
+        WITH origin AS (
+            SELECT ST_SetSRID(ST_MakePoint($2::float8, $3::float8), 4326)::geography AS geog
+        ),
+        candidates AS (
+            SELECT p.id, p.user_id, COALESCE(p.content, '') AS content, COALESCE(p.location_name, '') AS location_name,
+                   COALESCE(p.privacy_level, '') AS privacy_level, COALESCE(p.type, '') AS type,
+                   COALESCE(p.is_featured, FALSE) AS is_featured, p.created_at, p.updated_at,
+                   p.sighting_date, p.tagged_species_code, p.latitude, p.longitude,
+                   (NOT $6 AND p.user_id <> $1 AND COALESCE(t.is_sensitive, FALSE)) AS location_obfuscated
+            FROM posts p
+            CROSS JOIN origin
+            LEFT JOIN species_taxonomy t ON t.species_code = p.tagged_species_code
+            WHERE ST_DWithin(p.location, origin.geog, $4::float8 + $5::float8)
+              AND (p.user_id = $1 OR COALESCE(p.privacy_level, '') IN ('', 'public'))
+        ),
+        placed AS (
+            SELECT c.id, c.user_id, c.content,
+                   CASE WHEN c.location_obfuscated THEN '' ELSE c.location_name END AS location_name,
+                   c.privacy_level, c.type, c.is_featured,
+                   c.created_at, c.updated_at, c.sighting_date, c.tagged_species_code, c.location_obfuscated,
+                   CASE WHEN c.location_obfuscated THEN %s ELSE c.latitude::float8 END AS latitude,
+                   CASE WHEN c.location_obfuscated THEN %s ELSE c.longitude::float8 END AS longitude
+            FROM candidates c
+        ),
+        nearby AS (
+            SELECT placed.*,
+                   ST_Distance(ST_SetSRID(ST_MakePoint(placed.longitude, placed.latitude), 4326)::geography, origin.geog) / 1000 AS distance_km
+            FROM placed
+            CROSS JOIN origin
+        )`,
+		snapToGridSQL("c.latitude", SensitiveGridDegrees), snapToGridSQL("c.longitude", SensitiveGridDegrees))
+
+	radiusMeters := radiusKm * 1000
+	args := []any{viewerID, lng, lat, radiusMeters, sensitiveOffsetMeters, revealSensitive}
+
+	var totalCount int
+	countQuery := nearby + ` SELECT COUNT(*) FROM nearby WHERE distance_km <= $4::float8 / 1000`
+	if err := s.db.GetContext(ctx, &totalCount, countQuery, args...); err != nil {
+		return nil, err
+	}
+
+	posts := []*NearbyPost{}
+	query := nearby + `
+        SELECT id, user_id, content, location_name, latitude, longitude, privacy_level, type, is_featured,
+               created_at, updated_at, sighting_date, tagged_species_code, distance_km, location_obfuscated
+        FROM nearby
+        WHERE distance_km <= $4::float8 / 1000
+        ORDER BY distance_km, created_at DESC
+        LIMIT $7 OFFSET $8`
+	if err := s.db.SelectContext(ctx, &posts, query, append(args, limit, offset)...); err != nil {
+		return nil, err
+	}
+
+	return NewPaginatedList(posts, totalCount, limit, offset)
+}
@@ -16,8 +16,9 @@ type RoleStore struct {
 }
 
 var (
-	ADMIN string = "admin"
-	USER  string = "user"
+	ADMIN         string = "admin"
+	USER          string = "user"
+	TOUR_OPERATOR string = "tour_operator"
 )
 
 func (s *RoleStore) GetByID(ctx context.Context, id int64) (*Role, error) {
@@ -31,15 +32,21 @@ func (s *RoleStore) GetByID(ctx context.Context, id int64) (*Role, error) {
 }
 
 func (s *RoleStore) AddUserToRole(ctx context.Context, userID int64, roleName string) error {
+	return addUserToRole(ctx, s.db, userID, roleName)
+}
+
+// addUserToRole grants roleName to the user through q, so that other stores
+// can grant a role in their own transaction.
+func addUserToRole(ctx context.Context, q sqlx.ExtContext, userID int64, roleName string) error {
 	roleIdQuery := "SELECT id FROM roles WHERE name = $1;"
 	var roleID int64
-	err := s.db.GetContext(ctx, &roleID, roleIdQuery, roleName)
+	err := sqlx.GetContext(ctx, q, &roleID, roleIdQuery, roleName)
 	if err != nil {
 		return err
 	}
 
-	query := "INSERT INTO user_roles (user_id, role_id) VALUES ($1, $2);"
-	_, err = s.db.ExecContext(ctx, query, userID, roleID)
+	query := "INSERT INTO user_roles (user_id, role_id) VALUES ($1, $2) ON CONFLICT (user_id, role_id) DO NOTHING;"
+	_, err = q.ExecContext(ctx, query, userID, roleID)
 	if err != nil {
 		return err
 	}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"encoding/json"
+	"fmt"
 	"log"
 	"time"
 
@@ -149,4 +150,105 @@ func (s *SpeciesStore) GetRangeByScientificName(ctx context.Context, scientificN
 
 	log.Printf("[STORE] Partial search found %d rows.", len(ranges))
 	return ranges, nil
-}
\ No newline at end of file
+}
+
+// BoundingBox is a WGS 84 longitude/latitude rectangle.
+type BoundingBox struct {
+	MinLng float64
+	MinLat float64
+	MaxLng float64
+	MaxLat float64
+}
+
+// RangeSpecies is a species whose range covers a queried area. Taxonomy
+// fields are nil when the range's scientific name is not in the taxonomy.
+type RangeSpecies struct {
+	ScientificName   string  `json:"scientific_name" db:"sci_name"`
+	SpeciesCode      *string `json:"species_code" db:"species_code"`
+	CommonName       *string `json:"common_name" db:"common_name"`
+	FamilyCommonName *string `json:"family_common_name,omitempty" db:"family_common_name"`
+}
+
+// GetRangeSpeciesAtPoint returns the species whose range polygons contain the point.
+func (s *SpeciesStore) GetRangeSpeciesAtPoint(ctx context.Context, lat, lng float64, limit, offset int) (*PaginatedList[*RangeSpecies], error) {
+	return s.getRangeSpecies(ctx, `ST_Contains(r.geom, ST_SetSRID(ST_MakePoint($1, $2), 4326))`, []any{lng, lat}, limit, offset)
+}
+
+// GetRangeSpeciesInBBox returns the species whose range polygons intersect the box.
+func (s *SpeciesStore) GetRangeSpeciesInBBox(ctx context.Context, bbox BoundingBox, limit, offset int) (*PaginatedList[*RangeSpecies], error) {
+	return s.getRangeSpecies(ctx, `ST_Intersects(r.geom, ST_MakeEnvelope($1, $2, $3, $4, 4326))`,
+		[]any{bbox.MinLng, bbox.MinLat, bbox.MaxLng, bbox.MaxLat}, limit, offset)
+}
+
+// getRangeSpecies lists the distinct species of the ranges matching a spatial
+// condition on r.geom, which the GiST index on species_ranges serves.
+func (s *SpeciesStore) getRangeSpecies(ctx context.Context, condition string, args []any, limit, offset int) (*PaginatedList[*RangeSpecies], error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var totalCount int
+	countQuery := `SELECT COUNT(DISTINCT r.sci_name) FROM public.species_ranges r WHERE ` + condition
+	if err := s.db.GetContext(ctx, &totalCount, countQuery, args...); err != nil {
+		return nil, err
+	}
+
+	species := []*RangeSpecies{}
+	query := fmt.Sprintf(`
+        WITH matched AS (
+            SELECT DISTINCT r.sci_name FROM public.species_ranges r WHERE %s
+        )
+        SELECT m.sci_name, t.species_code, t.common_name, t.family_common_name
+        FROM matched m
+        LEFT JOIN species_taxonomy t ON LOWER(t.scientific_name) = LOWER(m.sci_name) AND t.category = 'species'
+        ORDER BY t.taxon_order NULLS LAST, m.sci_name
+        LIMIT $%d OFFSET $%d`, condition, len(args)+1, len(args)+2)
+	if err := s.db.SelectContext(ctx, &species, query, append(args, limit, offset)...); err != nil {
+		return nil, err
+	}
+
+	return NewPaginatedList(species, totalCount, limit, offset)
+}
+
+// rangeTileExtent is the MVT coordinate space of a tile; ST_AsMVT's default.
+const rangeTileExtent = 4096
+
+// GetRangeTile renders a species' range polygons inside tile z/x/y as a
+// Mapbox Vector Tile with a single "ranges" layer. Geometry is simplified to
+// about one tile unit at the requested zoom, so low zooms stay small and high
+// zooms keep their detail. An empty slice means the tile has no range.
+func (s *SpeciesStore) GetRangeTile(ctx context.Context, scientificName string, z, x, y int) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	// Degrees covered by one tile unit at this zoom.
+	tolerance := 360.0 / float64(int64(1)<<z) / rangeTileExtent
+
+	query := fmt.Sprintf(`
+        WITH bounds AS (
+            SELECT ST_TileEnvelope($1, $2, $3) AS geom
+        ),
+        tile AS (
+            SELECT r.sci_name,
+                   ST_AsMVTGeom(
+                       -- Web Mercator ends at 85.0511 degrees; ranges reaching
+                       -- the poles are clipped so they can be projected.
+                       ST_Transform(ST_ClipByBox2D(
+                           ST_SimplifyPreserveTopology(r.geom, $5),
+                           ST_MakeEnvelope(-180, -85.0511, 180, 85.0511, 4326)
+                       ), 3857),
+                       bounds.geom, %d, 64, true
+                   ) AS geom
+            FROM public.species_ranges r, bounds
+            WHERE LOWER(r.sci_name) = LOWER($4)
+              AND ST_Intersects(r.geom, ST_Transform(bounds.geom, 4326))
+        )
+        SELECT COALESCE(ST_AsMVT(tile.*, 'ranges', %d, 'geom'), '')
+        FROM tile
+        WHERE tile.geom IS NOT NULL`, rangeTileExtent, rangeTileExtent)
+
+	var mvt []byte
+	if err := s.db.GetContext(ctx, &mvt, query, z, x, y, scientificName, tolerance); err != nil {
+		return nil, err
+	}
+	return mvt, nil
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SpeciesProfile is the AI-written description of a species in one
+// language, with a picture of it from Wikipedia.
+type SpeciesProfile struct {
+	SpeciesCode string    `json:"species_code" db:"species_code"`
+	Language    string    `json:"language" db:"language"`
+	Description string    `json:"description" db:"description"`
+	ImageURL    *string   `json:"image_url" db:"image_url"`
+	Model       string    `json:"model" db:"model"`
+	GeneratedAt time.Time `json:"generated_at" db:"generated_at"`
+}
+
+type SpeciesProfileStore struct {
+	db *sqlx.DB
+}
+
+// Get returns sql.ErrNoRows when the species has no profile in language.
+func (s *SpeciesProfileStore) Get(ctx context.Context, speciesCode, language string) (*SpeciesProfile, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var profile SpeciesProfile
+	query := `
+    SELECT species_code, language, description, image_url, model, generated_at
+    FROM species_profiles WHERE species_code = $1 AND language = $2`
+	err := s.db.GetContext(ctx, &profile, query, speciesCode, language)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// Upsert stores a profile, replacing the one it refreshes.
+func (s *SpeciesProfileStore) Upsert(ctx context.Context, profile *SpeciesProfile) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+    INSERT INTO species_profiles (species_code, language, description, image_url, model)
+    VALUES ($1, $2, $3, $4, $5)
+    ON CONFLICT (species_code, language) DO UPDATE SET
+        description = EXCLUDED.description,
+        image_url = EXCLUDED.image_url,
+        model = EXCLUDED.model,
+        generated_at = NOW()
+    RETURNING generated_at`
+	return s.db.QueryRowContext(ctx, query, profile.SpeciesCode, profile.Language, profile.Description, profile.ImageURL, profile.Model).
+		Scan(&profile.GeneratedAt)
+}
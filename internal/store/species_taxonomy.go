@@ -0,0 +1,415 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	SpeciesNameLocaleEnglish    = "en"
+	SpeciesNameLocaleScientific = "sci"
+)
+
+// Species is one entry of the local eBird taxonomy.
+type Species struct {
+	SpeciesCode          string            `json:"species_code" db:"species_code"`
+	CommonName           string            `json:"common_name" db:"common_name"`
+	ScientificName       string            `json:"scientific_name" db:"scientific_name"`
+	Category             string            `json:"category" db:"category"`
+	TaxonOrder           float64           `json:"taxon_order" db:"taxon_order"`
+	OrderName            *string           `json:"order_name,omitempty" db:"order_name"`
+	FamilyCode           *string           `json:"family_code,omitempty" db:"family_code"`
+	FamilyCommonName     *string           `json:"family_common_name,omitempty" db:"family_common_name"`
+	FamilyScientificName *string           `json:"family_scientific_name,omitempty" db:"family_scientific_name"`
+	ReportAs             *string           `json:"report_as,omitempty" db:"report_as"`
+	Extinct              bool              `json:"extinct" db:"extinct"`
+	IsSensitive          bool              `json:"is_sensitive" db:"is_sensitive"`
+	LocalizedNames       map[string]string `json:"localized_names,omitempty" db:"-"`
+}
+
+// SpeciesSearchResult is an autocomplete match and the name that matched it.
+type SpeciesSearchResult struct {
+	Species
+	MatchedName   string `json:"matched_name" db:"matched_name"`
+	MatchedLocale string `json:"matched_locale" db:"matched_locale"`
+	Rank          int    `json:"-" db:"rank"`
+}
+
+// SpeciesFilter narrows List. Zero values are ignored.
+type SpeciesFilter struct {
+	Category      string
+	Family        string // scientific or common family name, case-insensitive
+	SensitiveOnly bool
+}
+
+const speciesColumns = `t.species_code, t.common_name, t.scientific_name, t.category, t.taxon_order, t.order_name,
+    t.family_code, t.family_common_name, t.family_scientific_name, t.report_as, t.extinct, t.is_sensitive`
+
+func (s *SpeciesStore) GetByCode(ctx context.Context, code string) (*Species, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var species Species
+	query := `SELECT ` + speciesColumns + ` FROM species_taxonomy t WHERE t.species_code = $1`
+	err := s.db.GetContext(ctx, &species, query, code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+
+	var names []struct {
+		Locale string `db:"locale"`
+		Name   string `db:"name"`
+	}
+	namesQuery := `SELECT locale, name FROM species_names WHERE species_code = $1 AND locale NOT IN ($2, $3)`
+	if err := s.db.SelectContext(ctx, &names, namesQuery, code, SpeciesNameLocaleEnglish, SpeciesNameLocaleScientific); err != nil {
+		return nil, err
+	}
+	species.LocalizedNames = make(map[string]string, len(names))
+	for _, n := range names {
+		species.LocalizedNames[n.Locale] = n.Name
+	}
+
+	return &species, nil
+}
+
+// GetByCodes returns the species found for codes, keyed by species code.
+func (s *SpeciesStore) GetByCodes(ctx context.Context, codes []string) (map[string]*Species, error) {
+	species := make(map[string]*Species, len(codes))
+	if len(codes) == 0 {
+		return species, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query, args, err := sqlx.In(`SELECT `+speciesColumns+` FROM species_taxonomy t WHERE t.species_code IN (?)`, codes)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []*Species
+	if err := s.db.SelectContext(ctx, &rows, s.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		species[row.SpeciesCode] = row
+	}
+	return species, nil
+}
+
+// FindCodeByName resolves a scientific name, or failing that an English
+// common name, to a species code. It returns sql.ErrNoRows when neither matches.
+func (s *SpeciesStore) FindCodeByName(ctx context.Context, scientificName, commonName string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var code string
+	query := `
+        SELECT species_code FROM species_taxonomy
+        WHERE ($1 <> '' AND LOWER(scientific_name) = LOWER($1))
+           OR ($2 <> '' AND LOWER(common_name) = LOWER($2))
+        ORDER BY (LOWER(scientific_name) = LOWER($1)) DESC, taxon_order
+        LIMIT 1`
+	err := s.db.GetContext(ctx, &code, query, scientificName, commonName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", sql.ErrNoRows
+		}
+		return "", err
+	}
+	return code, nil
+}
+
+func (s *SpeciesStore) Exists(ctx context.Context, code string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var exists bool
+	err := s.db.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM species_taxonomy WHERE species_code = $1)`, code)
+	return exists, err
+}
+
+// List pages through the taxonomy in taxonomic order.
+func (s *SpeciesStore) List(ctx context.Context, filter SpeciesFilter, limit, offset int) (*PaginatedList[*Species], error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	conditions := []string{"TRUE"}
+	args := []any{}
+	if filter.Category != "" {
+		args = append(args, strings.ToLower(filter.Category))
+		conditions = append(conditions, fmt.Sprintf("t.category = $%d", len(args)))
+	}
+	if filter.Family != "" {
+		args = append(args, filter.Family)
+		conditions = append(conditions, fmt.Sprintf("(LOWER(t.family_scientific_name) = LOWER($%[1]d) OR LOWER(t.family_common_name) = LOWER($%[1]d))", len(args)))
+	}
+	if filter.SensitiveOnly {
+		conditions = append(conditions, "t.is_sensitive")
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var totalCount int
+	if err := s.db.GetContext(ctx, &totalCount, `SELECT COUNT(*) FROM species_taxonomy t WHERE `+where, args...); err != nil {
+		return nil, err
+	}
+
+	species := []*Species{}
+	query := fmt.Sprintf(`SELECT %s FROM species_taxonomy t WHERE %s ORDER BY t.taxon_order LIMIT $%d OFFSET $%d`,
+		speciesColumns, where, len(args)+1, len(args)+2)
+	if err := s.db.SelectContext(ctx, &species, query, append(args, limit, offset)...); err != nil {
+		return nil, err
+	}
+
+	return NewPaginatedList(species, totalCount, limit, offset)
+}
+
+// Search is an autocomplete over common, scientific and localized names,
+// ignoring case and diacritics. Matches at the start of a name rank above
+// matches at the start of a later word, which rank above any other substring
+// match; full species rank above other categories. When locale is set, only
+// names in that locale plus English and scientific names are searched.
+func (s *SpeciesStore) Search(ctx context.Context, q, locale string, limit int) ([]*SpeciesSearchResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	results := []*SpeciesSearchResult{}
+	term := NormalizeSpeciesName(q)
+	if term == "" {
+		return results, nil
+	}
+
+	query := `
+        SELECT * FROM (
+            SELECT DISTINCT ON (t.species_code) ` + speciesColumns + `,
+                n.name AS matched_name,
+                n.locale AS matched_locale,
+                CASE
+                    WHEN n.search_name LIKE $1 || '%' THEN 0
+                    WHEN n.search_name LIKE '% ' || $1 || '%' THEN 1
+                    ELSE 2
+                END AS rank
+            FROM species_names n
+            JOIN species_taxonomy t ON t.species_code = n.species_code
+            WHERE n.search_name LIKE '%' || $1 || '%'
+              AND ($2 = '' OR n.locale IN ($2, $3, $4))
+            ORDER BY t.species_code, rank, (n.locale = $2) DESC
+        ) matches
+        ORDER BY rank, (category <> 'species'), taxon_order
+        LIMIT $5`
+	err := s.db.SelectContext(ctx, &results, query, term, locale, SpeciesNameLocaleEnglish, SpeciesNameLocaleScientific, limit)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// UpsertTaxonomy inserts or updates taxonomy rows along with their English
+// and scientific search names, in one transaction.
+func (s *SpeciesStore) UpsertTaxonomy(ctx context.Context, species []*Species) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+    INSERT INTO species_taxonomy (species_code, common_name, scientific_name, category, taxon_order, order_name,
+        family_code, family_common_name, family_scientific_name, report_as, extinct, updated_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
+    ON CONFLICT (species_code) DO UPDATE SET
+        common_name = EXCLUDED.common_name,
+        scientific_name = EXCLUDED.scientific_name,
+        category = EXCLUDED.category,
+        taxon_order = EXCLUDED.taxon_order,
+        order_name = EXCLUDED.order_name,
+        family_code = EXCLUDED.family_code,
+        family_common_name = EXCLUDED.family_common_name,
+        family_scientific_name = EXCLUDED.family_scientific_name,
+        report_as = EXCLUDED.report_as,
+        extinct = EXCLUDED.extinct,
+        updated_at = NOW()`
+
+	for _, sp := range species {
+		_, err := tx.ExecContext(ctx, query,
+			sp.SpeciesCode,
+			sp.CommonName,
+			sp.ScientificName,
+			sp.Category,
+			sp.TaxonOrder,
+			sp.OrderName,
+			sp.FamilyCode,
+			sp.FamilyCommonName,
+			sp.FamilyScientificName,
+			sp.ReportAs,
+			sp.Extinct,
+		)
+		if err != nil {
+			return fmt.Errorf("upserting %s: %w", sp.SpeciesCode, err)
+		}
+
+		if err := upsertSpeciesName(ctx, tx, sp.SpeciesCode, SpeciesNameLocaleEnglish, sp.CommonName); err != nil {
+			return err
+		}
+		if err := upsertSpeciesName(ctx, tx, sp.SpeciesCode, SpeciesNameLocaleScientific, sp.ScientificName); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SetSensitive marks exactly the given species codes as sensitive, clearing
+// the flag on every other species. It returns the number of species marked.
+func (s *SpeciesStore) SetSensitive(ctx context.Context, codes []string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE species_taxonomy SET is_sensitive = FALSE WHERE is_sensitive`); err != nil {
+		return 0, err
+	}
+
+	marked := 0
+	if len(codes) > 0 {
+		query, args, err := sqlx.In(`UPDATE species_taxonomy SET is_sensitive = TRUE WHERE species_code IN (?)`, codes)
+		if err != nil {
+			return 0, err
+		}
+		result, err := tx.ExecContext(ctx, tx.Rebind(query), args...)
+		if err != nil {
+			return 0, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		marked = int(affected)
+	}
+
+	return marked, tx.Commit()
+}
+
+// SetSpeciesSensitive flags or unflags one species. It returns sql.ErrNoRows
+// when the code is not in the taxonomy.
+func (s *SpeciesStore) SetSpeciesSensitive(ctx context.Context, code string, sensitive bool) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `UPDATE species_taxonomy SET is_sensitive = $1 WHERE species_code = $2`, sensitive, code)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetSensitiveCodes returns the set of sensitive species codes.
+func (s *SpeciesStore) GetSensitiveCodes(ctx context.Context) (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var codes []string
+	if err := s.db.SelectContext(ctx, &codes, `SELECT species_code FROM species_taxonomy WHERE is_sensitive`); err != nil {
+		return nil, err
+	}
+
+	sensitive := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		sensitive[code] = true
+	}
+	return sensitive, nil
+}
+
+// UpsertLocalizedNames stores common names in locale, keyed by species code.
+// Codes missing from the taxonomy are skipped; the number stored is returned.
+func (s *SpeciesStore) UpsertLocalizedNames(ctx context.Context, locale string, names map[string]string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var known []string
+	if err := tx.SelectContext(ctx, &known, `SELECT species_code FROM species_taxonomy`); err != nil {
+		return 0, err
+	}
+
+	stored := 0
+	for _, code := range known {
+		name, ok := names[code]
+		if !ok || name == "" {
+			continue
+		}
+		if err := upsertSpeciesName(ctx, tx, code, locale, name); err != nil {
+			return 0, err
+		}
+		stored++
+	}
+
+	return stored, tx.Commit()
+}
+
+func upsertSpeciesName(ctx context.Context, tx *sqlx.Tx, code, locale, name string) error {
+	query := `
+    INSERT INTO species_names (species_code, locale, name, search_name)
+    VALUES ($1, $2, $3, $4)
+    ON CONFLICT (species_code, locale) DO UPDATE SET name = EXCLUDED.name, search_name = EXCLUDED.search_name`
+	if _, err := tx.ExecContext(ctx, query, code, locale, name, NormalizeSpeciesName(name)); err != nil {
+		return fmt.Errorf("upserting %s name for %s: %w", locale, code, err)
+	}
+	return nil
+}
+
+// NormalizeSpeciesName lowercases a name, strips diacritics (including the
+// Vietnamese đ, which Unicode does not decompose) and collapses punctuation
+// and whitespace to single spaces, so searches match regardless of accents.
+func NormalizeSpeciesName(name string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range norm.NFD.String(name) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case r == 'đ' || r == 'Đ':
+			r = 'd'
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			space = b.Len() > 0
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
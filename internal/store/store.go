@@ -29,11 +29,14 @@ type Storage struct {
 		AddResetPasswordToken(ctx context.Context, email string, token string, expiresAt time.Time) error
 		GetUserByResetPasswordToken(ctx context.Context, token string) (*User, error)
 		GrantSubscriptionForOrder(ctx context.Context, userID int64, subscriptionID int64) error
-		GetUserLifeList(ctx context.Context, userID int64) ([]string, error)
+		GetUserLifeList(ctx context.Context, userID, viewerID int64, filter LifeListFilter) ([]*LifeListEntry, error)
+		GetUserLifeListCountries(ctx context.Context, userID, viewerID int64, filter LifeListFilter) ([]CountrySpeciesCount, error)
+		GetSeenSpeciesCodes(ctx context.Context, userID int64, codes []string) (map[string]bool, error)
 		GetAllUserEmails(ctx context.Context) ([]string, error)
 	}
 	Posts interface {
 		Create(context.Context, *Post) error
+		CreateWithObservation(ctx context.Context, post *Post, obs *Observation) error
 		GetById(ctx context.Context, postId int64) (*Post, error)
 		Update(ctx context.Context, post *Post) error
 		Delete(ctx context.Context, postId int64) error
@@ -48,6 +51,89 @@ type Storage struct {
 		GetFollowerPosts(ctx context.Context, userId int64, limit, offset int) (*PaginatedList[*Post], error)
 		// Logic: Add a new method to the interface to count user posts.
 		GetPostCountByUserID(ctx context.Context, userID int64) (int, error)
+		GetNearby(ctx context.Context, viewerID int64, lat, lng, radiusKm float64, revealSensitive bool, limit, offset int) (*PaginatedList[*NearbyPost], error)
+	}
+	Observations interface {
+		Create(ctx context.Context, obs *Observation) error
+		GetByID(ctx context.Context, id int64) (*Observation, error)
+		Update(ctx context.Context, obs *Observation) error
+		Delete(ctx context.Context, id int64) error
+		GetByUserID(ctx context.Context, userID int64, filter ObservationFilter, limit, offset int) (*PaginatedList[*Observation], error)
+		GetHeatmap(ctx context.Context, filter HeatmapFilter) ([]*HeatmapCell, error)
+	}
+	Checklists interface {
+		Create(ctx context.Context, checklist *Checklist) error
+		GetByID(ctx context.Context, id int64) (*Checklist, error)
+		Update(ctx context.Context, checklist *Checklist, replaceObservations bool) error
+		Delete(ctx context.Context, id int64) error
+		GetByUserID(ctx context.Context, userID int64, limit, offset int) (*PaginatedList[*Checklist], error)
+		GetWithObservationsByUserID(ctx context.Context, userID int64, from, to time.Time) ([]*Checklist, error)
+		ExistsByExternalID(ctx context.Context, userID int64, externalID string) (bool, error)
+	}
+	ImportJobs interface {
+		Create(ctx context.Context, job *ImportJob) error
+		GetByID(ctx context.Context, id int64) (*ImportJob, error)
+		GetByUserID(ctx context.Context, userID int64, limit, offset int) (*PaginatedList[*ImportJob], error)
+		Save(ctx context.Context, job *ImportJob) error
+	}
+	EbirdCache interface {
+		TrackHotspot(ctx context.Context, locID string) error
+		GetHotspot(ctx context.Context, locID string) (*CachedHotspot, error)
+		ListHotspots(ctx context.Context) ([]*CachedHotspot, error)
+		MarkRefreshed(ctx context.Context, locID string, at time.Time) error
+		GetCachedDates(ctx context.Context, locID string, from, to time.Time) (map[string]bool, error)
+		SaveHistoricDay(ctx context.Context, locID string, date time.Time, observations []*HistoricObservation) error
+		GetHistoricActivity(ctx context.Context, locIDs []string, speciesCode string, from, to time.Time) (*HistoricActivity, error)
+		GetWeeklyPresence(ctx context.Context, locIDs []string, speciesCode string, from, to time.Time) ([]*WeeklyPresence, error)
+		GetTopSpeciesByMonth(ctx context.Context, locIDs []string, from, to time.Time, perMonth int) ([]*MonthlySpeciesPresence, error)
+		GetVisitingTimes(ctx context.Context, locID, speciesCode string) (*HotspotVisitingTimes, error)
+		GetVisitingTimesSpecies(ctx context.Context, locID string) ([]string, error)
+		SaveVisitingTimes(ctx context.Context, vt *HotspotVisitingTimes) error
+	}
+	Identifications interface {
+		Create(ctx context.Context, identification *Identification) error
+		SetImageURL(ctx context.Context, id int64, imageURL string) error
+		GetByID(ctx context.Context, userID, id int64) (*Identification, error)
+		GetByUserID(ctx context.Context, userID int64, limit, offset int) (*PaginatedList[*Identification], error)
+		SaveFeedback(ctx context.Context, identification *Identification) error
+		GetAccuracy(ctx context.Context, model string) (*IdentificationAccuracy, error)
+		GetAccuracyBySpecies(ctx context.Context, model string, limit, offset int) (*PaginatedList[*SpeciesIdentificationAccuracy], error)
+		GetLabelled(ctx context.Context, limit, offset int) (*PaginatedList[*LabelledIdentification], error)
+	}
+	Chats interface {
+		Create(ctx context.Context, chat *Chat, messages []*ChatMessage) error
+		AddMessages(ctx context.Context, chat *Chat, messages []*ChatMessage, maxMessages int) error
+		GetByID(ctx context.Context, userID, id int64) (*Chat, error)
+		GetByUserID(ctx context.Context, userID int64, limit, offset int) (*PaginatedList[*Chat], error)
+		GetMessages(ctx context.Context, chatID int64) ([]*ChatMessage, error)
+	}
+	SpeciesProfiles interface {
+		Get(ctx context.Context, speciesCode, language string) (*SpeciesProfile, error)
+		Upsert(ctx context.Context, profile *SpeciesProfile) error
+	}
+	AIUsage interface {
+		Reserve(ctx context.Context, userID int64, day time.Time, feature string, limits AILimits) (*AIUsage, bool, error)
+		Release(ctx context.Context, userID int64, day time.Time, feature string) error
+		AddTokens(ctx context.Context, userID int64, day time.Time, inputTokens, outputTokens int) error
+		Get(ctx context.Context, userID int64, day time.Time) (*AIUsage, error)
+		GetDailyTotals(ctx context.Context, from, to time.Time) ([]*AIUsage, error)
+		GetUserTotals(ctx context.Context, from, to time.Time, limit, offset int) (*PaginatedList[*UserAIUsage], error)
+	}
+	Alerts interface {
+		GetSettings(ctx context.Context, userID int64) (*AlertSettings, error)
+		SaveSettings(ctx context.Context, settings *AlertSettings) error
+		ListEnabledSettings(ctx context.Context) ([]*AlertSettings, error)
+		GetWatchlist(ctx context.Context, userID int64) ([]*WatchlistEntry, error)
+		AddToWatchlist(ctx context.Context, userID int64, speciesCode string) error
+		RemoveFromWatchlist(ctx context.Context, userID int64, speciesCode string) error
+		CreateAlert(ctx context.Context, alert *RareBirdAlert) (bool, error)
+		CreatePostAlerts(ctx context.Context, since time.Time, bookmarkRadiusKm float64) (int, error)
+		GetPendingAlerts(ctx context.Context, userID int64, since time.Time) ([]*RareBirdAlert, error)
+		CountNotifiedSince(ctx context.Context, userID int64, since time.Time) (int, error)
+		MarkNotified(ctx context.Context, ids []int64, at time.Time) error
+		GetUndigestedAlerts(ctx context.Context, userID int64, since time.Time) ([]*RareBirdAlert, error)
+		MarkDigested(ctx context.Context, userID int64, ids []int64, at time.Time) error
+		GetAlerts(ctx context.Context, userID int64, limit, offset int) (*PaginatedList[*RareBirdAlert], error)
 	}
 	// Logic: Add the Notifications interface.
 	Notifications interface {
@@ -82,14 +168,40 @@ type Storage struct {
 		Update(ctx context.Context, tour *Tour) error
 		Delete(ctx context.Context, id int64) error
 		GetAll(ctx context.Context, limit, offset int) (*PaginatedList[*Tour], error)
+		GetByOperatorID(ctx context.Context, operatorId int64, limit, offset int) (*PaginatedList[*Tour], error)
+		GetBookedByUserID(ctx context.Context, userId int64) ([]*Tour, error)
 		AddTourImagesUrl(ctx context.Context, tourId int64, imageUrl string) error
 		GetTourImagesUrl(ctx context.Context, tourId int64) ([]string, error)
+		RemoveTourImageUrl(ctx context.Context, tourId int64, imageUrl string) error
+	}
+	TourOperators interface {
+		Create(ctx context.Context, operator *TourOperator, ownerID int64) error
+		GetByID(ctx context.Context, id int64) (*TourOperator, error)
+		Update(ctx context.Context, operator *TourOperator) error
+		GetByUserID(ctx context.Context, userID int64) ([]*TourOperator, error)
+		GetMemberRole(ctx context.Context, operatorID, userID int64) (string, error)
+		GetMembers(ctx context.Context, operatorID int64) ([]*TourOperatorMember, error)
+		AddMember(ctx context.Context, operatorID, userID int64, memberRole string) error
+		RemoveMember(ctx context.Context, operatorID, userID int64) error
+		GetBookings(ctx context.Context, operatorID int64, limit, offset int) (*PaginatedList[*TourBooking], error)
+		GetRevenue(ctx context.Context, operatorID int64) (*OperatorRevenue, error)
 	}
 	Events interface {
 		GetByID(ctx context.Context, id int64) (*Event, error)
 		Create(ctx context.Context, event *Event) error
+		Update(ctx context.Context, event *Event) error
 		GetAll(ctx context.Context, limit, offset int) (*PaginatedList[*Event], error)
+		GetInRange(ctx context.Context, from, to time.Time, limit, offset int) (*PaginatedList[*Event], error)
 		Delete(ctx context.Context, id int64) error
+		SetRSVP(ctx context.Context, rsvp *EventRSVP) error
+		GetRSVP(ctx context.Context, eventID, userID int64) (*EventRSVP, error)
+		DeleteRSVP(ctx context.Context, eventID, userID int64) error
+		GetRSVPedByUserID(ctx context.Context, userID int64) ([]*Event, error)
+	}
+	CalendarFeeds interface {
+		GetToken(ctx context.Context, userID int64) (string, error)
+		SetToken(ctx context.Context, userID int64, token string) error
+		GetUserIDByToken(ctx context.Context, token string) (int64, error)
 	}
 	Location interface {
 		GetByID(ctx context.Context, id int64) (*Location, error)
@@ -107,13 +219,30 @@ type Storage struct {
 	}
 	Bookmarks interface {
 		Create(ctx context.Context, bookmark *Bookmark) error
-		Delete(ctx context.Context, userID int64, hotspotLocationID int64) error
-		GetByUserID(ctx context.Context, userID int64) ([]*Bookmark, error)
-		GetTrendingBookmarks(ctx context.Context, limit, offset int) (*PaginatedList[*Bookmark], error)
+		Get(ctx context.Context, userID int64, hotspotLocationID string) (*Bookmark, error)
+		UpdateNote(ctx context.Context, userID int64, hotspotLocationID string, note *string) (*Bookmark, error)
+		Delete(ctx context.Context, userID int64, hotspotLocationID string) error
+		GetByUserID(ctx context.Context, userID int64, limit, offset int) (*PaginatedList[*Bookmark], error)
+		GetTrendingBookmarks(ctx context.Context, window time.Duration, limit, offset int) (*PaginatedList[*TrendingHotspot], error)
+		CountByHotspot(ctx context.Context, hotspotLocationID string) (int, error)
 		Exists(ctx context.Context, userID int64, hotspotLocationID string) (bool, error)
 	}
 	Species interface {
 		GetRangeByScientificName(ctx context.Context, scientificName string) ([]RangeData, error)
+		GetRangeSpeciesAtPoint(ctx context.Context, lat, lng float64, limit, offset int) (*PaginatedList[*RangeSpecies], error)
+		GetRangeSpeciesInBBox(ctx context.Context, bbox BoundingBox, limit, offset int) (*PaginatedList[*RangeSpecies], error)
+		GetRangeTile(ctx context.Context, scientificName string, z, x, y int) ([]byte, error)
+		GetByCode(ctx context.Context, code string) (*Species, error)
+		GetByCodes(ctx context.Context, codes []string) (map[string]*Species, error)
+		Exists(ctx context.Context, code string) (bool, error)
+		FindCodeByName(ctx context.Context, scientificName, commonName string) (string, error)
+		List(ctx context.Context, filter SpeciesFilter, limit, offset int) (*PaginatedList[*Species], error)
+		Search(ctx context.Context, q, locale string, limit int) ([]*SpeciesSearchResult, error)
+		UpsertTaxonomy(ctx context.Context, species []*Species) error
+		UpsertLocalizedNames(ctx context.Context, locale string, names map[string]string) (int, error)
+		SetSensitive(ctx context.Context, codes []string) (int, error)
+		SetSpeciesSensitive(ctx context.Context, code string, sensitive bool) error
+		GetSensitiveCodes(ctx context.Context) (map[string]bool, error)
 	}
 	Roles interface {
 		GetByID(ctx context.Context, id int64) (*Role, error)
@@ -141,13 +270,24 @@ func NewStore(db *sqlx.DB) *Storage {
 	return &Storage{
 		Users:         &UserStore{db},
 		Posts:         &PostStore{db},
+		Observations:  &ObservationStore{db},
+		Checklists:    &ChecklistStore{db},
+		ImportJobs:    &ImportJobStore{db},
+		EbirdCache:    &EbirdCacheStore{db},
+		Alerts:        &AlertStore{db},
+		Identifications: &IdentificationStore{db},
+		AIUsage:       &AIUsageStore{db},
+		Chats:         &ChatStore{db},
+		SpeciesProfiles: &SpeciesProfileStore{db},
 		// Logic: Add NotificationStore to the main store constructor.
 		Notifications: &NotificationStore{db},
 		Followers:     &FollowerStore{db},
 		Sessions:      &SessionStore{db},
 		Comments:      &CommentStore{db},
 		Tours:         &TourStore{db},
+		TourOperators: &TourOperatorStore{db},
 		Events:        &EventStore{db},
+		CalendarFeeds: &CalendarFeedStore{db},
 		Location:      &LocationStore{db},
 		Carts:         &CartStore{db},
 		Equipments:    &EquipmentStore{db},
@@ -0,0 +1,300 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	OperatorMemberOwner = "owner"
+	OperatorMemberStaff = "staff"
+)
+
+// TourOperator is an organization (store) that owns tours.
+type TourOperator struct {
+	ID          int64      `json:"id" db:"id"`
+	Name        string     `json:"name" db:"name"`
+	Description *string    `json:"description,omitempty" db:"description"`
+	AvatarUrl   *string    `json:"avatar_url,omitempty" db:"avatar_url"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// TourOperatorMember links a user to an operator as either owner or staff.
+type TourOperatorMember struct {
+	OperatorID int64     `json:"operator_id" db:"operator_id"`
+	UserID     int64     `json:"user_id" db:"user_id"`
+	Username   string    `json:"username" db:"username"`
+	Email      string    `json:"email" db:"email"`
+	MemberRole string    `json:"member_role" db:"member_role"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// TourBooking is a single attendee registration on one of an operator's tours.
+type TourBooking struct {
+	ID           int64     `json:"id" db:"id"`
+	TourID       int64     `json:"tour_id" db:"tour_id"`
+	TourName     string    `json:"tour_name" db:"tour_name"`
+	UserID       int64     `json:"user_id" db:"user_id"`
+	Username     string    `json:"username" db:"username"`
+	UserStatus   string    `json:"user_status" db:"user_status"`
+	Price        float64   `json:"price" db:"price"`
+	RegisteredAt time.Time `json:"registered_at" db:"registered_at"`
+}
+
+// OperatorRevenue summarises bookings across all of an operator's tours.
+type OperatorRevenue struct {
+	OperatorID     int64   `json:"operator_id" db:"operator_id"`
+	TourCount      int     `json:"tour_count" db:"tour_count"`
+	BookingCount   int     `json:"booking_count" db:"booking_count"`
+	TotalRevenue   float64 `json:"total_revenue" db:"total_revenue"`
+	CancelledCount int     `json:"cancelled_count" db:"cancelled_count"`
+}
+
+var (
+	// ErrOperatorNotFound and ErrMemberUserNotFound are returned when the
+	// operator or user of a new member no longer exists.
+	ErrOperatorNotFound   = errors.New("tour operator not found")
+	ErrMemberUserNotFound = errors.New("user not found")
+)
+
+type TourOperatorStore struct {
+	db *sqlx.DB
+}
+
+// Create inserts the operator, registers ownerID as its owner and grants
+// them the tour operator role in one transaction.
+func (s *TourOperatorStore) Create(ctx context.Context, operator *TourOperator, ownerID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO tour_operators (name, description, avatar_url)
+              VALUES ($1, $2, $3)
+              RETURNING id, created_at`
+	err = tx.QueryRowContext(ctx, query, operator.Name, operator.Description, operator.AvatarUrl).Scan(&operator.ID, &operator.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := addMember(ctx, tx, operator.ID, ownerID, OperatorMemberOwner); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *TourOperatorStore) GetByID(ctx context.Context, id int64) (*TourOperator, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var operator TourOperator
+	query := `SELECT id, name, description, avatar_url, created_at, updated_at FROM tour_operators WHERE id = $1`
+	err := s.db.GetContext(ctx, &operator, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+	return &operator, nil
+}
+
+func (s *TourOperatorStore) Update(ctx context.Context, operator *TourOperator) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `UPDATE tour_operators SET name = $1, description = $2, avatar_url = $3, updated_at = NOW()
+              WHERE id = $4
+              RETURNING updated_at`
+	return s.db.QueryRowContext(ctx, query, operator.Name, operator.Description, operator.AvatarUrl, operator.ID).Scan(&operator.UpdatedAt)
+}
+
+// GetByUserID lists the operators a user belongs to, as owner or staff.
+func (s *TourOperatorStore) GetByUserID(ctx context.Context, userID int64) ([]*TourOperator, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	operators := []*TourOperator{}
+	query := `SELECT o.id, o.name, o.description, o.avatar_url, o.created_at, o.updated_at
+              FROM tour_operators o
+              JOIN tour_operator_members m ON m.operator_id = o.id
+              WHERE m.user_id = $1
+              ORDER BY o.name`
+	err := s.db.SelectContext(ctx, &operators, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	return operators, nil
+}
+
+// GetMemberRole returns the user's role within the operator, or sql.ErrNoRows if they are not a member.
+func (s *TourOperatorStore) GetMemberRole(ctx context.Context, operatorID, userID int64) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var role string
+	query := `SELECT member_role FROM tour_operator_members WHERE operator_id = $1 AND user_id = $2`
+	err := s.db.GetContext(ctx, &role, query, operatorID, userID)
+	if err != nil {
+		return "", err
+	}
+	return role, nil
+}
+
+func (s *TourOperatorStore) GetMembers(ctx context.Context, operatorID int64) ([]*TourOperatorMember, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	members := []*TourOperatorMember{}
+	query := `SELECT m.operator_id, m.user_id, u.username, u.email, m.member_role, m.created_at
+              FROM tour_operator_members m
+              JOIN users u ON u.id = m.user_id
+              WHERE m.operator_id = $1
+              ORDER BY m.member_role, u.username`
+	err := s.db.SelectContext(ctx, &members, query, operatorID)
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// AddMember adds the user to the operator, or changes their member role,
+// and grants them the tour operator role in one transaction.
+func (s *TourOperatorStore) AddMember(ctx context.Context, operatorID, userID int64, memberRole string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := addMember(ctx, tx, operatorID, userID, memberRole); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func addMember(ctx context.Context, tx *sqlx.Tx, operatorID, userID int64, memberRole string) error {
+	query := `INSERT INTO tour_operator_members (operator_id, user_id, member_role)
+              VALUES ($1, $2, $3)
+              ON CONFLICT (operator_id, user_id) DO UPDATE SET member_role = EXCLUDED.member_role`
+	if _, err := tx.ExecContext(ctx, query, operatorID, userID, memberRole); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			switch pqErr.Constraint {
+			case "fk_operator_members_operator":
+				return ErrOperatorNotFound
+			case "fk_operator_members_user":
+				return ErrMemberUserNotFound
+			}
+		}
+		return err
+	}
+
+	return addUserToRole(ctx, tx, userID, TOUR_OPERATOR)
+}
+
+// RemoveMember removes a staff member. Owners cannot be removed this way.
+// A user left without any operator loses the tour operator role in the same
+// transaction.
+func (s *TourOperatorStore) RemoveMember(ctx context.Context, operatorID, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM tour_operator_members WHERE operator_id = $1 AND user_id = $2 AND member_role <> $3`
+	result, err := tx.ExecContext(ctx, query, operatorID, userID, OperatorMemberOwner)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	revokeQuery := `DELETE FROM user_roles ur
+              USING roles r
+              WHERE ur.role_id = r.id AND r.name = $2 AND ur.user_id = $1
+                AND NOT EXISTS (SELECT 1 FROM tour_operator_members WHERE user_id = $1)`
+	if _, err := tx.ExecContext(ctx, revokeQuery, userID, TOUR_OPERATOR); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *TourOperatorStore) GetBookings(ctx context.Context, operatorID int64, limit, offset int) (*PaginatedList[*TourBooking], error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	bookings := []*TourBooking{}
+	query := `SELECT ta.id, ta.tour_id, t.name AS tour_name, ta.user_id, u.username, ta.user_status, t.price, ta.registered_at
+              FROM tour_attendees ta
+              JOIN tours t ON t.id = ta.tour_id
+              JOIN users u ON u.id = ta.user_id
+              WHERE t.operator_id = $1
+              ORDER BY ta.registered_at DESC
+              LIMIT $2 OFFSET $3`
+	err := s.db.SelectContext(ctx, &bookings, query, operatorID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	var totalCount int
+	countQuery := `SELECT COUNT(*) FROM tour_attendees ta JOIN tours t ON t.id = ta.tour_id WHERE t.operator_id = $1`
+	err = s.db.GetContext(ctx, &totalCount, countQuery, operatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPaginatedList(bookings, totalCount, limit, offset)
+}
+
+// GetRevenue sums the tour price of every non-cancelled booking on the operator's tours.
+func (s *TourOperatorStore) GetRevenue(ctx context.Context, operatorID int64) (*OperatorRevenue, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	revenue := OperatorRevenue{OperatorID: operatorID}
+	query := `SELECT
+                  COUNT(DISTINCT t.id) AS tour_count,
+                  COUNT(ta.id) FILTER (WHERE ta.user_status <> 'cancelled') AS booking_count,
+                  COALESCE(SUM(t.price) FILTER (WHERE ta.user_status <> 'cancelled'), 0) AS total_revenue,
+                  COUNT(ta.id) FILTER (WHERE ta.user_status = 'cancelled') AS cancelled_count
+              FROM tours t
+              LEFT JOIN tour_attendees ta ON ta.tour_id = t.id
+              WHERE t.operator_id = $1`
+	err := s.db.QueryRowContext(ctx, query, operatorID).Scan(
+		&revenue.TourCount,
+		&revenue.BookingCount,
+		&revenue.TotalRevenue,
+		&revenue.CancelledCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &revenue, nil
+}
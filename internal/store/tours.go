@@ -25,6 +25,44 @@ type Tour struct {
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    *time.Time `json:"updated_at"`
 	ImagesUrl    []string   `json:"images_url"`
+	// OperatorId is the tour operator (store) that owns this tour.
+	OperatorId     *int64  `json:"operator_id"`
+	StoreName      *string `json:"store_name,omitempty"`
+	StoreAvatarUrl *string `json:"store_avatar_url,omitempty"`
+}
+
+// tourColumns selects a tour together with its operator's display fields.
+// Queries using it must alias tours as t and LEFT JOIN tour_operators as o.
+const tourColumns = `t.id, t.event_id, t.name, t.description, t.thumbnail_url, t.price, t.capacity, t.duration, t.start_date, t.end_date, t.location_id, t.created_at, t.updated_at, t.operator_id, o.name, o.avatar_url`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTour(row rowScanner) (*Tour, error) {
+	tour := &Tour{}
+	err := row.Scan(
+		&tour.ID,
+		&tour.EventId,
+		&tour.Name,
+		&tour.Description,
+		&tour.ThumbnailUrl,
+		&tour.Price,
+		&tour.Capacity,
+		&tour.Duration,
+		&tour.StartDate,
+		&tour.EndDate,
+		&tour.LocationId,
+		&tour.CreatedAt,
+		&tour.UpdatedAt,
+		&tour.OperatorId,
+		&tour.StoreName,
+		&tour.StoreAvatarUrl,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return tour, nil
 }
 
 type TourStore struct {
@@ -32,41 +70,29 @@ type TourStore struct {
 }
 
 func (s *TourStore) Create(ctx context.Context, tour *Tour) error {
-	query := `INSERT INTO tours (event_id, name, description, thumbnail_url, price, duration, start_date, end_date, location_id, created_at) 
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW()) RETURNING id`
+	query := `INSERT INTO tours (event_id, name, description, thumbnail_url, price, capacity, duration, start_date, end_date, location_id, operator_id, created_at) 
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW()) RETURNING id, created_at`
 	err := s.db.QueryRowContext(ctx, query,
 		tour.EventId,
 		tour.Name,
 		tour.Description,
 		tour.ThumbnailUrl,
 		tour.Price,
+		tour.Capacity,
 		tour.Duration,
 		tour.StartDate,
 		tour.EndDate,
-		tour.LocationId).Scan(&tour.ID)
+		tour.LocationId,
+		tour.OperatorId).Scan(&tour.ID, &tour.CreatedAt)
 	return err
 }
 
 // TODO :Add tour images
 func (s *TourStore) GetByID(ctx context.Context, id int64) (*Tour, error) {
-	query := `SELECT id, event_id, name, description, thumbnail_url, price, duration, start_date, end_date, location_id, created_at, updated_at
-        FROM tours WHERE id = $1`
+	query := `SELECT ` + tourColumns + `
+        FROM tours t LEFT JOIN tour_operators o ON o.id = t.operator_id WHERE t.id = $1`
 
-	tour := &Tour{}
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&tour.ID,
-		&tour.EventId,
-		&tour.Name,
-		&tour.Description,
-		&tour.ThumbnailUrl,
-		&tour.Price,
-		&tour.Duration,
-		&tour.StartDate,
-		&tour.EndDate,
-		&tour.LocationId,
-		&tour.CreatedAt,
-		&tour.UpdatedAt,
-	)
+	tour, err := scanTour(s.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -77,31 +103,90 @@ func (s *TourStore) GetByID(ctx context.Context, id int64) (*Tour, error) {
 }
 
 func (s *TourStore) Update(ctx context.Context, tour *Tour) error {
-	query := `UPDATE tours SET event_id = $1, name = $2, description = $3, thumbnail_url = $4, price = $5, duration = $6, start_date = $7, end_date = $8, location_id = $9, updated_at = NOW() WHERE id = $10`
+	query := `UPDATE tours SET event_id = $1, name = $2, description = $3, thumbnail_url = $4, price = $5, capacity = $6, duration = $7, start_date = $8, end_date = $9, location_id = $10, operator_id = $11, updated_at = NOW() WHERE id = $12`
 	_, err := s.db.ExecContext(ctx, query,
 		tour.EventId,
 		tour.Name,
 		tour.Description,
 		tour.ThumbnailUrl,
 		tour.Price,
+		tour.Capacity,
 		tour.Duration,
 		tour.StartDate,
 		tour.EndDate,
 		tour.LocationId,
+		tour.OperatorId,
 		tour.ID)
 	return err
 }
 
 func (s *TourStore) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
 	query := `DELETE FROM tours WHERE id = $1`
-	_, err := s.db.ExecContext(ctx, query, id)
-	return err
+	result, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
 }
 
 func (s *TourStore) GetAll(ctx context.Context, limit, offset int) (*PaginatedList[*Tour], error) {
-	query := `SELECT id, name, description, thumbnail_url, price, duration, start_date, end_date, location_id, created_at, updated_at
-        FROM tours ORDER BY created_at DESC LIMIT $1 OFFSET $2`
-	rows, err := s.db.QueryContext(ctx, query, limit, offset)
+	query := `SELECT ` + tourColumns + `
+        FROM tours t LEFT JOIN tour_operators o ON o.id = t.operator_id
+        ORDER BY t.created_at DESC LIMIT $1 OFFSET $2`
+	return s.listTours(ctx, query, `SELECT COUNT(*) FROM tours`, limit, offset)
+}
+
+func (s *TourStore) GetByOperatorID(ctx context.Context, operatorId int64, limit, offset int) (*PaginatedList[*Tour], error) {
+	query := `SELECT ` + tourColumns + `
+        FROM tours t LEFT JOIN tour_operators o ON o.id = t.operator_id
+        WHERE t.operator_id = $3
+        ORDER BY t.created_at DESC LIMIT $1 OFFSET $2`
+	countQuery := `SELECT COUNT(*) FROM tours WHERE operator_id = $1`
+	return s.listTours(ctx, query, countQuery, limit, offset, operatorId)
+}
+
+// GetBookedByUserID returns the tours the user is registered on, excluding cancelled bookings.
+func (s *TourStore) GetBookedByUserID(ctx context.Context, userId int64) ([]*Tour, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `SELECT ` + tourColumns + `
+        FROM tours t
+        LEFT JOIN tour_operators o ON o.id = t.operator_id
+        WHERE t.id IN (SELECT tour_id FROM tour_attendees WHERE user_id = $1 AND user_status <> 'cancelled')
+        ORDER BY t.start_date ASC`
+	rows, err := s.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tours := []*Tour{}
+	for rows.Next() {
+		tour, err := scanTour(rows)
+		if err != nil {
+			return nil, err
+		}
+		tours = append(tours, tour)
+	}
+	return tours, rows.Err()
+}
+
+// listTours runs a paginated tour query. The query takes limit and offset as
+// $1 and $2 followed by args; the count query takes only args.
+func (s *TourStore) listTours(ctx context.Context, query, countQuery string, limit, offset int, args ...any) (*PaginatedList[*Tour], error) {
+	rows, err := s.db.QueryContext(ctx, query, append([]any{limit, offset}, args...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -109,14 +194,22 @@ func (s *TourStore) GetAll(ctx context.Context, limit, offset int) (*PaginatedLi
 	defer rows.Close()
 	var tours []*Tour
 	for rows.Next() {
-		tour := &Tour{}
-		err := rows.Scan(&tour.ID, &tour.Name, &tour.Description, &tour.ThumbnailUrl, &tour.Price, &tour.Duration, &tour.StartDate, &tour.EndDate, &tour.LocationId, &tour.CreatedAt, &tour.UpdatedAt)
+		tour, err := scanTour(rows)
 		if err != nil {
 			return nil, err
 		}
 		tours = append(tours, tour)
 	}
-	paginatedList, err := NewPaginatedList(tours, len(tours), limit, offset)
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var totalCount int
+	if err := s.db.GetContext(ctx, &totalCount, countQuery, args...); err != nil {
+		return nil, err
+	}
+
+	paginatedList, err := NewPaginatedList(tours, totalCount, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -143,6 +236,26 @@ func (s *TourStore) AddTourImagesUrl(
 	return nil
 }
 
+func (s *TourStore) RemoveTourImageUrl(ctx context.Context, tourId int64, imageUrl string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `DELETE FROM tour_images WHERE tour_id = $1 AND image_url = $2`
+	result, err := s.db.ExecContext(ctx, query, tourId, imageUrl)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
 func (s *TourStore) GetTourImagesUrl(ctx context.Context, tourId int64) ([]string, error) {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()